@@ -3,11 +3,14 @@ package httpx
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"math/rand"
 	"net"
 	"net/http"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,30 +19,95 @@ import (
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
 	"github.com/labstack/gommon/color"
+	"github.com/madlabx/pkgx/errors"
+	"github.com/madlabx/pkgx/log"
+	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasttemplate"
 )
 
 type AccessLogTiming string
 
+// PreflightLogging controls the access logging of CORS preflight requests.
+type PreflightLogging string
+
 const (
 	defaultBufSize = 4096
 
+	defaultBodyDumpMaxBuffer = 1 << 20
+
+	// StatusClientClosedRequest is the synthetic status (borrowed from Nginx)
+	// logged when the client disconnects or the request context is cancelled
+	// before a response was written. Nothing is sent to the client.
+	StatusClientClosedRequest = 499
+
 	AccessLogBeforeRun AccessLogTiming = "before"
 	AccessLogAfterRun  AccessLogTiming = "after"
 	AccessLogBoth      AccessLogTiming = "both"
+
+	// PreflightLogNormal logs preflight requests like any other
+	PreflightLogNormal PreflightLogging = "log"
+	// PreflightLogDebug logs preflight requests only when Logger is at debug
+	// level, structured entries are emitted at debug level
+	PreflightLogDebug PreflightLogging = "debug"
+	// PreflightLogSkip never logs preflight requests
+	PreflightLogSkip PreflightLogging = "skip"
 )
 
 type (
 	Filter func(echo.Context) bool
 
+	// StatusLevelMap maps a status class, 5 for 5xx, to the level of its
+	// access entries, see LoggerConfig.StatusLevels.
+	StatusLevelMap map[int]logrus.Level
+
 	// LoggerConfig defines the config for Logger middleware.
 	LoggerConfig struct {
 		// Skipper defines a function to skip middleware.
 		Skipper middleware.Skipper
 
-		// OutBodyFilter defines a function to print body_out, false by default due to additional memory used
+		// OutBodyFilter defines a function to print body_out, false by default due to additional memory used.
+		// The body_out of a HEAD request is always empty.
+		// A streamed response, flushed by the handler, sent with Transfer-Encoding
+		// chunked or asked for as SSE, is not held, its body_out is "[streamed, N bytes]".
 		OutBodyFilter middleware.Skipper
 
+		// BodyDumpPolicy looks up the per route override of body_in/body_out dumping.
+		// Optional. The global settings apply when nil or nothing is found.
+		BodyDumpPolicy func(c echo.Context) (BodyDumpPolicy, bool)
+
+		// BodyDumpSampleRate is the fraction of requests whose bodies are
+		// dumped, e.g. 0.01, the access line of every request is logged all
+		// the same, without body_in/body_out for the others, whose response is
+		// not buffered. It applies on top of OutBodyFilter and BodyDumpPolicy,
+		// a route with bodies disabled is never dumped. Optional. 0 or 1 dumps
+		// every request.
+		BodyDumpSampleRate float64
+
+		// BodyDumpOnErrorOnly still buffers the bodies, but dumps body_in and
+		// body_out only in the entry after the handler and only if the status
+		// is 4xx or 5xx, the buffers of the other requests are dropped. The
+		// request body is read before the handler then, whatever the format.
+		// Sampling applies first, a request not sampled is not dumped even if
+		// it fails, keep BodyDumpSampleRate at 1 to dump every failure.
+		BodyDumpOnErrorOnly bool
+
+		// BodyDumpLimits overrides the body dump limit of the bodies whose
+		// Content-Type matches a pattern, a glob like "image/*" or a prefix
+		// like "application/json", e.g. to dump JSON fully but cap the rest.
+		// The longest matching pattern wins, a limit of 0 disables the dump.
+		// Only printable contents, see isPrintableTextContent, and those of
+		// a RegisterBodyRenderer are dumped whatever the limit, and a
+		// BodyDumpPolicy BufferSize takes precedence. Optional. The global
+		// limit applies when nothing matches.
+		BodyDumpLimits map[string]int
+
+		// BodyDumpMaxBuffer caps the response body held for body_out, the
+		// client gets the whole response all the same. A body beyond it is
+		// dumped up to it followed by " [truncated at N bytes]", when its
+		// limit, e.g. of BodyDumpLimits, is larger, which protects the memory
+		// of the large downloads. Optional. Default value 1MB.
+		BodyDumpMaxBuffer int
+
 		// Tags to construct the logger format.
 		//
 		// - time_unix
@@ -56,8 +124,10 @@ type (
 		// - protocol
 		// - referer
 		// - user_agent
+		// - client_cn (CommonName of verified client certificate, mutual TLS only)
 		// - status
 		// - error
+		// - level (see StatusLevels)
 		// - latency (In nanoseconds)
 		// - latency_human (Human readable)
 		// - bytes_in (Bytes received)
@@ -71,7 +141,8 @@ type (
 		//
 		// Example "${remote_ip} ${status}"
 		//
-		// Optional. Default value DefaultLoggerConfig.Format.
+		// Optional. Default value DefaultLoggerConfig.Format, with client_cn
+		// after user_agent for the requests over mutual TLS.
 		FormatAfter  string `yaml:"format_after"`
 		FormatBefore string `yaml:"format_before"`
 		Timing       AccessLogTiming
@@ -83,15 +154,60 @@ type (
 		// Optional. Default value os.Stdout.
 		Output io.Writer
 
+		// Sink receives each access entry as parsed fields along with its
+		// formatted line, instead of Output, see AccessLogSink. Optional.
+		// Default value writes the lines to Output, like NewWriterSink.
+		Sink AccessLogSink
+
+		// Structured emits each access entry as fields(url, req_bytes, res_bytes,
+		// content_type, ...) through Logger, FormatBefore/FormatAfter, Output
+		// and Sink are not used then. The response entry has the latency as
+		// numbers to aggregate on, latency_ns in nanoseconds and latency_ms in
+		// milliseconds, besides latency_human, and client_cn for the requests
+		// over mutual TLS.
+		Structured bool
+
+		// ETag sets a hash of the body as ETag of 2xx GET responses up to
+		// ETagMaxSize, answering 304 when If-None-Match matches. The body is held
+		// back until the handler is done, larger responses are streamed without
+		// ETag. Optional. ETagMaxSize defaults to 1MB.
+		ETag        bool
+		ETagMaxSize int64
+
+		// Preflight controls the logging of CORS preflight requests, see
+		// IsPreflight. Optional. Default value PreflightLogNormal.
+		Preflight PreflightLogging
+
+		// Logger receives the structured entries, and decides the level for
+		// PreflightLogDebug and StatusLevels. Optional. Default value
+		// log.StandardLogger().
+		Logger *log.Logger
+
+		// StatusLevels sets the level of the entries logged after the handler
+		// by the class of the status, e.g. SeverityStatusLevels for 5xx at
+		// error and 4xx at warn, to alert on the level. The other classes, and
+		// the entries before the handler, stay at info. The lines of
+		// FormatBefore/FormatAfter are then dropped below the level of Logger
+		// too, and tell theirs with the tag level. Optional. Default value
+		// nil, all at info.
+		StatusLevels StatusLevelMap
+
 		templateAfter  *fasttemplate.Template
 		templateBefore *fasttemplate.Template
-		colorer        *color.Color
-		pool           *sync.Pool
-		bodyBufferSize int64
+		// the default formats with client_cn, for the requests over mutual TLS
+		templateAfterMTLS  *fasttemplate.Template
+		templateBeforeMTLS *fasttemplate.Template
+		colorer            *color.Color
+		pool               *sync.Pool
+		bodyBufferSize     int64
 	}
 )
 
 var (
+	// SeverityStatusLevels logs 5xx at error, 4xx at warn and the rest at
+	// info.
+	SeverityStatusLevels = StatusLevelMap{5: logrus.ErrorLevel, 4: logrus.WarnLevel}
+
 	// DefaultLoggerConfig is the default Logger middleware config.
 	DefaultLoggerConfig = LoggerConfig{
 		Skipper:       middleware.DefaultSkipper,
@@ -113,6 +229,12 @@ var (
 	once         sync.Once
 )
 
+// withClientCN adds the client_cn tag after user_agent to a default format
+func withClientCN(format string) string {
+	const userAgent = `"user_agent":"${user_agent}",`
+	return strings.Replace(format, userAgent, userAgent+`"client_cn":"${client_cn}",`, 1)
+}
+
 // DefaultOutBodyFilter returns false which processes the middleware.
 func DefaultOutBodyFilter(echo.Context) bool {
 	return false
@@ -150,6 +272,9 @@ func LoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 			config.FormatBefore = DefaultLoggerConfig.FormatBefore
 		}
 		config.templateBefore = fasttemplate.New(config.FormatBefore+"\n", "${", "}")
+		if config.FormatBefore == DefaultLoggerConfig.FormatBefore {
+			config.templateBeforeMTLS = fasttemplate.New(withClientCN(config.FormatBefore)+"\n", "${", "}")
+		}
 	}
 
 	if config.Timing != AccessLogBeforeRun {
@@ -157,7 +282,21 @@ func LoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 			config.FormatAfter = DefaultLoggerConfig.FormatAfter
 		}
 		config.templateAfter = fasttemplate.New(config.FormatAfter+"\n", "${", "}")
+		if config.FormatAfter == DefaultLoggerConfig.FormatAfter {
+			config.templateAfterMTLS = fasttemplate.New(withClientCN(config.FormatAfter)+"\n", "${", "}")
+		}
 	}
+	if config.Preflight == "" {
+		config.Preflight = PreflightLogNormal
+	}
+	if config.BodyDumpMaxBuffer <= 0 {
+		config.BodyDumpMaxBuffer = defaultBodyDumpMaxBuffer
+	}
+	if (config.Structured || config.Preflight == PreflightLogDebug || len(config.StatusLevels) > 0) &&
+		config.Logger == nil {
+		config.Logger = log.StandardLogger()
+	}
+
 	config.colorer = color.New()
 	config.colorer.SetOutput(config.Output)
 	config.pool = &sync.Pool{
@@ -166,28 +305,19 @@ func LoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 		},
 	}
 
-	loggingRequestBody := func(c echo.Context, bytesIn int64) string {
-		if bytesIn > 0 && bytesIn <= config.bodyBufferSize &&
-			isPrintableTextContent(c.Request().Header.Get(echo.HeaderContentType)) {
-			// Request
-			var reqBody []byte
-			if c.Request().Body != nil { // Read
-				reqBody, _ = io.ReadAll(c.Request().Body)
-			}
-			c.Request().Body = io.NopCloser(bytes.NewBuffer(reqBody)) // Reset
-			bytesIn = min(bytesIn, int64(len(reqBody)))
-			return fmt.Sprintf("in[%v]:%v", bytesIn, string(reqBody[:bytesIn]))
+	loggingRequestBody := func(dump *requestBodyDump, c echo.Context, bytesIn int64, limit int64) string {
+		if body, ok := dump.get(c, bytesIn, limit); ok {
+			return fmt.Sprintf("in[%v]:%v", len(body), body)
 		}
 		return fmt.Sprintf("in[%v]", bytesIn)
 	}
 
-	loggingResponseBody := func(c echo.Context, doPrintBodyOut bool, bytesOut int64, respBody []byte) string {
-		if doPrintBodyOut && bytesOut > 0 && bytesOut <= config.bodyBufferSize &&
-			isPrintableTextContent(c.Response().Header().Get(echo.HeaderContentType)) {
-			//skip "\n"
-			bytesOut = min(bytesOut, int64(len(respBody)))
-			bytesOut = max(0, bytesOut-1)
-			return fmt.Sprintf("out[%v]:%v", bytesOut, string(respBody[:bytesOut]))
+	loggingResponseBody := func(c echo.Context, doPrintBodyOut bool, bytesOut int64, respBody *responseBodyDump, limit int64) string {
+		if body, ok := dumpResponseBody(c, doPrintBodyOut, bytesOut, respBody, limit, config.BodyDumpMaxBuffer); ok {
+			if respBody.streamed {
+				return fmt.Sprintf("out[%v]:%v", bytesOut, body)
+			}
+			return fmt.Sprintf("out[%v]:%v", len(body), body)
 		}
 		return fmt.Sprintf("out[%v]", bytesOut)
 	}
@@ -198,18 +328,86 @@ func LoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 				return next(c)
 			}
 
+			entryLevel, debugPreflight := logrus.InfoLevel, false
+			if config.Preflight != PreflightLogNormal && IsPreflight(c) {
+				if config.Preflight == PreflightLogSkip || !config.Logger.IsLevelEnabled(logrus.DebugLevel) {
+					return next(c)
+				}
+				entryLevel, debugPreflight = logrus.DebugLevel, true
+			}
+
 			req := c.Request()
 			res := c.Response()
 			start := time.Now()
 
-			doPrintBodyOut := config.OutBodyFilter(c)
-			respBody := newLimitBuffer(config.bodyBufferSize)
-			if doPrintBodyOut {
+			// a HEAD response has no body, whatever the handler writes
+			doPrintBodyOut := config.OutBodyFilter(c) && req.Method != http.MethodHead
+			bodyLimit := config.bodyBufferSize
+			// a route limit or no dump at all ignores BodyDumpLimits
+			fixedLimit := false
+			if config.BodyDumpPolicy != nil {
+				if policy, ok := config.BodyDumpPolicy(c); ok {
+					if policy.Disable {
+						doPrintBodyOut = false
+						bodyLimit, fixedLimit = 0, true
+					} else if policy.BufferSize > 0 {
+						bodyLimit, fixedLimit = policy.BufferSize, true
+					}
+				}
+			}
+			if !sampled(config.BodyDumpSampleRate) {
+				doPrintBodyOut = false
+				bodyLimit, fixedLimit = 0, true
+			}
+			limitFor := func(contentType string) int64 {
+				if fixedLimit {
+					return bodyLimit
+				}
+				return bodyDumpLimit(config.BodyDumpLimits, contentType, bodyLimit)
+			}
+			reqLimit := limitFor(req.Header.Get(echo.HeaderContentType))
+			bufLimit := bodyLimit
+			if !fixedLimit {
+				// the response Content-Type is not known yet
+				for _, l := range config.BodyDumpLimits {
+					bufLimit = max(bufLimit, int64(l))
+				}
+			}
+			bufLimit = min(bufLimit, int64(config.BodyDumpMaxBuffer))
+			respBody := &responseBodyDump{limitBuffer: newLimitBuffer(bufLimit), streamed: isStreaming(req)}
+			var etagW *etagWriter
+			if config.ETag && req.Method == http.MethodGet {
+				var dump io.Writer
+				if doPrintBodyOut {
+					dump = respBody
+				}
+				// the held body feeds the dump, no tee needed
+				etagW = newETagWriter(res.Writer, dump, config.ETagMaxSize)
+				res.Writer = etagW
+				if doPrintBodyOut {
+					res.Writer = &bodyDumpResponseWriter{Writer: etagW, ResponseWriter: etagW, dump: respBody}
+				}
+			} else if doPrintBodyOut {
 				mw := io.MultiWriter(c.Response().Writer, respBody)
-				writer := &bodyDumpResponseWriter{Writer: mw, ResponseWriter: c.Response().Writer}
+				writer := &bodyDumpResponseWriter{Writer: mw, ResponseWriter: c.Response().Writer, dump: respBody}
 				c.Response().Writer = writer
 			}
 
+			var (
+				handlerErr error
+				reqDump    requestBodyDump
+				afterRun   bool
+			)
+			withBodies := func() bool {
+				return !config.BodyDumpOnErrorOnly || (afterRun && res.Status >= http.StatusBadRequest)
+			}
+			// levelOf is the level of the entry being logged
+			levelOf := func() logrus.Level {
+				if !afterRun || debugPreflight {
+					return entryLevel
+				}
+				return config.StatusLevels.level(res.Status, entryLevel)
+			}
 			loggingTemplate := func(buf *bytes.Buffer, tag string) (int, error) {
 				switch tag {
 				case "time_unix":
@@ -217,17 +415,13 @@ func LoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 				case "time_unix_nano":
 					return buf.WriteString(strconv.FormatInt(time.Now().UnixNano(), 10))
 				case "time_rfc3339":
-					return buf.WriteString(time.Now().Format(time.RFC3339))
+					return buf.WriteString(log.Now().Format(time.RFC3339))
 				case "time_rfc3339_nano":
-					return buf.WriteString(time.Now().Format(time.RFC3339Nano))
+					return buf.WriteString(log.Now().Format(time.RFC3339Nano))
 				case "time_custom":
-					return buf.WriteString(time.Now().Format(config.CustomTimeFormat))
+					return buf.WriteString(log.Now().Format(config.CustomTimeFormat))
 				case "id":
-					id := req.Header.Get(echo.HeaderXRequestID)
-					if id == "" {
-						id = res.Header().Get(echo.HeaderXRequestID)
-					}
-					return buf.WriteString(id)
+					return buf.WriteString(GetRequestId(c))
 				case "remote_ip":
 					return buf.WriteString(c.RealIP())
 				case "host":
@@ -248,6 +442,9 @@ func LoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 					return buf.WriteString(req.Referer())
 				case "user_agent":
 					return buf.WriteString(req.UserAgent())
+				case "client_cn":
+					subject, _ := ClientCertSubject(c)
+					return buf.WriteString(subject.CommonName)
 
 				case "bytes_in":
 					cl := req.Header.Get(echo.HeaderContentLength)
@@ -261,7 +458,10 @@ func LoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 						cl = "0"
 					}
 					bytesIn, _ := strconv.Atoi(cl)
-					return buf.WriteString(loggingRequestBody(c, int64(bytesIn)))
+					if !withBodies() {
+						return buf.WriteString(fmt.Sprintf("in[%v]", bytesIn))
+					}
+					return buf.WriteString(loggingRequestBody(&reqDump, c, int64(bytesIn), reqLimit))
 
 				case "latency":
 					l := time.Now().Sub(start)
@@ -271,7 +471,8 @@ func LoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 				case "bytes_out":
 					return buf.WriteString(strconv.FormatInt(res.Size, 10))
 				case "body_out":
-					return buf.WriteString(loggingResponseBody(c, doPrintBodyOut, res.Size, respBody.Bytes()))
+					return buf.WriteString(loggingResponseBody(c, doPrintBodyOut && withBodies(), res.Size, respBody,
+						limitFor(res.Header().Get(echo.HeaderContentType))))
 				case "status":
 					n := res.Status
 					s := config.colorer.Green(n)
@@ -284,6 +485,12 @@ func LoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 						s = config.colorer.Cyan(n)
 					}
 					return buf.WriteString(s)
+				case "error":
+					if handlerErr != nil {
+						return buf.WriteString(handlerErr.Error())
+					}
+				case "level":
+					return buf.WriteString(levelOf().String())
 				default:
 					switch {
 					case strings.HasPrefix(tag, "header_in:"):
@@ -304,52 +511,311 @@ func LoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 				return 0, nil
 			}
 
+			structuredFields := func(after bool) logrus.Fields {
+				cl := req.Header.Get(echo.HeaderContentLength)
+				bytesIn, _ := strconv.ParseInt(cl, 10, 64)
+				fields := logrus.Fields{
+					"id":           GetRequestId(c),
+					"remote_ip":    c.RealIP(),
+					"method":       req.Method,
+					"url":          req.RequestURI,
+					"req_bytes":    bytesIn,
+					"content_type": req.Header.Get(echo.HeaderContentType),
+				}
+				if subject, ok := ClientCertSubject(c); ok && subject.CommonName != "" {
+					fields["client_cn"] = subject.CommonName
+				}
+				if withBodies() {
+					if body, ok := reqDump.get(c, bytesIn, reqLimit); ok {
+						fields["req_body"] = body
+					}
+				}
+				if !after {
+					return fields
+				}
+
+				fields["status"] = res.Status
+				latency := time.Now().Sub(start)
+				fields["latency_ns"] = latency.Nanoseconds()
+				fields["latency_ms"] = float64(latency) / float64(time.Millisecond)
+				fields["latency_human"] = latency.String()
+				fields["res_bytes"] = res.Size
+				if body, ok := dumpResponseBody(c, doPrintBodyOut && withBodies(), res.Size, respBody,
+					limitFor(res.Header().Get(echo.HeaderContentType)), config.BodyDumpMaxBuffer); ok {
+					fields["res_body"] = body
+				}
+				if handlerErr != nil {
+					fields[logrus.ErrorKey] = handlerErr.Error()
+				}
+				return fields
+			}
+
+			runNext := func() {
+				if config.BodyDumpOnErrorOnly {
+					// the handler drains the body, keep it for a failure
+					bytesIn, _ := strconv.ParseInt(req.Header.Get(echo.HeaderContentLength), 10, 64)
+					reqDump.get(c, bytesIn, reqLimit)
+				}
+				if handlerErr = next(c); handlerErr != nil {
+					if isClientGone(c, handlerErr) && !res.Committed {
+						// nobody to send the error response to
+						res.Status = StatusClientClosedRequest
+					} else {
+						c.Error(handlerErr)
+					}
+				} else if !res.Committed && isClientGone(c, nil) {
+					res.Status = StatusClientClosedRequest
+					handlerErr = req.Context().Err()
+				}
+				if etagW != nil {
+					etagW.finish(c)
+				}
+				afterRun = true
+			}
+
+			if config.Structured {
+				if len(config.StatusLevels) == 0 && !config.Logger.IsLevelEnabled(entryLevel) {
+					// skip the field and body dump building of dropped entries
+					runNext()
+					return nil
+				}
+				if config.Timing != AccessLogAfterRun && config.Logger.IsLevelEnabled(entryLevel) {
+					config.Logger.WithFields(structuredFields(false)).Log(entryLevel, "request")
+				}
+				runNext()
+				if level := levelOf(); config.Timing != AccessLogBeforeRun && config.Logger.IsLevelEnabled(level) {
+					config.Logger.WithFields(structuredFields(true)).Log(level, "response")
+				}
+				return nil
+			}
+
 			buf := config.pool.Get().(*bytes.Buffer)
 			defer config.pool.Put(buf)
+			emit := func(after bool) error {
+				level := levelOf()
+				if len(config.StatusLevels) > 0 && !config.Logger.IsLevelEnabled(level) {
+					return nil
+				}
+				// the global fields of the logs close the line
+				if gf := log.FormatGlobalFields(); gf != "" {
+					if b := buf.Bytes(); len(b) > 0 && b[len(b)-1] == '\n' {
+						buf.Truncate(len(b) - 1)
+						buf.WriteString(gf)
+						buf.WriteByte('\n')
+					}
+				}
+				if config.Sink == nil {
+					_, err := config.Output.Write(buf.Bytes())
+					return err
+				}
+				config.Sink.Log(newAccessEntry(c, after, level, start, handlerErr, buf.Bytes()))
+				return nil
+			}
+
+			templateBefore, templateAfter := config.templateBefore, config.templateAfter
+			if _, ok := ClientCertSubject(c); ok {
+				if config.templateBeforeMTLS != nil {
+					templateBefore = config.templateBeforeMTLS
+				}
+				if config.templateAfterMTLS != nil {
+					templateAfter = config.templateAfterMTLS
+				}
+			}
 
-			if config.templateBefore != nil {
+			if templateBefore != nil {
 				//Log after run
 				buf.Reset()
-				if _, err = config.templateBefore.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
+				if _, err = templateBefore.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
 					return loggingTemplate(buf, tag)
 				}); err != nil {
 					return
 				}
-				if _, err = config.Output.Write(buf.Bytes()); err != nil {
+				if err = emit(false); err != nil {
 					return
 				}
 			}
 
-			if err = next(c); err != nil {
-				c.Error(err)
-			}
+			runNext()
 
-			if config.templateAfter == nil {
+			if templateAfter == nil {
 				return
 			}
 
 			//Log after run
 			buf.Reset()
-			if _, err = config.templateAfter.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
+			if _, err = templateAfter.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
 				return loggingTemplate(buf, tag)
 			}); err != nil {
 				return
 			}
 
-			_, err = config.Output.Write(buf.Bytes())
+			err = emit(true)
 
 			return
 		}
 	}
 }
 
+// ParseStatusLevels parses a StatusLevelMap from config, e.g.
+// {"5xx": "error", "4xx": "warn"}, a class being written "5xx" or "5".
+func ParseStatusLevels(m map[string]string) (StatusLevelMap, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	levels := make(StatusLevelMap, len(m))
+	for class, level := range m {
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(class), "xx"))
+		if err != nil || n < 1 || n > 5 {
+			return nil, errors.Errorf("invalid status class %q, should be one of 1xx to 5xx", class)
+		}
+		l, err := logrus.ParseLevel(level)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid level of status class %q", class)
+		}
+		levels[n] = l
+	}
+	return levels, nil
+}
+
+// level returns the level of status, def if its class is not mapped
+func (m StatusLevelMap) level(status int, def logrus.Level) logrus.Level {
+	if l, ok := m[status/100]; ok {
+		return l
+	}
+	return def
+}
+
+// sampleFloat exists so it can be mocked out by tests.
+var sampleFloat = rand.Float64
+
+// sampled draws whether a request is picked at rate, outside (0, 1) all are
+func sampled(rate float64) bool {
+	return rate <= 0 || rate >= 1 || sampleFloat() < rate
+}
+
+func requestId(c echo.Context) string {
+	id := c.Request().Header.Get(echo.HeaderXRequestID)
+	if id == "" {
+		id = c.Response().Header().Get(echo.HeaderXRequestID)
+	}
+	return id
+}
+
+// isClientGone reports whether the request ended because the client closed
+// the connection or its context was cancelled.
+func isClientGone(c echo.Context, err error) bool {
+	if err != nil && errors.Is(err, context.Canceled) {
+		return true
+	}
+	return errors.Is(c.Request().Context().Err(), context.Canceled)
+}
+
+// requestBodyDump reads the request body at most once per request, so that
+// logging body_in both before and after the handler neither consumes the body
+// the handler reads nor sees a body already drained by the handler.
+type requestBodyDump struct {
+	done bool
+	body string
+	ok   bool
+}
+
+func (d *requestBodyDump) get(c echo.Context, bytesIn int64, limit int64) (string, bool) {
+	if !d.done {
+		d.body, d.ok = dumpRequestBody(c, bytesIn, limit)
+		d.done = true
+	}
+	return d.body, d.ok
+}
+
+// dumpRequestBody reads the request body for logging and puts it back, the
+// handler always gets the complete body, even if reading failed midway.
+func dumpRequestBody(c echo.Context, bytesIn int64, limit int64) (string, bool) {
+	req := c.Request()
+	render, dumpable := bodyRenderer(req.Header.Get(echo.HeaderContentType))
+	if req.Body == nil || req.Body == http.NoBody || bytesIn <= 0 || bytesIn > limit || !dumpable {
+		return "", false
+	}
+
+	reqBody, err := io.ReadAll(req.Body)
+	if err != nil {
+		// hand over what was read plus the rest, let the handler see the error
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(reqBody), req.Body), req.Body}
+		return "", false
+	}
+	req.Body = io.NopCloser(bytes.NewReader(reqBody)) // Reset
+	bytesIn = min(bytesIn, int64(len(reqBody)))
+	if render != nil {
+		return renderBody(render, reqBody[:bytesIn]), true
+	}
+	return string(reqBody[:bytesIn]), true
+}
+
+// dumpResponseBody returns body_out, the body held up to limit, truncated
+// when over maxBuffer, see LoggerConfig.BodyDumpMaxBuffer, or a marker of its
+// size when streamed
+func dumpResponseBody(c echo.Context, doPrintBodyOut bool, bytesOut int64, dump *responseBodyDump, limit int64,
+	maxBuffer int) (string, bool) {
+	if doPrintBodyOut && dump.streamed {
+		return fmt.Sprintf("[streamed, %d bytes]", bytesOut), true
+	}
+	respBody := dump.Bytes()
+	render, dumpable := bodyRenderer(c.Response().Header().Get(echo.HeaderContentType))
+	if !doPrintBodyOut || bytesOut <= 0 || bytesOut > limit || !dumpable {
+		return "", false
+	}
+
+	if bytesOut > int64(len(respBody)) && len(respBody) >= maxBuffer {
+		body := string(respBody)
+		if render != nil {
+			body = renderBody(render, respBody)
+		}
+		return fmt.Sprintf("%s [truncated at %d bytes]", body, len(respBody)), true
+	}
+	bytesOut = min(bytesOut, int64(len(respBody)))
+	if render != nil {
+		return renderBody(render, respBody[:bytesOut]), true
+	}
+	//skip "\n"
+	bytesOut = max(0, bytesOut-1)
+	return string(respBody[:bytesOut]), true
+}
+
+// bodyDumpLimit returns the limit of the longest pattern of limits matching
+// contentType, def if none
+func bodyDumpLimit(limits map[string]int, contentType string, def int64) int64 {
+	if len(limits) == 0 {
+		return def
+	}
+	mt := mediaType(contentType)
+	best, limit := -1, def
+	for pattern, l := range limits {
+		pattern = strings.ToLower(pattern)
+		var match bool
+		if strings.ContainsAny(pattern, "*?[") {
+			match, _ = path.Match(pattern, mt)
+		} else {
+			match = strings.HasPrefix(mt, pattern)
+		}
+		if match && (len(pattern) > best || (len(pattern) == best && int64(l) < limit)) {
+			best, limit = len(pattern), int64(l)
+		}
+	}
+	return limit
+}
+
 func isPrintableTextContent(contentType string) bool {
 	return strings.HasPrefix(contentType, echo.MIMEApplicationJSON)
 }
 
+// bodyDumpResponseWriter tees the response into dump, until it turns out
+// streamed: flushed by the handler or sent with Transfer-Encoding chunked
 type bodyDumpResponseWriter struct {
 	io.Writer
 	http.ResponseWriter
+	dump *responseBodyDump
 }
 
 func (w *bodyDumpResponseWriter) WriteHeader(code int) {
@@ -357,10 +823,14 @@ func (w *bodyDumpResponseWriter) WriteHeader(code int) {
 }
 
 func (w *bodyDumpResponseWriter) Write(b []byte) (int, error) {
+	if !w.dump.streamed && strings.EqualFold(w.Header().Get("Transfer-Encoding"), "chunked") {
+		w.dump.stream()
+	}
 	return w.Writer.Write(b)
 }
 
 func (w *bodyDumpResponseWriter) Flush() {
+	w.dump.stream()
 	w.ResponseWriter.(http.Flusher).Flush()
 }
 
@@ -378,24 +848,52 @@ func newLimitBuffer(size int64) *limitBuffer {
 		size = defaultBufSize
 	}
 	return &limitBuffer{
-		buf: make([]byte, size),
+		limit: int(size),
 	}
 }
 
 // limitBuffer only receive first n bytes, ignore the left, and not log rise any error
+// the buffer grows on write, so a large limit costs only what is written
 type limitBuffer struct {
-	buf []byte
-	n   int
+	buf   []byte
+	limit int
 }
 
 // Write only receive first n bytes, ignore the left, and not log rise any error.
 // Return with len(p) to fake the normal behavior
 func (b *limitBuffer) Write(p []byte) (n int, err error) {
 	toWrite := min(len(p), b.Available())
-	_ = copy(b.buf[b.n:], p[:toWrite])
-	b.n += toWrite
+	b.buf = append(b.buf, p[:toWrite]...)
 	return len(p), nil
 }
 
-func (b *limitBuffer) Available() int { return len(b.buf) - b.n }
-func (b *limitBuffer) Bytes() []byte  { return b.buf[:b.n] }
+func (b *limitBuffer) Available() int { return b.limit - len(b.buf) }
+func (b *limitBuffer) Bytes() []byte  { return b.buf }
+
+// responseBodyDump holds the response body for body_out, nothing once the
+// response is streamed, e.g. SSE or progress output, whose body_out is then
+// "[streamed, N bytes]"
+type responseBodyDump struct {
+	*limitBuffer
+	streamed bool
+}
+
+func (d *responseBodyDump) Write(p []byte) (int, error) {
+	if d.streamed {
+		return len(p), nil
+	}
+	return d.limitBuffer.Write(p)
+}
+
+// stream stops holding the body, releasing what is held so far
+func (d *responseBodyDump) stream() {
+	d.streamed = true
+	d.buf = nil
+}
+
+// IsPreflight reports whether c is a CORS preflight request, which the CORS
+// middleware answers without reaching a handler.
+func IsPreflight(c echo.Context) bool {
+	req := c.Request()
+	return req.Method == http.MethodOptions && req.Header.Get(echo.HeaderAccessControlRequestMethod) != ""
+}
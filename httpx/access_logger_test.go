@@ -0,0 +1,552 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+	"github.com/madlabx/pkgx/log"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAccessLogEcho(buf *bytes.Buffer, format string, modify ...func(*LoggerConfig)) *echo.Echo {
+	lc := LoggerConfig{
+		OutBodyFilter:  func(echo.Context) bool { return true },
+		FormatAfter:    format,
+		Timing:         AccessLogAfterRun,
+		Output:         buf,
+		bodyBufferSize: DefaultBodyBufferSize,
+	}
+	for _, m := range modify {
+		m(&lc)
+	}
+
+	e := echo.New()
+	e.Use(LoggerWithConfig(lc))
+	return e
+}
+
+func TestAccessLogBodyDumpPolicy(t *testing.T) {
+	var (
+		buf      bytes.Buffer
+		policies bodyDumpPolicies
+	)
+	policies.set(http.MethodPost, "/upload", BodyDumpPolicy{Disable: true})
+	policies.set(http.MethodPost, "/payments", BodyDumpPolicy{BufferSize: 8})
+
+	e := newTestAccessLogEcho(&buf, "${path} ${body_in}", func(lc *LoggerConfig) {
+		lc.BodyDumpPolicy = policies.lookup
+		lc.bodyBufferSize = 4
+	})
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.POST("/upload", ok)
+	e.POST("/payments", ok)
+	e.POST("/other", ok)
+
+	for _, path := range []string{"/upload", "/payments", "/other"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"a":1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderContentLength, "7")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, "/upload in[7]\n/payments in[7]:{\"a\":1}\n/other in[7]\n", buf.String())
+}
+
+func TestAccessLogBodyDumpSampleRate(t *testing.T) {
+	defer func(f func() float64) { sampleFloat = f }(sampleFloat)
+	draws := []float64{0.05, 0.5}
+	sampleFloat = func() float64 {
+		d := draws[0]
+		draws = draws[1:]
+		return d
+	}
+
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${status} ${body_in} ${body_out}", func(lc *LoggerConfig) {
+		lc.BodyDumpSampleRate = 0.1
+	})
+	var buffered []bool
+	e.POST("/echo", func(c echo.Context) error {
+		_, ok := c.Response().Writer.(*bodyDumpResponseWriter)
+		buffered = append(buffered, ok)
+		return c.JSON(http.StatusOK, 1)
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"a":1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderContentLength, "7")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// both access lines are logged, only the sampled one with bodies
+	assert.Equal(t, "200 in[7]:{\"a\":1} out[1]:1\n200 in[7] out[2]\n", buf.String())
+	assert.Equal(t, []bool{true, false}, buffered)
+}
+
+func TestAccessLogBodyDumpOnErrorOnly(t *testing.T) {
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${status} ${body_in} ${body_out}", func(lc *LoggerConfig) {
+		lc.Timing = AccessLogBoth
+		lc.FormatBefore = "${body_in}"
+		lc.BodyDumpOnErrorOnly = true
+	})
+	e.POST("/items", func(c echo.Context) error {
+		var item struct{ A int }
+		if err := c.Bind(&item); err != nil {
+			return err
+		}
+		if item.A < 0 {
+			return c.JSON(http.StatusBadRequest, -1)
+		}
+		return c.JSON(http.StatusOK, 1)
+	})
+
+	for _, body := range []string{`{"a":1}`, `{"a":-1}`} {
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderContentLength, strconv.Itoa(len(body)))
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// the body drained by Bind is still dumped for the failure
+	assert.Equal(t, "in[7]\n200 in[7] out[2]\n"+
+		"in[8]\n400 in[8]:{\"a\":-1} out[2]:-1\n", buf.String())
+}
+
+func TestAccessLogBodyDumpLimits(t *testing.T) {
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${body_in} ${body_out}", func(lc *LoggerConfig) {
+		lc.bodyBufferSize = 4
+		lc.BodyDumpLimits = map[string]int{
+			"application/json":        64,
+			"application/json+small*": 2,
+			"application/vnd.*":       0,
+		}
+	})
+	e.POST("/echo", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(`{"id":12345}`+"\n"))
+	})
+
+	for _, ct := range []string{echo.MIMEApplicationJSONCharsetUTF8, "application/json+small", "application/vnd.api+json"} {
+		req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"a":1}`))
+		req.Header.Set(echo.HeaderContentType, ct)
+		req.Header.Set(echo.HeaderContentLength, "7")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// the response is application/json, dumped up to 64 bytes
+	assert.Equal(t, "in[7]:{\"a\":1} out[12]:{\"id\":12345}\n"+
+		"in[7] out[12]:{\"id\":12345}\n"+
+		"in[7] out[12]:{\"id\":12345}\n", buf.String())
+}
+
+func TestAccessLogBodyDumpMaxBuffer(t *testing.T) {
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${bytes_out} ${body_out}", func(lc *LoggerConfig) {
+		lc.BodyDumpLimits = map[string]int{"application/json": 1 << 20}
+		lc.BodyDumpMaxBuffer = 8
+	})
+	body := `{"id":1234567890}` + "\n"
+	e.GET("/big", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(body))
+	})
+	e.GET("/small", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, []byte(`{"a":1}`+"\n"))
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/big", nil))
+	// the client gets the whole body, only the dump is capped
+	assert.Equal(t, body, rec.Body.String())
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/small", nil))
+
+	assert.Equal(t, "18 out[31]:{\"id\":12 [truncated at 8 bytes]\n"+
+		"8 out[7]:{\"a\":1}\n", buf.String())
+}
+
+func TestAccessLogBodyRenderer(t *testing.T) {
+	RegisterBodyRenderer("Application/X-Protobuf", func(body []byte) string {
+		return fmt.Sprintf("proto:%x", body)
+	})
+	RegisterBodyRenderer("application/x-broken", func([]byte) string { panic("bad message") })
+	defer RegisterBodyRenderer("application/x-protobuf", nil)
+	defer RegisterBodyRenderer("application/x-broken", nil)
+
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${body_in} ${body_out}")
+	e.POST("/rpc", func(c echo.Context) error {
+		return c.Blob(http.StatusOK, c.Request().Header.Get(echo.HeaderContentType), []byte{0x08, 0x96, 0x01})
+	})
+
+	for _, ct := range []string{"application/x-protobuf; proto=Msg", "application/x-broken", "application/octet-stream"} {
+		req := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader([]byte{0x0a, 0x00}))
+		req.Header.Set(echo.HeaderContentType, ct)
+		req.Header.Set(echo.HeaderContentLength, "2")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// the bodies are rendered whole, an unregistered type is not dumped
+	assert.Equal(t, "in[10]:proto:0a00 out[12]:proto:089601\n"+
+		"in[34]:[body renderer panic: bad message] out[34]:[body renderer panic: bad message]\n"+
+		"in[2] out[3]\n", buf.String())
+}
+
+func TestBodyDumpLimit(t *testing.T) {
+	limits := map[string]int{"application/json": 100, "application/*": 10, "image/*": 1}
+	assert.Equal(t, int64(100), bodyDumpLimit(limits, "application/json; charset=UTF-8", 5))
+	assert.Equal(t, int64(10), bodyDumpLimit(limits, "application/xml", 5))
+	assert.Equal(t, int64(1), bodyDumpLimit(limits, "IMAGE/PNG", 5))
+	assert.Equal(t, int64(5), bodyDumpLimit(limits, "text/plain", 5))
+	assert.Equal(t, int64(5), bodyDumpLimit(nil, "text/plain", 5))
+}
+
+func TestAccessLogGlobalFields(t *testing.T) {
+	log.SetGlobalFields(logrus.Fields{"service": "billing"})
+	defer log.SetGlobalFields(nil)
+
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${method} ${status}")
+	e.GET("/ping", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	assert.Equal(t, "GET 200 service=billing\n", buf.String())
+}
+
+func TestAccessLogStatusLevels(t *testing.T) {
+	lg, out, hook := log.NewTestLogger()
+	lg.SetLevel(logrus.WarnLevel)
+
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${level} ${status}", func(lc *LoggerConfig) {
+		lc.StatusLevels = SeverityStatusLevels
+		lc.Logger = lg
+	})
+	for path, code := range map[string]int{"/ok": http.StatusOK, "/bad": http.StatusBadRequest, "/fail": http.StatusBadGateway} {
+		code := code
+		e.GET(path, func(c echo.Context) error { return c.NoContent(code) })
+	}
+	for _, path := range []string{"/ok", "/bad", "/fail"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// the info line of /ok is below the level of the logger
+	assert.Equal(t, "warning 400\nerror 502\n", buf.String())
+
+	e = newTestAccessLogEcho(nil, "", func(lc *LoggerConfig) {
+		lc.Structured = true
+		lc.StatusLevels = SeverityStatusLevels
+		lc.Logger = lg
+	})
+	e.GET("/fail", func(c echo.Context) error { return c.NoContent(http.StatusServiceUnavailable) })
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+	assert.Equal(t, "response", hook.LastEntry().Message)
+	assert.NotEmpty(t, out.String())
+}
+
+func TestParseStatusLevels(t *testing.T) {
+	levels, err := ParseStatusLevels(map[string]string{"5xx": "error", "4": "warn"})
+	require.NoError(t, err)
+	assert.Equal(t, SeverityStatusLevels, levels)
+	assert.Equal(t, logrus.InfoLevel, levels.level(http.StatusOK, logrus.InfoLevel))
+	assert.Equal(t, logrus.ErrorLevel, levels.level(http.StatusBadGateway, logrus.InfoLevel))
+
+	_, err = ParseStatusLevels(map[string]string{"6xx": "error"})
+	assert.Error(t, err)
+	_, err = ParseStatusLevels(map[string]string{"5xx": "loud"})
+	assert.Error(t, err)
+}
+
+func TestAccessLogClientClosedRequest(t *testing.T) {
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${status} ${error}")
+	e.GET("/slow", func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		return c.Request().Context().Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil).WithContext(ctx)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "499 context canceled\n", buf.String())
+}
+
+func TestAccessLogStructured(t *testing.T) {
+	var buf bytes.Buffer
+	lg := log.New()
+	lg.SetOutput(&buf)
+	lg.SetFormatter(&logrus.JSONFormatter{DisableTimestamp: true})
+
+	e := newTestAccessLogEcho(&buf, "", func(lc *LoggerConfig) {
+		lc.Structured = true
+		lc.Logger = lg
+	})
+	e.POST("/users", func(c echo.Context) error {
+		return c.JSON(http.StatusCreated, map[string]int{"id": 1})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users?x=1", strings.NewReader(`{"a":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderContentLength, "7")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "response", entry["msg"])
+	assert.Equal(t, "/users?x=1", entry["url"])
+	assert.Equal(t, float64(201), entry["status"])
+	assert.Equal(t, float64(7), entry["req_bytes"])
+	assert.Equal(t, float64(9), entry["res_bytes"])
+	assert.Equal(t, `{"a":1}`, entry["req_body"])
+	assert.Equal(t, `{"id":1}`, entry["res_body"])
+	assert.Equal(t, echo.MIMEApplicationJSON, entry["content_type"])
+}
+
+func TestAccessLogStructuredLatency(t *testing.T) {
+	var buf bytes.Buffer
+	lg := log.New()
+	lg.SetOutput(&buf)
+	lg.SetFormatter(&logrus.JSONFormatter{DisableTimestamp: true})
+
+	e := newTestAccessLogEcho(&buf, "", func(lc *LoggerConfig) {
+		lc.Structured = true
+		lc.Logger = lg
+		lc.OutBodyFilter = func(echo.Context) bool { return false }
+	})
+	e.GET("/slow", func(c echo.Context) error {
+		time.Sleep(2 * time.Millisecond)
+		return c.String(http.StatusOK, "done")
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	var entry map[string]any
+	d := json.NewDecoder(&buf)
+	d.UseNumber()
+	require.NoError(t, d.Decode(&entry))
+	assert.NotContains(t, entry, "res_body")
+
+	ns, err := entry["latency_ns"].(json.Number).Int64()
+	require.NoError(t, err, "an integer")
+	ms, err := entry["latency_ms"].(json.Number).Float64()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, ns, int64(2*time.Millisecond))
+	assert.InDelta(t, float64(ns)/1e6, ms, 1e-6)
+	human, err := time.ParseDuration(entry["latency_human"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(ns), human)
+}
+
+func TestAccessLogClientCN(t *testing.T) {
+	mtls := func(req *http.Request) *http.Request {
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+			{Subject: pkix.Name{CommonName: "svc-a"}},
+		}}}
+		return req
+	}
+
+	// the default format tells it over mutual TLS only
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "")
+	e.GET("/users", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.ServeHTTP(httptest.NewRecorder(), mtls(httptest.NewRequest(http.MethodGet, "/users", nil)))
+	assert.Contains(t, buf.String(), `"client_cn":"svc-a",`)
+	buf.Reset()
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.NotContains(t, buf.String(), "client_cn")
+
+	// a custom one only where it has the tag
+	buf.Reset()
+	e = newTestAccessLogEcho(&buf, "${status}")
+	e.GET("/users", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.ServeHTTP(httptest.NewRecorder(), mtls(httptest.NewRequest(http.MethodGet, "/users", nil)))
+	assert.Equal(t, "200\n", buf.String())
+
+	buf.Reset()
+	lg := log.New()
+	lg.SetOutput(&buf)
+	lg.SetFormatter(&logrus.JSONFormatter{DisableTimestamp: true})
+	e = newTestAccessLogEcho(&buf, "", func(lc *LoggerConfig) {
+		lc.Structured = true
+		lc.Logger = lg
+	})
+	e.GET("/users", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.ServeHTTP(httptest.NewRecorder(), mtls(httptest.NewRequest(http.MethodGet, "/users", nil)))
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "svc-a", entry["client_cn"])
+
+	buf.Reset()
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	entry = nil
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.NotContains(t, entry, "client_cn")
+}
+
+func TestAccessLogHeadBody(t *testing.T) {
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${method} ${status} ${body_out}")
+	e.HEAD("/users", func(c echo.Context) error {
+		// net/http discards it
+		return c.String(http.StatusOK, "users")
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodHead, "/users", nil))
+	assert.Equal(t, "HEAD 200 out[5]\n", buf.String(), "no body dumped")
+}
+
+func TestAccessLogRequestBodyRestored(t *testing.T) {
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${body_in}", func(lc *LoggerConfig) {
+		lc.Timing = AccessLogBoth
+		lc.FormatBefore = "${body_in}"
+	})
+
+	type payload struct {
+		Name string `json:"name"`
+	}
+	var got payload
+	e.POST("/bind", func(c echo.Context) error {
+		if err := c.Bind(&got); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	body := `{"name":"alice"}`
+	req := httptest.NewRequest(http.MethodPost, "/bind", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderContentLength, strconv.Itoa(len(body)))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "alice", got.Name)
+	line := "in[16]:" + body + "\n"
+	assert.Equal(t, line+line, buf.String())
+}
+
+func TestAccessLogPreflight(t *testing.T) {
+	preflight := func() *http.Request {
+		req := httptest.NewRequest(http.MethodOptions, "/users", nil)
+		req.Header.Set(echo.HeaderOrigin, "http://example.com")
+		req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPost)
+		return req
+	}
+
+	for _, tc := range []struct {
+		mode  PreflightLogging
+		level logrus.Level
+		want  string
+	}{
+		{PreflightLogNormal, logrus.InfoLevel, "OPTIONS 204\nGET 200\n"},
+		{PreflightLogDebug, logrus.InfoLevel, "GET 200\n"},
+		{PreflightLogDebug, logrus.DebugLevel, "OPTIONS 204\nGET 200\n"},
+		{PreflightLogSkip, logrus.DebugLevel, "GET 200\n"},
+	} {
+		var (
+			buf     bytes.Buffer
+			counter requestCounter
+		)
+		lg := log.New()
+		lg.SetLevel(tc.level)
+
+		e := newTestAccessLogEcho(&buf, "${method} ${status}", func(lc *LoggerConfig) {
+			lc.Preflight = tc.mode
+			lc.Logger = lg
+		})
+		e.Pre(counter.middleware)
+		e.Use(middleware.CORS())
+		e.GET("/users", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+		e.ServeHTTP(httptest.NewRecorder(), preflight())
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+		assert.Equal(t, tc.want, buf.String(), "%s at %s", tc.mode, tc.level)
+		assert.Equal(t, uint64(1), counter.total.Load())
+		assert.Equal(t, uint64(1), counter.preflight.Load())
+	}
+}
+
+func TestAccessLogETag(t *testing.T) {
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${status} ${body_out}", func(lc *LoggerConfig) {
+		lc.ETag = true
+		lc.ETagMaxSize = 16
+	})
+	e.GET("/small", func(c echo.Context) error { return c.JSON(http.StatusOK, map[string]int{"a": 1}) })
+	e.GET("/large", func(c echo.Context) error { return c.String(http.StatusOK, strings.Repeat("x", 32)) })
+	e.GET("/missing", func(c echo.Context) error { return c.JSON(http.StatusNotFound, map[string]int{"a": 1}) })
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/small", nil))
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, "{\"a\":1}\n", rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/small", nil)
+	req.Header.Set("If-None-Match", `"other", `+etag)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	for _, path := range []string{"/large", "/missing"} {
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Empty(t, rec.Header().Get("ETag"), path)
+		assert.NotEmpty(t, rec.Body.String(), path)
+	}
+
+	assert.Equal(t, "200 out[7]:{\"a\":1}\n304 out[0]\n200 out[32]\n404 out[7]:{\"a\":1}\n", buf.String())
+}
+
+func TestAccessLogStreamedBody(t *testing.T) {
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${path} ${body_out}")
+	rec := httptest.NewRecorder()
+	var sent []string
+	e.GET("/progress", func(c echo.Context) error {
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res.WriteHeader(http.StatusOK)
+		for _, chunk := range []string{`{"done":1}`, `{"done":2}`} {
+			_, _ = res.Write([]byte(chunk))
+			res.Flush()
+			sent = append(sent, rec.Body.String())
+		}
+		return nil
+	})
+	e.GET("/chunked", func(c echo.Context) error {
+		c.Response().Header().Set("Transfer-Encoding", "chunked")
+		return c.JSON(http.StatusOK, 1)
+	})
+	e.GET("/plain", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, 1)
+	})
+
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/progress", nil))
+	// the data reaches the client at each flush
+	assert.Equal(t, []string{`{"done":1}`, `{"done":1}{"done":2}`}, sent)
+	assert.True(t, rec.Flushed)
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/chunked", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/plain", nil))
+
+	assert.Equal(t, "/progress out[20]:[streamed, 20 bytes]\n/chunked out[2]:[streamed, 2 bytes]\n/plain out[1]:1\n", buf.String())
+}
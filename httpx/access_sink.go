@@ -0,0 +1,92 @@
+package httpx
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// AccessEntry is an access log entry as parsed fields, handed to an
+	// AccessLogSink.
+	AccessEntry struct {
+		Time time.Time
+		// After tells the entry logged once the handler is done from the one
+		// logged before it, whose Status, Latency, BytesOut and Error are zero,
+		// see AccessLogTiming.
+		After bool
+		// Level is the level of the entry, see LoggerConfig.StatusLevels.
+		Level     logrus.Level
+		RequestId string
+		RemoteIP  string
+		Method    string
+		Host      string
+		URI       string
+		Path      string
+		Status    int
+		Latency   time.Duration
+		BytesIn   int64
+		BytesOut  int64
+		Error     error
+		// Line is the entry formatted by FormatBefore or FormatAfter, with the
+		// trailing newline. It is only valid during Log, copy it to keep it.
+		Line []byte
+	}
+
+	// AccessLogSink receives the access log entries, e.g. to ship them to a
+	// collector, instead of writing the formatted lines to Output. Log is
+	// called from the request goroutines, concurrently, and should not block.
+	AccessLogSink interface {
+		Log(entry AccessEntry)
+	}
+
+	writerSink struct {
+		w io.Writer
+	}
+)
+
+// NewWriterSink returns the AccessLogSink writing the formatted lines to w,
+// which is what the Logger middleware does without a sink. Write errors are
+// dropped.
+func NewWriterSink(w io.Writer) AccessLogSink {
+	return writerSink{w: w}
+}
+
+func (s writerSink) Log(entry AccessEntry) {
+	_, _ = s.w.Write(entry.Line)
+}
+
+// newAccessEntry parses the access fields of c, line is its formatted text
+func newAccessEntry(c echo.Context, after bool, level logrus.Level, start time.Time, handlerErr error,
+	line []byte) AccessEntry {
+	req := c.Request()
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	bytesIn, _ := strconv.ParseInt(req.Header.Get(echo.HeaderContentLength), 10, 64)
+	entry := AccessEntry{
+		Time:      log.Now(),
+		After:     after,
+		Level:     level,
+		RequestId: GetRequestId(c),
+		RemoteIP:  c.RealIP(),
+		Method:    req.Method,
+		Host:      req.Host,
+		URI:       req.RequestURI,
+		Path:      path,
+		BytesIn:   bytesIn,
+		Line:      line,
+	}
+	if after {
+		entry.Status = c.Response().Status
+		entry.Latency = entry.Time.Sub(start)
+		entry.BytesOut = c.Response().Size
+		entry.Error = handlerErr
+	}
+	return entry
+}
@@ -0,0 +1,75 @@
+package httpx
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	mu      sync.Mutex
+	entries []AccessEntry
+}
+
+func (s *recordingSink) Log(entry AccessEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry.Line = append([]byte(nil), entry.Line...)
+	s.entries = append(s.entries, entry)
+}
+
+func TestAccessLogSink(t *testing.T) {
+	var (
+		buf  bytes.Buffer
+		sink recordingSink
+	)
+	e := newTestAccessLogEcho(&buf, "${method} ${path} ${status}", func(lc *LoggerConfig) {
+		lc.Timing = AccessLogBoth
+		lc.FormatBefore = "${method} ${path}"
+		lc.Sink = &sink
+	})
+	e.GET("/items/:id", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "no such item")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items/7", nil)
+	req.Header.Set(echo.HeaderXRequestID, "rid-1")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Empty(t, buf.String())
+	require.Len(t, sink.entries, 2)
+
+	before, after := sink.entries[0], sink.entries[1]
+	assert.False(t, before.After)
+	assert.Equal(t, "GET /items/7\n", string(before.Line))
+	assert.Equal(t, 0, before.Status)
+	assert.Nil(t, before.Error)
+
+	assert.True(t, after.After)
+	assert.Equal(t, "GET /items/7 404\n", string(after.Line))
+	assert.Equal(t, "rid-1", after.RequestId)
+	assert.Equal(t, http.MethodGet, after.Method)
+	assert.Equal(t, "/items/7", after.Path)
+	assert.Equal(t, http.StatusNotFound, after.Status)
+	assert.Positive(t, after.BytesOut)
+	assert.False(t, after.Time.Before(before.Time))
+	assert.EqualError(t, after.Error, "code=404, message=no such item")
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	e := newTestAccessLogEcho(&buf, "${method} ${status}", func(lc *LoggerConfig) {
+		lc.Sink = NewWriterSink(lc.Output)
+	})
+	e.GET("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "GET 204\n", buf.String())
+}
@@ -0,0 +1,102 @@
+package httpx
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// ConcurrencyStats is a snapshot of the admission control counters.
+type ConcurrencyStats struct {
+	Limit    int
+	InFlight int
+	Queued   int64
+	Rejected uint64
+}
+
+// admission caps the requests processed concurrently, see
+// ServerConfig.MaxConcurrentRequests.
+type admission struct {
+	sem           chan struct{}
+	queueWhenFull bool
+	queueTimeout  time.Duration
+
+	queued   atomic.Int64
+	rejected atomic.Uint64
+}
+
+func newAdmission(sc *ServerConfig) *admission {
+	if sc == nil || sc.MaxConcurrentRequests <= 0 {
+		return nil
+	}
+	return &admission{
+		sem:           make(chan struct{}, sc.MaxConcurrentRequests),
+		queueWhenFull: sc.QueueWhenFull,
+		queueTimeout:  sc.QueueTimeout,
+	}
+}
+
+func (ad *admission) stats() ConcurrencyStats {
+	return ConcurrencyStats{
+		Limit:    cap(ad.sem),
+		InFlight: len(ad.sem),
+		Queued:   ad.queued.Load(),
+		Rejected: ad.rejected.Load(),
+	}
+}
+
+// acquire takes a slot, waiting for one if queueWhenFull. It returns false
+// when the request is to be rejected.
+func (ad *admission) acquire(c echo.Context) (bool, error) {
+	select {
+	case ad.sem <- struct{}{}:
+		return true, nil
+	default:
+	}
+	if !ad.queueWhenFull {
+		return false, nil
+	}
+
+	ad.queued.Add(1)
+	defer ad.queued.Add(-1)
+
+	var timeout <-chan time.Time
+	if ad.queueTimeout > 0 {
+		t := time.NewTimer(ad.queueTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case ad.sem <- struct{}{}:
+		return true, nil
+	case <-timeout:
+		return false, nil
+	case <-c.Request().Context().Done():
+		return false, c.Request().Context().Err()
+	}
+}
+
+// admissionMiddleware applies agw.admission, set up by Run from ServerConf.
+func (agw *ApiGateway) admissionMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ad := agw.admission
+		if ad == nil {
+			return next(c)
+		}
+
+		ok, err := ad.acquire(c)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			ad.rejected.Add(1)
+			return SendResp(c, StatusResp(http.StatusServiceUnavailable))
+		}
+		defer func() { <-ad.sem }()
+
+		return next(c)
+	}
+}
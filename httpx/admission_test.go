@@ -0,0 +1,58 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmission(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		sc   ServerConfig
+		want int
+	}{
+		{"reject", ServerConfig{MaxConcurrentRequests: 1}, http.StatusServiceUnavailable},
+		{"queue timeout", ServerConfig{MaxConcurrentRequests: 1, QueueWhenFull: true, QueueTimeout: 20 * time.Millisecond}, http.StatusServiceUnavailable},
+		{"queue", ServerConfig{MaxConcurrentRequests: 1, QueueWhenFull: true}, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			agw := &ApiGateway{Echo: echo.New(), ServerConf: &tc.sc}
+			agw.admission = newAdmission(agw.ServerConf)
+			agw.Use(agw.admissionMiddleware)
+
+			entered, release := make(chan struct{}), make(chan struct{})
+			agw.GET("/slow", func(c echo.Context) error {
+				entered <- struct{}{}
+				<-release
+				return c.NoContent(http.StatusOK)
+			})
+			agw.GET("/fast", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+			go agw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+			<-entered
+			assert.Equal(t, 1, agw.Stats().Concurrency.InFlight)
+
+			done := make(chan int)
+			go func() {
+				rec := httptest.NewRecorder()
+				agw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+				done <- rec.Code
+			}()
+			if tc.sc.QueueWhenFull && tc.sc.QueueTimeout == 0 {
+				require.Eventually(t, func() bool { return agw.Stats().Concurrency.Queued == 1 }, time.Second, time.Millisecond)
+				close(release)
+				assert.Equal(t, tc.want, <-done)
+				return
+			}
+			assert.Equal(t, tc.want, <-done)
+			close(release)
+			assert.Equal(t, uint64(1), agw.Stats().Concurrency.Rejected)
+		})
+	}
+}
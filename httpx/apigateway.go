@@ -3,26 +3,136 @@ package httpx
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/labstack/echo"
-	"github.com/labstack/echo/middleware"
 	labstacklog "github.com/labstack/gommon/log"
+	"github.com/madlabx/pkgx/errors"
 	"github.com/madlabx/pkgx/log"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	DefaultBodyBufferSize = 4096
+
+	defaultShutdownTimeout = 5 * time.Second
 )
 
+// drainLogInterval is the pace of logging the requests in flight when
+// stopping, it exists so it can be shortened by tests.
+var drainLogInterval = time.Second
+
 type LogConfig struct {
-	LogFile        log.FileConfig
+	LogFile log.FileConfig
+	// Level is the level of the access Logger, which is its own instance even
+	// when writing to the main log output, so it is independent of the
+	// application log level set through the log package.
 	Level          string          `vx_default:"info"`
 	Timing         AccessLogTiming `vx_default:"both"`
 	BodyBufferSize int64           `vx_default:"4096"`
+	// Format is the formatter of the access Logger when NewApiGateway is not
+	// given one, text, json or systemd, see log.NewFormatter.
+	Format string `vx_default:"text"`
+	// BodyDumpSampleRate is the fraction of requests, e.g. 0.01, whose bodies
+	// are dumped in their access line. Every request is still logged, the
+	// access log is not sampled, so it stays complete while the cost and
+	// privacy exposure of bodies are bounded. See LoggerConfig.
+	BodyDumpSampleRate float64 `vx_default:"1"`
+	// BodyDumpOnErrorOnly dumps the bodies only in the access line of 4xx and
+	// 5xx responses, see LoggerConfig.
+	BodyDumpOnErrorOnly bool `vx_default:"false"`
+	// BodyDumpLimits overrides BodyBufferSize by Content-Type pattern, e.g.
+	// {"application/json": 65536, "image/*": 64}, see LoggerConfig.
+	BodyDumpLimits map[string]int
+	// BodyDumpMaxBuffer caps the response body held for the dump, a larger
+	// one is dumped truncated, see LoggerConfig.
+	BodyDumpMaxBuffer int `vx_default:"1048576"`
+	// RequestIdHeaders are the trusted headers carrying the request id from
+	// upstream, checked in order, e.g. X-Request-ID, X-Correlation-ID, traceparent.
+	// An id is generated if none is present. Default X-Request-ID.
+	RequestIdHeaders []string
+	// Structured logs access entries as fields through the access Logger, good
+	// with a JSON formatter. false keeps the single line ContentFormatBefore/After.
+	Structured bool `vx_default:"false"`
+	// PanicStackFrames is the number of stack frames making up the signature of
+	// a recovered panic, PanicDedupWindow the period in which panics of the same
+	// signature are reported once, see RecoverConfig.
+	PanicStackFrames int           `vx_default:"8"`
+	PanicDedupWindow time.Duration `vx_default:"1m"`
+	// PreflightLog is one of log, debug and skip, to keep CORS preflight
+	// requests out of the access log, see PreflightLogging.
+	PreflightLog PreflightLogging `vx_default:"log"`
+	// ETag sets a body hash as ETag of 2xx GET responses up to ETagMaxSize
+	// bytes and answers 304 to a matching If-None-Match, see LoggerConfig.ETag.
+	ETag        bool  `vx_default:"false"`
+	ETagMaxSize int64 `vx_default:"1048576"`
+	// AllowedContentTypes, if set, answers 415 to the requests whose body has
+	// another Content-Type, see ContentTypeConfig. AllowContentTypes does it
+	// per route.
+	AllowedContentTypes []string
+	// TrailingSlash is one of ignore, strip, add and redirect, see
+	// TrailingSlash. Redirects are answered with TrailingSlashRedirectCode,
+	// 301 or 308, before the access logger so they are not logged.
+	TrailingSlash             TrailingSlash `vx_default:"ignore"`
+	TrailingSlashRedirectCode int           `vx_default:"301"`
+	// MethodOverrideHeader and MethodOverrideForm, e.g. X-HTTP-Method-Override
+	// and _method, let a POST carry the PUT, PATCH or DELETE it stands for, for
+	// the clients which can only send GET and POST, see
+	// MethodOverrideWithConfig. Empty for both, the default, disables it.
+	MethodOverrideHeader string
+	MethodOverrideForm   string
+	// AccessLogSink, if set, receives the access entries instead of the access
+	// Logger output, e.g. to ship them to a collector, see AccessLogSink. Not
+	// used with Structured.
+	AccessLogSink AccessLogSink
+	// StatusLevels sets the level of the access entries by status class,
+	// e.g. {"5xx": "error", "4xx": "warn"}, the others stay at info, and
+	// entries below Level are dropped, see LoggerConfig.StatusLevels.
+	StatusLevels map[string]string
+	// ErrorLevels sets the level of the log of the errors returned by the
+	// handlers and middleware, by status class, e.g. {"4xx": "debug"},
+	// replacing the default 5xx at error and 4xx at warn, the others at info.
+	// Every error reaching the HTTPErrorHandler of the Echo is logged through
+	// Logger with the request id, method, path and status, then written.
+	ErrorLevels map[string]string
+	// StreamLogFrames and StreamLogInterval log a summary of the frames and
+	// bytes sent on a WebSocket or SSE stream, told by its Upgrade or Accept
+	// header, every StreamLogFrames frames or every StreamLogInterval,
+	// whichever comes first, and once it is over, to the access log at info.
+	// A frame is a write of the handler, the interval is checked on a write,
+	// an idle stream logs nothing. 0 for both, the default, disables it.
+	StreamLogFrames   int           `vx_default:"0"`
+	StreamLogInterval time.Duration `vx_default:"0"`
+	// Coalesce makes identical GET/HEAD requests in flight, same URI and
+	// Authorization, Cookie, Accept and Accept-Encoding headers, share the
+	// response of the first one, whose handler alone runs, e.g. against a
+	// thundering herd on a cache expiry. Only enable it for idempotent GET
+	// routes. Responses over CoalesceMaxSize bytes, or failed, are not shared,
+	// the waiters run their handler then. See CoalesceStats.
+	Coalesce        bool  `vx_default:"false"`
+	CoalesceMaxSize int64 `vx_default:"1048576"`
+	// AuditLog is the output of Audit, e.g. its own rotated file, nil
+	// disables the audit log.
+	AuditLog *log.FileConfig
+	// QuietStartup suppresses the "startup config" entry logged at info by
+	// NewApiGateway with the output, level and rotation settings in use.
+	QuietStartup bool `vx_default:"false"`
+	// SNIStrict rejects the TLS handshakes whose server name matches none of
+	// the certificates added by AddCertificate, which otherwise get the
+	// certificate of RunTLS/RunMTLS. See AddCertificate.
+	SNIStrict bool `vx_default:"false"`
+	// AutoHead answers the HEAD requests of the paths with a GET route and
+	// no HEAD one with the GET handler, instead of 405. The handler and the
+	// middleware see a HEAD request, http.Server discards the body written and
+	// keeps the headers, Content-Length included, computed from the body if
+	// unset and small enough to be buffered. The runtime routes of AddRoute
+	// are not covered.
+	AutoHead bool `vx_default:"false"`
 	// Tags to construct the Logger format.
 	//
 	// - time_unix
@@ -39,6 +149,7 @@ type LogConfig struct {
 	// - protocol
 	// - referer
 	// - user_agent
+	// - client_cn (CommonName of verified client certificate, mutual TLS only)
 	// - status
 	// - error
 	// - latency (In nanoseconds)
@@ -63,12 +174,61 @@ type ApiGateway struct {
 	Logger      *log.Logger
 	LogConf     *LogConfig
 	EntryFormat logrus.Formatter
+	// ServerConf is read by Run/RunTLS, set it before starting the server.
+	ServerConf *ServerConfig
+	// OnPanic reports the panics recovered from handlers, see
+	// RecoverConfig.OnPanic. nil logs them to Logger.
+	OnPanic func(sig string, count int, rec interface{})
+	// RequestValidator, if set, checks every request before its handler,
+	// see RequestValidator.
+	RequestValidator RequestValidator
+
+	requests         requestCounter
+	admission        *admission
+	requestTimeout   *requestTimeout
+	routeTimeouts    routeTimeouts
+	multipartMemory  int64
+	latency          *latencySummary
+	bodyDumpPolicies bodyDumpPolicies
+	responseCache    *ResponseCache
+	tlsStats         *tlsStats
+	sniCerts         sniCertificates
+	auditLogger      *log.Logger
+	breakersMu       sync.Mutex
+	breakers         []*CircuitBreaker
+	probes           *probes
+	readinessMu      sync.Mutex
+	readinessChecks  []namedCheck
+	dynamic          dynamicRoutes
+	coalescer        *coalescer
+	statusLevels     StatusLevelMap
+	errorLevels      StatusLevelMap
+	cors             atomic.Pointer[echo.MiddlewareFunc]
+	shutdownMu       sync.Mutex
+	shutdownHooks    []func(ctx context.Context) error
 }
 
 func NewApiGateway(pCtx context.Context, lc *LogConfig, logFormat logrus.Formatter) (*ApiGateway, error) {
+	return NewApiGatewayWithEcho(pCtx, lc, logFormat, nil)
+}
+
+// NewApiGatewayWithEcho builds the gateway on e, already configured by the
+// caller, a nil e behaves like NewApiGateway. Binder, Validator, Renderer,
+// HTTPErrorHandler and the routes of e are kept, the errors being logged
+// before HTTPErrorHandler writes them, see LogConfig.ErrorLevels. The gateway
+// overrides the output and level of e.Logger and appends its middleware
+// (FromContext, request counts, latency, request id, access log, stream
+// summaries, CORS, content type, readiness, admission, timeout, multipart,
+// recover, coalescing, validator, runtime routes) with e.Use, after the
+// middleware already installed on e, plus the trailing slash handling of
+// LogConfig.TrailingSlash with e.Pre.
+func NewApiGatewayWithEcho(pCtx context.Context, lc *LogConfig, logFormat logrus.Formatter, e *echo.Echo) (*ApiGateway, error) {
+	if e == nil {
+		e = echo.New()
+	}
 	agw := &ApiGateway{
 		ctx:         context.WithoutCancel(pCtx),
-		Echo:        echo.New(),
+		Echo:        e,
 		LogConf:     lc,
 		EntryFormat: logFormat,
 	}
@@ -77,8 +237,10 @@ func NewApiGateway(pCtx context.Context, lc *LogConfig, logFormat logrus.Formatt
 	if err := agw.initAccessLog(); err != nil {
 		return nil, err
 	}
+	agw.initAuditLog()
 
 	agw.configEcho()
+	agw.wrapHTTPErrorHandler()
 	return agw, nil
 }
 
@@ -86,33 +248,95 @@ func (agw *ApiGateway) Run(ip, port string) error {
 	return agw.startEcho(fmt.Sprintf("%s:%s", ip, port))
 }
 
+// Stop ends the readiness polling of ServeProbes, shuts the server down
+// gracefully, see ServerConfig.ShutdownTimeout, runs the hooks registered by OnShutdown, then flushes and closes the access
+// and audit log outputs, see log.CloseLogger, so that the last entries are
+// persisted. The output of LogFile "main" belongs to the standard logger and
+// is left to the application, see log.Close.
 func (agw *ApiGateway) Stop() error {
-	return agw.shutdownEcho()
+	agw.stopReadinessPolling()
+	err := agw.shutdownEcho()
+	err = errors.Join(err, agw.runShutdownHooks())
+	if agw.LogConf.LogFile.Filename != "main" {
+		if cerr := log.CloseLogger(agw.Logger); cerr != nil && err == nil {
+			err = errors.Wrap(cerr)
+		}
+	}
+	if agw.auditLogger != nil && agw.LogConf.AuditLog.Filename != "main" {
+		if cerr := log.CloseLogger(agw.auditLogger); cerr != nil && err == nil {
+			err = errors.Wrap(cerr)
+		}
+	}
+	return err
 }
 
 func (agw *ApiGateway) initAccessLog() error {
 	if agw.LogConf == nil {
-		agw.LogConf = &LogConfig{}
-		agw.Logger = log.StandardLogger()
-	} else {
-		agw.Logger = log.NewLogger(agw.ctx, agw.LogConf.LogFile)
+		agw.LogConf = &LogConfig{LogFile: log.FileConfig{Filename: "main"}}
 	}
+	// never share the standard logger, its level belongs to the application
+	agw.Logger = log.NewLogger(agw.ctx, agw.LogConf.LogFile)
 
-	level, err := logrus.ParseLevel(agw.LogConf.Level)
-	if err != nil {
+	if agw.LogConf.Level == "" {
+		agw.LogConf.Level = logrus.InfoLevel.String()
+	}
+	if err := agw.SetAccessLogLevel(agw.LogConf.Level); err != nil {
 		return err
 	}
-	agw.Logger.SetLevel(level)
 
 	// Set body format
 	if agw.EntryFormat == nil {
-		agw.EntryFormat = &log.TextFormatter{QuoteEmptyFields: true}
+		f, err := log.NewFormatter(agw.LogConf.Format)
+		if err != nil {
+			return err
+		}
+		agw.EntryFormat = f
 	}
 	agw.Logger.SetFormatter(agw.EntryFormat)
 
+	levels, err := ParseStatusLevels(agw.LogConf.StatusLevels)
+	if err != nil {
+		return err
+	}
+	agw.statusLevels = levels
+
+	if len(agw.LogConf.ErrorLevels) > 0 {
+		if agw.errorLevels, err = ParseStatusLevels(agw.LogConf.ErrorLevels); err != nil {
+			return err
+		}
+	} else {
+		agw.errorLevels = SeverityStatusLevels
+	}
+
+	if !agw.LogConf.QuietStartup {
+		agw.logStartupConfig()
+	}
 	return nil
 }
 
+// logStartupConfig logs the access log settings applied, to tell from the log
+// itself how it is written and rotated
+func (agw *ApiGateway) logStartupConfig() {
+	conf := agw.LogConf
+	agw.Logger.InfoKV("startup config",
+		"output", conf.LogFile.Filename,
+		"level", agw.Logger.GetLevel().String(),
+		"format", conf.Format,
+		"structured", conf.Structured,
+		"max_size_mb", conf.LogFile.MaxSize,
+		"max_age_days", conf.LogFile.MaxAge,
+		"max_backups", conf.LogFile.MaxBackups,
+		"compress", conf.LogFile.Compress,
+		"local_time", conf.LogFile.LocalTime,
+		"fallback", conf.LogFile.Fallback)
+}
+
+// SetAccessLogLevel changes the level of the access Logger only, the
+// application log level is left alone.
+func (agw *ApiGateway) SetAccessLogLevel(level string) error {
+	return log.SetLoggerLevel(agw.Logger, level)
+}
+
 func (agw *ApiGateway) configEcho() {
 	var (
 		e = agw.Echo
@@ -129,6 +353,27 @@ func (agw *ApiGateway) configEcho() {
 		e.Logger.SetLevel(labstacklog.INFO)
 	}
 
+	if mw := trailingSlashMiddleware(agw.LogConf.TrailingSlash, agw.LogConf.TrailingSlashRedirectCode); mw != nil {
+		e.Pre(mw)
+	}
+	if mw := agw.methodOverrideMiddleware(); mw != nil {
+		e.Pre(mw)
+	}
+	if agw.LogConf.AutoHead {
+		e.Pre(agw.autoHeadMiddleware)
+		e.Use(headRestoreMiddleware)
+	}
+
+	e.Use(agw.contextMiddleware)
+
+	e.Use(agw.requests.middleware)
+
+	e.Use(agw.latencyMiddleware)
+
+	e.Use(RequestIdWithConfig(RequestIdConfig{
+		Headers: agw.LogConf.RequestIdHeaders,
+	}))
+
 	e.Use(LoggerWithConfig(LoggerConfig{
 		OutBodyFilter: func(c echo.Context) bool {
 			//文件上传下载不要打印
@@ -139,23 +384,58 @@ func (agw *ApiGateway) configEcho() {
 			//}
 			return true
 		},
-		FormatAfter:      agw.LogConf.ContentFormatAfter,
-		FormatBefore:     agw.LogConf.ContentFormatBefore,
-		CustomTimeFormat: "2006/01/02 15:04:05.000",
-		Output:           agw.Logger.Out,
-		bodyBufferSize:   agw.LogConf.BodyBufferSize,
-		Timing:           agw.LogConf.Timing,
+		FormatAfter:         agw.LogConf.ContentFormatAfter,
+		FormatBefore:        agw.LogConf.ContentFormatBefore,
+		CustomTimeFormat:    "2006/01/02 15:04:05.000",
+		BodyDumpPolicy:      agw.bodyDumpPolicies.lookup,
+		BodyDumpSampleRate:  agw.LogConf.BodyDumpSampleRate,
+		BodyDumpOnErrorOnly: agw.LogConf.BodyDumpOnErrorOnly,
+		BodyDumpLimits:      agw.LogConf.BodyDumpLimits,
+		BodyDumpMaxBuffer:   agw.LogConf.BodyDumpMaxBuffer,
+		Output:              agw.Logger.Out,
+		Sink:                agw.LogConf.AccessLogSink,
+		Structured:          agw.LogConf.Structured,
+		Preflight:           agw.LogConf.PreflightLog,
+		ETag:                agw.LogConf.ETag,
+		ETagMaxSize:         agw.LogConf.ETagMaxSize,
+		Logger:              agw.Logger,
+		StatusLevels:        agw.statusLevels,
+		bodyBufferSize:      agw.LogConf.BodyBufferSize,
+		Timing:              agw.LogConf.Timing,
 	}))
 
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins:     []string{"*"},
-		ExposeHeaders:    []string{"*"},
-		AllowMethods:     []string{"*"},
-		AllowHeaders:     []string{"*"},
-		AllowCredentials: true,
-		//AllowMethods: []string{Echo.GET, Echo.PUT, Echo.POST, Echo.DELETE},
+	e.Use(agw.streamLogMiddleware)
+
+	agw.SetCORS(globalCORSConfig)
+	e.Use(agw.corsMiddleware)
+
+	e.Use(ContentTypeWithConfig(ContentTypeConfig{
+		AllowedContentTypes: agw.LogConf.AllowedContentTypes,
+	}))
+
+	e.Use(agw.readinessMiddleware)
+
+	e.Use(agw.admissionMiddleware)
+
+	e.Use(agw.timeoutMiddleware)
+
+	e.Use(agw.multipartMiddleware)
+
+	e.Use(RecoverWithConfig(RecoverConfig{
+		StackFrames: agw.LogConf.PanicStackFrames,
+		DedupWindow: agw.LogConf.PanicDedupWindow,
+		OnPanic:     agw.onPanic,
 	}))
 
+	if agw.LogConf.Coalesce {
+		agw.coalescer = newCoalescer(agw.LogConf.CoalesceMaxSize)
+		e.Use(agw.coalescer.middleware)
+	}
+
+	e.Use(agw.validatorMiddleware)
+
+	e.Use(agw.dynamicRoutesMiddleware)
+
 	//TODO 检查是否可以恢复。不注释回无法下载css
 	//e.Use(func(next Echo.HandlerFunc) Echo.HandlerFunc {
 	//	return func(c Echo.Context) error {
@@ -165,14 +445,88 @@ func (agw *ApiGateway) configEcho() {
 	//})
 }
 
+func (agw *ApiGateway) onPanic(sig string, count int, rec interface{}) {
+	if agw.OnPanic != nil {
+		agw.OnPanic(sig, count, rec)
+		return
+	}
+	agw.Logger.Errorf("recovered panic, sig=%s count=%d: %v", sig, count, rec)
+}
+
+// UseResponseCache installs an in-memory response cache for GET/HEAD, its
+// counters are reported by Stats. Use the returned cache to set per-route TTLs.
+func (agw *ApiGateway) UseResponseCache(config CacheConfig) *ResponseCache {
+	agw.responseCache = NewResponseCache(config)
+	agw.Echo.Use(agw.responseCache.Middleware())
+	return agw.responseCache
+}
+
+// Handler returns the gateway as a http.Handler, with every middleware wired
+// up by NewApiGateway and the admission control, request timeout and latency
+// summary of ServerConf, and the readiness polling of ServeProbes, without
+// binding a port. Meant for tests through httptest.
+func (agw *ApiGateway) Handler() http.Handler {
+	agw.admission = newAdmission(agw.ServerConf)
+	agw.requestTimeout = newRequestTimeout(agw.ServerConf)
+	agw.latency = newLatencySummary(agw.ServerConf)
+	agw.multipartMemory = newMultipartMemory(agw.ServerConf)
+	agw.startReadinessPolling()
+	return agw.Echo
+}
+
 func (agw *ApiGateway) startEcho(addr string) error {
+	l, err := agw.newListener(addr)
+	if err != nil {
+		return err
+	}
+	if l != nil {
+		agw.Echo.Listener = l
+	}
+	agw.applyServerConfig(agw.Echo.Server)
 	return agw.Echo.Start(addr)
 }
 
+func (agw *ApiGateway) shutdownTimeout() time.Duration {
+	if sc := agw.ServerConf; sc != nil && sc.ShutdownTimeout > 0 {
+		return sc.ShutdownTimeout
+	}
+	return defaultShutdownTimeout
+}
+
 func (agw *ApiGateway) shutdownEcho() error {
-	ctx, cancel := context.WithTimeout(agw.ctx, 5*time.Second)
+	timeout := agw.shutdownTimeout()
+	ctx, cancel := context.WithTimeout(agw.ctx, timeout)
 	defer cancel()
-	return agw.Echo.Shutdown(ctx)
+
+	done := make(chan struct{})
+	defer close(done)
+	go agw.logDraining(done, drainLogInterval)
+
+	err := agw.Echo.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		if n := agw.InFlight(); n > 0 {
+			agw.Logger.Warnf("shutdown timed out after %v with %d requests in flight, closing them, active routes: %s",
+				timeout, n, agw.requests.activeRoutes())
+		}
+		_ = agw.Echo.Close()
+	}
+	return err
+}
+
+// logDraining logs the requests in flight every interval until done
+func (agw *ApiGateway) logDraining(done <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if n := agw.InFlight(); n > 0 {
+				agw.Logger.Infof("shutting down, draining %d requests in flight", n)
+			}
+		}
+	}
 }
 
 func (agw *ApiGateway) RoutesToString() string {
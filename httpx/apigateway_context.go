@@ -0,0 +1,25 @@
+package httpx
+
+import (
+	"github.com/labstack/echo"
+)
+
+// ContextKeyGateway is the echo.Context key holding the *ApiGateway serving
+// the request, read by FromContext. A handler under test can be given a fake
+// gateway with c.Set(ContextKeyGateway, agw).
+const ContextKeyGateway = "httpx.gateway"
+
+// FromContext returns the gateway serving the request, to reach its Logger,
+// Stats or config from a handler, nil if the context was not set up by an
+// ApiGateway.
+func FromContext(c echo.Context) *ApiGateway {
+	agw, _ := c.Get(ContextKeyGateway).(*ApiGateway)
+	return agw
+}
+
+func (agw *ApiGateway) contextMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Set(ContextKeyGateway, agw)
+		return next(c)
+	}
+}
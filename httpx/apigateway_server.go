@@ -0,0 +1,199 @@
+package httpx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/madlabx/pkgx/errors"
+)
+
+// ServerConfig tunes the listener and http.Server used by Run/RunTLS.
+// A nil or zero ServerConfig keeps the default Echo behavior.
+type ServerConfig struct {
+	// Backlog is the length of the TCP accept queue, 0 keeps the system
+	// default (net.core.somaxconn on Linux). Linux/BSD only.
+	Backlog int
+
+	// ReusePort sets SO_REUSEPORT on the listening socket, so that several
+	// processes can accept on the same port. Linux/BSD only.
+	ReusePort bool
+
+	// DisableKeepAlives closes the connection after each response, which
+	// then carries "Connection: close".
+	DisableKeepAlives bool
+
+	// IdleConnTimeout is how long a keep-alive connection waits for the next
+	// request, 0 keeps the net/http default (ReadTimeout or unlimited).
+	IdleConnTimeout time.Duration
+
+	// MaxIdleConns caps the keep-alive connections waiting for a request,
+	// a connection going idle beyond the cap is closed. 0 means unlimited.
+	MaxIdleConns int
+
+	// MaxConcurrentRequests caps the requests processed at the same time,
+	// excess ones are rejected with 503 or queued, see QueueWhenFull.
+	// 0 means unlimited.
+	MaxConcurrentRequests int
+
+	// QueueWhenFull makes excess requests wait for a free slot up to
+	// QueueTimeout instead of being rejected at once. A QueueTimeout of 0
+	// waits until the client gives up.
+	QueueWhenFull bool
+	QueueTimeout  time.Duration
+
+	// RequestTimeout bounds the context of each request, exposed to handlers
+	// through RequestDeadline. 0 means unlimited. SetRouteTimeout overrides
+	// it for a route.
+	RequestTimeout time.Duration
+
+	// PropagateDeadline also bounds the request by the X-Request-Deadline
+	// header of the upstream hop, handlers pass the deadline on to the next
+	// one with ForwardDeadline.
+	PropagateDeadline bool
+
+	// ProxyProtocol expects the PROXY protocol header, v1 or v2, of a L4 load
+	// balancer like HAProxy or an AWS NLB first on every connection, and takes
+	// the client address from it, so RemoteAddr and RealIP are the client's.
+	// A connection with a missing or malformed header is dropped, within
+	// ProxyHeaderTimeout, default 5s. Only enable it behind such a balancer.
+	ProxyProtocol      bool
+	ProxyHeaderTimeout time.Duration
+
+	// ShutdownTimeout bounds the draining of the requests in flight by Stop,
+	// which logs their count meanwhile, the requests still running then are
+	// logged by route and their connections closed. Default 5s.
+	ShutdownTimeout time.Duration
+
+	// MaxMultipartMemory is the memory a multipart form is parsed into, up
+	// to which its file parts are held in memory, the rest being spilled to
+	// temporary files, removed once the request is done. The forms are then
+	// parsed before the handler, so a handler streaming the parts with
+	// Request.MultipartReader needs it 0, the default, which leaves the
+	// parsing to the handler, with the 32MB of Go. The temporary files go to
+	// os.TempDir, set TMPDIR to move them, Go has no other way.
+	MaxMultipartMemory int64
+
+	// LatencyWindow is the number of recent requests whose latency is
+	// summarized in the p50/p90/p99 of Stats, 0 disables the summary.
+	LatencyWindow int
+}
+
+func (sc *ServerConfig) needCustomListener() bool {
+	return sc != nil && (sc.Backlog > 0 || sc.ReusePort || sc.ProxyProtocol)
+}
+
+// newListener builds the listener for addr according to agw.ServerConf.
+// It returns nil when the default Echo listener is fine.
+func (agw *ApiGateway) newListener(addr string) (net.Listener, error) {
+	sc := agw.ServerConf
+	if !sc.needCustomListener() {
+		return nil, nil
+	}
+
+	lc := net.ListenConfig{
+		Control: func(_, _ string, rc syscall.RawConn) error {
+			if !sc.ReusePort {
+				return nil
+			}
+			var sockErr error
+			if err := rc.Control(func(fd uintptr) { sockErr = setReusePort(fd) }); err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+
+	l, err := lc.Listen(context.WithoutCancel(agw.ctx), "tcp", addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to listen on %s", addr)
+	}
+
+	if sc.Backlog > 0 {
+		if err = applyBacklog(l, sc.Backlog); err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+	}
+
+	if sc.ProxyProtocol {
+		l = newProxyProtoListener(l, sc.ProxyHeaderTimeout)
+	}
+	return l, nil
+}
+
+func applyBacklog(l net.Listener, backlog int) error {
+	tl, ok := l.(*net.TCPListener)
+	if !ok {
+		return errors.Errorf("backlog needs a tcp listener, got %T", l)
+	}
+
+	rc, err := tl.SyscallConn()
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	var sockErr error
+	if err = rc.Control(func(fd uintptr) { sockErr = setBacklog(fd, backlog) }); err != nil {
+		return errors.Wrap(err)
+	}
+	if sockErr != nil {
+		return errors.Wrapf(sockErr, "failed to set backlog %d", backlog)
+	}
+	return nil
+}
+
+// applyServerConfig sets the connection lifecycle options on s, the
+// admission control, the request timeout and the latency summary, and starts
+// the readiness polling.
+func (agw *ApiGateway) applyServerConfig(s *http.Server) {
+	sc := agw.ServerConf
+	agw.admission = newAdmission(sc)
+	agw.requestTimeout = newRequestTimeout(sc)
+	agw.latency = newLatencySummary(sc)
+	agw.multipartMemory = newMultipartMemory(sc)
+	agw.startReadinessPolling()
+	if sc == nil {
+		return
+	}
+
+	if sc.DisableKeepAlives {
+		s.SetKeepAlivesEnabled(false)
+	}
+	if sc.IdleConnTimeout > 0 {
+		s.IdleTimeout = sc.IdleConnTimeout
+	}
+	if sc.MaxIdleConns > 0 {
+		s.ConnState = newIdleConnLimiter(sc.MaxIdleConns, s.ConnState)
+	}
+}
+
+// newIdleConnLimiter returns a http.Server ConnState hook closing connections
+// which go idle while max connections are idle already.
+func newIdleConnLimiter(max int, chained func(net.Conn, http.ConnState)) func(net.Conn, http.ConnState) {
+	var (
+		mu   sync.Mutex
+		idle = make(map[net.Conn]struct{})
+	)
+	return func(conn net.Conn, state http.ConnState) {
+		mu.Lock()
+		if state == http.StateIdle {
+			if len(idle) >= max {
+				mu.Unlock()
+				_ = conn.Close()
+				return
+			}
+			idle[conn] = struct{}{}
+		} else {
+			delete(idle, conn)
+		}
+		mu.Unlock()
+
+		if chained != nil {
+			chained(conn, state)
+		}
+	}
+}
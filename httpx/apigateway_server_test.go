@@ -0,0 +1,28 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerConfigDisableKeepAlives(t *testing.T) {
+	agw := &ApiGateway{ServerConf: &ServerConfig{DisableKeepAlives: true}}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	agw.applyServerConfig(ts.Config)
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	// the client consumes the "Connection: close" header into resp.Close
+	assert.True(t, resp.Close)
+}
@@ -0,0 +1,52 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package httpx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerConfigReusePort(t *testing.T) {
+	first := &ApiGateway{ctx: context.Background(), ServerConf: &ServerConfig{ReusePort: true}}
+	l1, err := first.newListener("127.0.0.1:0")
+	require.NoError(t, err)
+	defer l1.Close()
+	addr := l1.Addr().String()
+
+	second := &ApiGateway{ctx: context.Background(), ServerConf: &ServerConfig{ReusePort: true}}
+	l2, err := second.newListener(addr)
+	require.NoError(t, err, "both gateways bind the port")
+	defer l2.Close()
+
+	// a socket without SO_REUSEPORT may not join them
+	plain := &ApiGateway{ctx: context.Background(), ServerConf: &ServerConfig{Backlog: 16}}
+	_, err = plain.newListener(addr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to listen on "+addr)
+}
+
+func TestServerConfigBacklog(t *testing.T) {
+	agw := &ApiGateway{ctx: context.Background(), ServerConf: &ServerConfig{Backlog: 16}}
+	l, err := agw.newListener("127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	go func() { _ = s.Serve(l) }()
+	defer s.Close()
+
+	resp, err := http.Get("http://" + l.Addr().String())
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	assert.Error(t, applyBacklog(&net.UnixListener{}, 16), "not a tcp listener")
+}
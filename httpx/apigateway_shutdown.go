@@ -0,0 +1,45 @@
+package httpx
+
+import (
+	"context"
+
+	"github.com/madlabx/pkgx/errors"
+)
+
+// OnShutdown registers fn to run by Stop, e.g. to close a DB pool or to
+// deregister from the service discovery. The hooks run once the server is
+// drained, the requests in flight done or closed at the ShutdownTimeout of
+// ServerConfig, so that no handler sees the resources go away, and before
+// the access and audit logs are closed, so that they can log.
+//
+// They run one after the other in the reverse order of registration, like
+// defer, the resources acquired last released first. ctx bounds all of them
+// by ShutdownTimeout, counted from the end of the draining. A hook failing
+// does not stop the next ones, the errors are logged and returned by Stop
+// joined.
+func (agw *ApiGateway) OnShutdown(fn func(ctx context.Context) error) {
+	agw.shutdownMu.Lock()
+	defer agw.shutdownMu.Unlock()
+	agw.shutdownHooks = append(agw.shutdownHooks, fn)
+}
+
+func (agw *ApiGateway) runShutdownHooks() error {
+	agw.shutdownMu.Lock()
+	hooks := append([]func(ctx context.Context) error(nil), agw.shutdownHooks...)
+	agw.shutdownMu.Unlock()
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(agw.ctx, agw.shutdownTimeout())
+	defer cancel()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			agw.Logger.Errorf("shutdown hook %d failed: %v", i, err)
+			errs = append(errs, errors.Wrap(err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,62 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnShutdown(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+	var out syncBuffer
+	agw.Logger.SetOutput(&out)
+	agw.ServerConf = &ServerConfig{ShutdownTimeout: time.Second}
+
+	handlerDone := make(chan struct{})
+	agw.GET("/slow", func(c echo.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		close(handlerDone)
+		return c.NoContent(http.StatusOK)
+	})
+
+	var order []string
+	errDB := errors.New("db close failed")
+	agw.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "discovery")
+		return nil
+	})
+	agw.OnShutdown(func(ctx context.Context) error {
+		// after the draining
+		select {
+		case <-handlerDone:
+		default:
+			t.Error("hook run before the requests in flight are done")
+		}
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		order = append(order, "db")
+		return errDB
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	agw.Echo.Listener = l
+	go func() { _ = agw.Run("127.0.0.1", "0") }()
+
+	go func() { _, _ = http.Get("http://" + l.Addr().String() + "/slow") }()
+	require.Eventually(t, func() bool { return agw.InFlight() == 1 }, time.Second, 5*time.Millisecond)
+
+	err = agw.Stop()
+	assert.ErrorIs(t, err, errDB)
+	assert.Equal(t, []string{"db", "discovery"}, order)
+	assert.Contains(t, out.String(), "db close failed")
+}
@@ -0,0 +1,39 @@
+package httpx
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/madlabx/pkgx/errors"
+)
+
+// RunUntilSignal runs the server like Run until one of signals, SIGINT and
+// SIGTERM by default, is received, then shuts it down gracefully and returns.
+// The server error is returned if it fails to start or stops by itself.
+func (agw *ApiGateway) RunUntilSignal(ip, port string, signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, signals...)
+	defer signal.Stop(sigCh)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- agw.Run(ip, port) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-sigCh:
+	}
+
+	if err := agw.Stop(); err != nil {
+		return err
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
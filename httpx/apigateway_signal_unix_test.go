@@ -0,0 +1,37 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package httpx
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiGatewayRunUntilSignal(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{
+		LogFile: log.FileConfig{Filename: "discard"},
+	}, nil)
+	require.NoError(t, err)
+	agw.HideBanner = true
+
+	done := make(chan error, 1)
+	go func() { done <- agw.RunUntilSignal("127.0.0.1", "0", syscall.SIGUSR1) }()
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+
+	select {
+	case err = <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop on signal")
+	}
+}
@@ -0,0 +1,127 @@
+package httpx
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/labstack/echo"
+)
+
+// GatewayStats is a snapshot of the gateway counters, the section of a feature
+// not enabled is nil.
+type GatewayStats struct {
+	Requests    RequestCounts
+	Concurrency *ConcurrencyStats `json:",omitempty"`
+	Cache       *CacheStats       `json:",omitempty"`
+	Latency     *LatencyStats     `json:",omitempty"`
+	Coalesce    *CoalesceStats    `json:",omitempty"`
+	// TLS is set once served over TLS, see TLSStats.
+	TLS *TLSStats `json:",omitempty"`
+	// Circuits are the circuits of the breakers made by NewCircuitBreaker.
+	Circuits map[string]CircuitStats `json:",omitempty"`
+}
+
+// RequestCounts counts the requests served. CORS preflight requests are kept
+// out of Total and counted in Preflight. InFlight are the requests being
+// processed, preflight ones included.
+type RequestCounts struct {
+	Total     uint64
+	Preflight uint64
+	InFlight  int64
+}
+
+type requestCounter struct {
+	total     atomic.Uint64
+	preflight atomic.Uint64
+	inFlight  atomic.Int64
+	// method and route path -> *atomic.Int64 of its requests in flight
+	active sync.Map
+}
+
+func (rc *requestCounter) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if IsPreflight(c) {
+			rc.preflight.Add(1)
+		} else {
+			rc.total.Add(1)
+		}
+
+		rc.inFlight.Add(1)
+		defer rc.inFlight.Add(-1)
+		key := c.Request().Method + " " + c.Path()
+		v, ok := rc.active.Load(key)
+		if !ok {
+			v, _ = rc.active.LoadOrStore(key, new(atomic.Int64))
+		}
+		route := v.(*atomic.Int64)
+		route.Add(1)
+		defer route.Add(-1)
+
+		return next(c)
+	}
+}
+
+// activeRoutes lists the routes with requests in flight and their count, e.g.
+// "GET /files/:id=2, POST /upload=1", sorted
+func (rc *requestCounter) activeRoutes() string {
+	var routes []string
+	rc.active.Range(func(key, value interface{}) bool {
+		if n := value.(*atomic.Int64).Load(); n > 0 {
+			routes = append(routes, key.(string)+"="+strconv.FormatInt(n, 10))
+		}
+		return true
+	})
+	sort.Strings(routes)
+	return strings.Join(routes, ", ")
+}
+
+// InFlight returns the number of requests being processed, e.g. to tell when
+// a draining gateway is done.
+func (agw *ApiGateway) InFlight() int {
+	return int(agw.requests.inFlight.Load())
+}
+
+// Stats returns a snapshot of the counters of the enabled features.
+func (agw *ApiGateway) Stats() GatewayStats {
+	gs := GatewayStats{
+		Requests: RequestCounts{
+			Total:     agw.requests.total.Load(),
+			Preflight: agw.requests.preflight.Load(),
+			InFlight:  agw.requests.inFlight.Load(),
+		},
+	}
+	if agw.admission != nil {
+		cs := agw.admission.stats()
+		gs.Concurrency = &cs
+	}
+	if agw.responseCache != nil {
+		cs := agw.responseCache.Stats()
+		gs.Cache = &cs
+	}
+	if agw.latency != nil {
+		ls := agw.latency.stats()
+		gs.Latency = &ls
+	}
+	if agw.coalescer != nil {
+		cs := agw.coalescer.stats()
+		gs.Coalesce = &cs
+	}
+	if agw.tlsStats != nil {
+		ts := agw.tlsStats.stats()
+		gs.TLS = &ts
+	}
+	agw.breakersMu.Lock()
+	for _, cb := range agw.breakers {
+		for key, cs := range cb.Stats() {
+			if gs.Circuits == nil {
+				gs.Circuits = map[string]CircuitStats{}
+			}
+			gs.Circuits[key] = cs
+		}
+	}
+	agw.breakersMu.Unlock()
+	return gs
+}
@@ -0,0 +1,260 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiGatewayHandler(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{
+		LogFile: log.FileConfig{Filename: "discard"},
+		Level:   "info",
+	}, nil)
+	require.NoError(t, err)
+	agw.GET("/ping", func(c echo.Context) error { return c.String(http.StatusOK, "pong") })
+	agw.GET("/panic", func(echo.Context) error { panic("boom") })
+
+	h := agw.Handler()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(echo.HeaderOrigin, "http://example.com")
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "pong", rec.Body.String())
+	assert.NotEmpty(t, rec.Header().Get(echo.HeaderXRequestID))
+	assert.NotEmpty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	assert.Equal(t, uint64(2), agw.Stats().Requests.Total)
+}
+
+func TestApiGatewayAccessLogLevel(t *testing.T) {
+	defer logrus.SetLevel(logrus.GetLevel())
+
+	agw, err := NewApiGateway(context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.NotSame(t, logrus.StandardLogger(), agw.Logger.Logger)
+
+	// the application going quiet keeps the access log flowing
+	log.SetLevel(logrus.WarnLevel)
+	assert.True(t, agw.Logger.IsLevelEnabled(logrus.InfoLevel))
+
+	require.NoError(t, agw.SetAccessLogLevel("warn"))
+	assert.False(t, agw.Logger.IsLevelEnabled(logrus.InfoLevel))
+	assert.Equal(t, logrus.WarnLevel, logrus.GetLevel())
+}
+
+func TestApiGatewayRunUntilSignalStartFailure(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{
+		LogFile: log.FileConfig{Filename: "discard"},
+	}, nil)
+	require.NoError(t, err)
+	agw.HideBanner = true
+
+	assert.Error(t, agw.RunUntilSignal("127.0.0.1", "not-a-port"))
+}
+
+func TestApiGatewayRequestValidator(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{
+		LogFile: log.FileConfig{Filename: "discard"},
+	}, nil)
+	require.NoError(t, err)
+
+	type user struct {
+		Name string `json:"name"`
+	}
+	agw.RequestValidator = func(c echo.Context) error {
+		if c.Request().Method != http.MethodPost {
+			return nil
+		}
+		var u user
+		if err := c.Bind(&u); err != nil {
+			return err
+		}
+		if u.Name == "" {
+			return errors.New("name is required")
+		}
+		SetValidated(c, &u)
+		return nil
+	}
+	agw.POST("/users", func(c echo.Context) error {
+		return c.String(http.StatusCreated, GetValidated(c).(*user).Name)
+	})
+
+	for body, want := range map[string]int{
+		`{"name":"alice"}`: http.StatusCreated,
+		`{}`:               http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		agw.Handler().ServeHTTP(rec, req)
+		assert.Equal(t, want, rec.Code, body)
+	}
+}
+
+type upperBinder struct{ echo.DefaultBinder }
+
+func TestNewApiGatewayWithEcho(t *testing.T) {
+	e := echo.New()
+	e.Binder = &upperBinder{}
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Set("X-Custom", "1")
+			return next(c)
+		}
+	})
+
+	agw, err := NewApiGatewayWithEcho(context.Background(), &LogConfig{
+		LogFile: log.FileConfig{Filename: "discard"},
+	}, nil, e)
+	require.NoError(t, err)
+	assert.Same(t, e, agw.Echo)
+	assert.IsType(t, &upperBinder{}, agw.Binder)
+
+	agw.GET("/ping", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	rec := httptest.NewRecorder()
+	agw.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	assert.Equal(t, "1", rec.Header().Get("X-Custom"))
+	assert.NotEmpty(t, rec.Header().Get(echo.HeaderXRequestID))
+}
+
+func TestFromContext(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+
+	var got *ApiGateway
+	agw.GET("/", func(c echo.Context) error {
+		got = FromContext(c)
+		return c.NoContent(http.StatusOK)
+	})
+	agw.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Same(t, agw, got)
+
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	assert.Nil(t, FromContext(c))
+	fake := &ApiGateway{}
+	c.Set(ContextKeyGateway, fake)
+	assert.Same(t, fake, FromContext(c))
+}
+
+func TestApiGatewayLogFormat(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}, Format: "systemd"}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &log.SystemdFormatter{}, agw.EntryFormat)
+
+	_, err = NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}, Format: "xml"}, nil)
+	assert.Error(t, err)
+}
+
+func TestApiGatewayStartupConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "access.log")
+	conf := LogConfig{LogFile: log.FileConfig{Filename: file, MaxSize: 10, MaxBackups: 3, Compress: true}, Format: "json"}
+	_, err := NewApiGateway(context.Background(), &conf, nil)
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Len(t, lines, 1)
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "startup config", entry["msg"])
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, file, entry["output"])
+	assert.Equal(t, "json", entry["format"])
+	assert.EqualValues(t, 10, entry["max_size_mb"])
+	assert.EqualValues(t, 3, entry["max_backups"])
+	assert.Equal(t, true, entry["compress"])
+
+	quiet := filepath.Join(t.TempDir(), "access.log")
+	_, err = NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: quiet}, QuietStartup: true}, nil)
+	require.NoError(t, err)
+	b, _ = os.ReadFile(quiet)
+	assert.Empty(t, b)
+}
+
+func TestApiGatewayTraceLevel(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}, Level: "trace"}, nil)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	agw.Logger.SetOutput(&buf)
+	assert.True(t, agw.Logger.IsLevelEnabled(logrus.TraceLevel))
+	agw.Logger.TraceKV("routing", "path", "/")
+	assert.Contains(t, buf.String(), "TRAC")
+	assert.Contains(t, buf.String(), "routing")
+
+	buf.Reset()
+	require.NoError(t, agw.SetAccessLogLevel("debug"))
+	agw.Logger.TraceKV("routing", "path", "/")
+	assert.Empty(t, buf.String())
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestApiGatewayStopDraining(t *testing.T) {
+	defer func(d time.Duration) { drainLogInterval = d }(drainLogInterval)
+	drainLogInterval = 20 * time.Millisecond
+
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+	var out syncBuffer
+	agw.Logger.SetOutput(&out)
+	agw.ServerConf = &ServerConfig{ShutdownTimeout: 200 * time.Millisecond}
+
+	release := make(chan struct{})
+	defer close(release)
+	agw.GET("/slow/:id", func(c echo.Context) error {
+		<-release
+		return c.NoContent(http.StatusOK)
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	agw.Echo.Listener = l
+	go func() { _ = agw.Run("127.0.0.1", "0") }()
+
+	go func() { _, _ = http.Get("http://" + l.Addr().String() + "/slow/1") }()
+	require.Eventually(t, func() bool { return agw.InFlight() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int64(1), agw.Stats().Requests.InFlight)
+
+	assert.ErrorIs(t, agw.Stop(), context.DeadlineExceeded)
+	assert.Contains(t, out.String(), "draining 1 requests in flight")
+	assert.Contains(t, out.String(), "with 1 requests in flight, closing them, active routes: GET /slow/:id=1")
+}
@@ -0,0 +1,102 @@
+package httpx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"os"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/errors"
+)
+
+// RunTLS serves HTTPS with the given certificate and key.
+func (agw *ApiGateway) RunTLS(ip, port, certFile, keyFile string) error {
+	tc, err := newServerTLSConfig(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	return agw.startEchoTLS(fmt.Sprintf("%s:%s", ip, port), tc)
+}
+
+// RunMTLS serves HTTPS and requires every client to present a certificate
+// signed by a CA in clientCAFile. The verified subject is available to
+// handlers through ClientCertSubject.
+func (agw *ApiGateway) RunMTLS(ip, port, certFile, keyFile, clientCAFile string) error {
+	tc, err := newServerTLSConfig(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	pool, err := loadCertPool(clientCAFile)
+	if err != nil {
+		return err
+	}
+	tc.ClientCAs = pool
+	tc.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return agw.startEchoTLS(fmt.Sprintf("%s:%s", ip, port), tc)
+}
+
+func (agw *ApiGateway) startEchoTLS(addr string, tc *tls.Config) error {
+	e := agw.Echo
+	s := e.TLSServer
+	s.Addr = addr
+	if !e.DisableHTTP2 {
+		tc.NextProtos = append(tc.NextProtos, "h2")
+	}
+	agw.applySNI(tc)
+	s.TLSConfig = tc
+	agw.observeTLS(s, tc)
+
+	l, err := agw.newListener(addr)
+	if err != nil {
+		return err
+	}
+	if l != nil {
+		e.TLSListener = tls.NewListener(l, tc)
+	}
+	agw.applyServerConfig(s)
+	return e.StartServer(s)
+}
+
+func newServerTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("invalid tls configuration, need both cert and key file")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load key pair, cert:%s, key:%s", certFile, keyFile)
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	if caFile == "" {
+		return nil, errors.New("invalid mtls configuration, need client CA file")
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read client CA file:%s", caFile)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.Errorf("no valid PEM certificate found in client CA file:%s", caFile)
+	}
+	return pool, nil
+}
+
+// ClientCertSubject returns the subject of the verified client certificate,
+// false if the request did not come over mutual TLS.
+func ClientCertSubject(c echo.Context) (pkix.Name, bool) {
+	cs := c.Request().TLS
+	if cs == nil || len(cs.VerifiedChains) == 0 || len(cs.VerifiedChains[0]) == 0 {
+		return pkix.Name{}, false
+	}
+	return cs.VerifiedChains[0][0].Subject, true
+}
@@ -0,0 +1,179 @@
+package httpx
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCA returns a CA certificate, its key and its PEM
+func newTestCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return ca, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// newTestClientCert returns a client certificate of cn signed by ca
+func newTestClientCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, cn string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"pkgx"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writeTestKeyPair writes cert and its key as PEM files into dir
+func writeTestKeyPair(t *testing.T, dir string, cert tls.Certificate) (string, string) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	require.NoError(t, err)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func freeTestPort(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+	return port
+}
+
+func TestRunMTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, serverPool := newTestCert(t, time.Now().Add(time.Hour))
+	certFile, keyFile := writeTestKeyPair(t, dir, serverCert)
+	ca, caKey, caPEM := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+	agw.GET("/whoami", func(c echo.Context) error {
+		subject, ok := ClientCertSubject(c)
+		if !ok {
+			return c.NoContent(http.StatusUnauthorized)
+		}
+		return c.String(http.StatusOK, subject.CommonName+"/"+subject.Organization[0])
+	})
+
+	port := freeTestPort(t)
+	go func() { _ = agw.RunMTLS("127.0.0.1", port, certFile, keyFile, caFile) }()
+	defer func() { _ = agw.Stop() }()
+
+	url := "https://127.0.0.1:" + port + "/whoami"
+	get := func(certs ...tls.Certificate) (string, error) {
+		tr := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: serverPool, Certificates: certs}}
+		defer tr.CloseIdleConnections()
+		res, err := (&http.Client{Transport: tr}).Get(url)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		return string(body), err
+	}
+
+	client := newTestClientCert(t, ca, caKey, "svc-a")
+	var body string
+	require.Eventually(t, func() bool {
+		body, err = get(client)
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "svc-a/pkgx", body)
+
+	_, err = get()
+	assert.Error(t, err, "no client certificate")
+
+	other, otherKey, _ := newTestCA(t)
+	_, err = get(newTestClientCert(t, other, otherKey, "svc-b"))
+	assert.Error(t, err, "signed by another CA")
+}
+
+func TestRunMTLSClientCA(t *testing.T) {
+	dir := t.TempDir()
+	cert, _ := newTestCert(t, time.Now().Add(time.Hour))
+	certFile, keyFile := writeTestKeyPair(t, dir, cert)
+	invalid := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, os.WriteFile(invalid, []byte("not a certificate"), 0o600))
+
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+	for caFile, msg := range map[string]string{
+		"":                             "need client CA file",
+		filepath.Join(dir, "none.pem"): "failed to read client CA file",
+		invalid:                        "no valid PEM certificate found",
+	} {
+		err = agw.RunMTLS("127.0.0.1", "0", certFile, keyFile, caFile)
+		require.Error(t, err, caFile)
+		assert.Contains(t, err.Error(), msg)
+	}
+
+	_, _, caPEM := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+	pool, err := loadCertPool(caFile)
+	require.NoError(t, err)
+	assert.NotNil(t, pool)
+}
+
+func TestClientCertSubject(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+	_, ok := ClientCertSubject(c)
+	assert.False(t, ok, "plaintext")
+
+	// TLS without a verified client certificate, e.g. RunTLS
+	leaf := &x509.Certificate{Subject: pkix.Name{CommonName: "svc-a"}}
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}
+	_, ok = ClientCertSubject(c)
+	assert.False(t, ok, "unverified")
+
+	req.TLS.VerifiedChains = [][]*x509.Certificate{{leaf, {Subject: pkix.Name{CommonName: "test CA"}}}}
+	subject, ok := ClientCertSubject(c)
+	assert.True(t, ok)
+	assert.Equal(t, "svc-a", subject.CommonName)
+}
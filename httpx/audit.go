@@ -0,0 +1,65 @@
+package httpx
+
+import (
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/sirupsen/logrus"
+)
+
+// ContextKeyPrincipal is the echo.Context key holding the authenticated
+// principal recorded by Audit, set by the authentication middleware with
+// SetPrincipal.
+const ContextKeyPrincipal = "httpx.principal"
+
+// SetPrincipal records the authenticated principal of the request, e.g. the
+// user id of a verified token, for Audit.
+func SetPrincipal(c echo.Context, principal string) {
+	c.Set(ContextKeyPrincipal, principal)
+}
+
+// GetPrincipal returns the principal set by SetPrincipal, or else the common
+// name of the verified client certificate, "" if none.
+func GetPrincipal(c echo.Context) string {
+	if p, ok := c.Get(ContextKeyPrincipal).(string); ok && p != "" {
+		return p
+	}
+	if subject, ok := ClientCertSubject(c); ok {
+		return subject.CommonName
+	}
+	return ""
+}
+
+// initAuditLog opens LogConf.AuditLog, the audit Logger writes JSON entries
+// at info level whatever the access and application log levels.
+func (agw *ApiGateway) initAuditLog() {
+	if agw.LogConf.AuditLog == nil {
+		return
+	}
+	agw.auditLogger = log.NewLogger(agw.ctx, *agw.LogConf.AuditLog)
+	agw.auditLogger.SetLevel(logrus.InfoLevel)
+	agw.auditLogger.SetFormatter(&log.JSONFormatter{})
+}
+
+// Audit records who did what in the audit log, LogConfig.AuditLog, apart from
+// the access and application logs: the principal (see GetPrincipal), action,
+// resource and result, a nil result being a success, with the request id,
+// client ip and time. Entries are never sampled nor truncated. It does
+// nothing if LogConfig.AuditLog is not set.
+func (agw *ApiGateway) Audit(c echo.Context, action, resource string, result error) {
+	if agw.auditLogger == nil {
+		return
+	}
+	fields := logrus.Fields{
+		"request_id": GetRequestId(c),
+		"principal":  GetPrincipal(c),
+		"remote_ip":  c.RealIP(),
+		"action":     action,
+		"resource":   resource,
+		"result":     "success",
+	}
+	if result != nil {
+		fields["result"] = "failure"
+		fields[logrus.ErrorKey] = result.Error()
+	}
+	agw.auditLogger.WithFields(fields).Info("audit")
+}
@@ -0,0 +1,61 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.log")
+	agw, err := NewApiGateway(context.Background(), &LogConfig{
+		LogFile:  log.FileConfig{Filename: "discard"},
+		Level:    "error",
+		AuditLog: &log.FileConfig{Filename: file, MaxSize: 10},
+	}, nil)
+	require.NoError(t, err)
+
+	agw.DELETE("/users/:id", func(c echo.Context) error {
+		SetPrincipal(c, "alice")
+		agw.Audit(c, "user.delete", "users/"+c.Param("id"), nil)
+		agw.Audit(c, "user.delete", "users/"+c.Param("id"), errors.New("forbidden"))
+		return c.NoContent(http.StatusNoContent)
+	})
+	agw.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/users/42", nil))
+
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "alice", entry["principal"])
+	assert.Equal(t, "user.delete", entry["action"])
+	assert.Equal(t, "users/42", entry["resource"])
+	assert.Equal(t, "success", entry["result"])
+	assert.NotEmpty(t, entry["request_id"])
+	assert.NotEmpty(t, entry["time"])
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "failure", entry["result"])
+	assert.Equal(t, "forbidden", entry["error"])
+}
+
+func TestAuditDisabled(t *testing.T) {
+	agw := &ApiGateway{}
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	agw.Audit(c, "noop", "", nil)
+	assert.Empty(t, GetPrincipal(c))
+}
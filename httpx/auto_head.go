@@ -0,0 +1,55 @@
+package httpx
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+// contextKeyAutoHead marks the HEAD requests routed to a GET route by
+// autoHeadMiddleware
+const contextKeyAutoHead = "httpx.auto_head"
+
+// autoHeadMiddleware routes the HEAD requests of the paths without a HEAD
+// route to their GET one, by switching the method to GET before routing, for
+// headRestoreMiddleware to switch it back after. Echo answers them 405
+// otherwise. It is installed with Pre from LogConfig.AutoHead.
+func (agw *ApiGateway) autoHeadMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		if req.Method != http.MethodHead {
+			return next(c)
+		}
+
+		// routed on a context of its own, not to leave anything on c
+		probe := agw.AcquireContext()
+		defer agw.ReleaseContext(probe)
+		probe.Reset(req, nil)
+		path := getRequestPath(req)
+		agw.Router().Find(http.MethodHead, path, probe)
+		if routeMatched(probe) {
+			return next(c)
+		}
+		probe.SetHandler(echo.NotFoundHandler)
+		agw.Router().Find(http.MethodGet, path, probe)
+		if !routeMatched(probe) {
+			return next(c)
+		}
+
+		req.Method = http.MethodGet
+		c.Set(contextKeyAutoHead, true)
+		return next(c)
+	}
+}
+
+// headRestoreMiddleware switches the requests routed by autoHeadMiddleware
+// back to HEAD, it is the first middleware of Use for all the others and the
+// handler to see the method of the client
+func headRestoreMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if autoHead, _ := c.Get(contextKeyAutoHead).(bool); autoHead {
+			c.Request().Method = http.MethodHead
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,84 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiGatewayAutoHead(t *testing.T) {
+	newServer := func(autoHead bool) (*httptest.Server, *bytes.Buffer) {
+		agw, err := NewApiGateway(context.Background(), &LogConfig{
+			LogFile:    log.FileConfig{Filename: "discard"},
+			Format:     "json",
+			Structured: true,
+			AutoHead:   autoHead,
+		}, nil)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		agw.Logger.SetOutput(&buf)
+		agw.GET("/users/:id", func(c echo.Context) error {
+			c.Response().Header().Set("X-Method", c.Request().Method)
+			return c.String(http.StatusOK, "user "+c.Param("id"))
+		})
+		agw.HEAD("/explicit", func(c echo.Context) error {
+			c.Response().Header().Set("X-Route", "head")
+			return c.NoContent(http.StatusNoContent)
+		})
+		agw.GET("/explicit", func(c echo.Context) error { return c.String(http.StatusOK, "get") })
+		agw.POST("/posts", func(c echo.Context) error { return c.NoContent(http.StatusCreated) })
+
+		srv := httptest.NewServer(agw.Handler())
+		t.Cleanup(srv.Close)
+		return srv, &buf
+	}
+
+	head := func(srv *httptest.Server, path string) *http.Response {
+		res, err := http.Head(srv.URL + path)
+		require.NoError(t, err)
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Empty(t, b)
+		return res
+	}
+
+	srv, buf := newServer(true)
+	res := head(srv, "/users/42")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.EqualValues(t, len("user 42"), res.ContentLength)
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, res.Header.Get(echo.HeaderContentType))
+	assert.Equal(t, http.MethodHead, res.Header.Get("X-Method"), "the handler sees the method of the client")
+
+	var entry map[string]interface{}
+	require.NoError(t, json.NewDecoder(buf).Decode(&entry))
+	assert.Equal(t, http.MethodHead, entry["method"])
+	assert.Equal(t, "/users/42", entry["url"])
+	assert.NotContains(t, entry, "res_body")
+
+	res = head(srv, "/explicit")
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Equal(t, "head", res.Header.Get("X-Route"))
+	assert.Equal(t, http.StatusMethodNotAllowed, head(srv, "/posts").StatusCode)
+	assert.Equal(t, http.StatusNotFound, head(srv, "/missing").StatusCode)
+
+	// a GET is left alone
+	get, err := http.Get(srv.URL + "/users/7")
+	require.NoError(t, err)
+	b, _ := io.ReadAll(get.Body)
+	_ = get.Body.Close()
+	assert.Equal(t, "user 7", string(b))
+	assert.Equal(t, http.MethodGet, get.Header.Get("X-Method"))
+
+	srv, _ = newServer(false)
+	assert.Equal(t, http.StatusMethodNotAllowed, head(srv, "/users/42").StatusCode)
+}
@@ -0,0 +1,48 @@
+package httpx
+
+import (
+	"sync"
+
+	"github.com/labstack/echo"
+)
+
+// BodyDumpPolicy overrides the global body_in/body_out dumping of LogConfig for one route.
+type BodyDumpPolicy struct {
+	// Disable turns off body dumping for the route, e.g. for uploads.
+	Disable bool
+	// BufferSize replaces LogConfig.BodyBufferSize for the route, 0 keeps the global one.
+	BufferSize int64
+}
+
+// bodyDumpPolicies is a registry keyed by method and route path
+type bodyDumpPolicies struct {
+	mu       sync.RWMutex
+	policies map[string]BodyDumpPolicy
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+func (bp *bodyDumpPolicies) set(method, path string, p BodyDumpPolicy) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if bp.policies == nil {
+		bp.policies = make(map[string]BodyDumpPolicy)
+	}
+	bp.policies[routeKey(method, path)] = p
+}
+
+// lookup matches on the registered route path, e.g. "/users/:id", not the raw URL.
+func (bp *bodyDumpPolicies) lookup(c echo.Context) (BodyDumpPolicy, bool) {
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+	p, ok := bp.policies[routeKey(c.Request().Method, c.Path())]
+	return p, ok
+}
+
+// SetBodyDumpPolicy overrides the body dumping settings for the route
+// registered with method and path, e.g. ("POST", "/v1/payments").
+func (agw *ApiGateway) SetBodyDumpPolicy(method, path string, p BodyDumpPolicy) {
+	agw.bodyDumpPolicies.set(method, path, p)
+}
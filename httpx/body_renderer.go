@@ -0,0 +1,60 @@
+package httpx
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	bodyRenderersMu sync.RWMutex
+	bodyRenderers   = map[string]func(body []byte) string{}
+)
+
+// RegisterBodyRenderer makes the Logger middleware dump body_in and body_out
+// of contentType, a media type like "application/x-protobuf" matched without
+// its parameters, as rendered by fn, e.g. the message type and fields of a
+// protobuf, where such bodies are not dumped at all by default. It only
+// applies to the contents not printable as is, see isPrintableTextContent. A
+// nil fn unregisters the renderer.
+//
+// fn gets the whole body, the body dump limits apply as for the printable
+// contents, and must not modify it. It is called from the request goroutines,
+// concurrently, a panic of it is dumped in place of the body.
+func RegisterBodyRenderer(contentType string, fn func(body []byte) string) {
+	mt := mediaType(contentType)
+	bodyRenderersMu.Lock()
+	defer bodyRenderersMu.Unlock()
+	if fn == nil {
+		delete(bodyRenderers, mt)
+		return
+	}
+	bodyRenderers[mt] = fn
+}
+
+// bodyRenderer tells whether a body of contentType is dumped, and the renderer
+// to dump it with, nil for a printable one dumped as is
+func bodyRenderer(contentType string) (func(body []byte) string, bool) {
+	if isPrintableTextContent(contentType) {
+		return nil, true
+	}
+	bodyRenderersMu.RLock()
+	defer bodyRenderersMu.RUnlock()
+	fn, ok := bodyRenderers[mediaType(contentType)]
+	return fn, ok
+}
+
+// renderBody runs fn on body, not to fail the request on a broken renderer
+func renderBody(fn func(body []byte) string, body []byte) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprintf("[body renderer panic: %v]", r)
+		}
+	}()
+	return fn(body)
+}
+
+// mediaType is contentType lowercased, without its parameters
+func mediaType(contentType string) string {
+	return strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+}
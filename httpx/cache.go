@@ -0,0 +1,270 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+const (
+	defaultCacheMaxEntries = 1024
+	defaultCacheMaxBytes   = 64 << 20
+)
+
+type (
+	// CacheConfig defines the config for the response cache middleware.
+	CacheConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper middleware.Skipper
+
+		// TTL applies to every route without an override from SetTTL,
+		// 0 caches only the routes registered with SetTTL.
+		TTL time.Duration
+
+		// VaryHeaders are request headers which take part in the cache key
+		// besides method and URI, e.g. "Accept-Encoding".
+		VaryHeaders []string
+
+		// MaxEntries bounds the number of cached responses, the least recently
+		// used one is evicted first. Optional. Default 1024.
+		MaxEntries int
+
+		// MaxBytes bounds the total size of cached bodies.
+		// Optional. Default 64MB.
+		MaxBytes int64
+	}
+
+	// CacheStats is a snapshot of the response cache counters.
+	CacheStats struct {
+		Hits    uint64
+		Misses  uint64
+		Entries int
+		Bytes   int64
+	}
+
+	// ResponseCache is an in-memory LRU cache of 2xx responses to GET/HEAD.
+	ResponseCache struct {
+		config CacheConfig
+
+		mu      sync.Mutex
+		lru     *list.List
+		entries map[string]*list.Element
+		bytes   int64
+
+		ttls   sync.Map
+		hits   atomic.Uint64
+		misses atomic.Uint64
+	}
+
+	cacheEntry struct {
+		key     string
+		status  int
+		header  http.Header
+		body    []byte
+		stored  time.Time
+		expires time.Time
+	}
+)
+
+// NewResponseCache creates a ResponseCache, install it with Middleware.
+func NewResponseCache(config CacheConfig) *ResponseCache {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultCacheMaxEntries
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = defaultCacheMaxBytes
+	}
+	return &ResponseCache{
+		config:  config,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// SetTTL overrides the cache TTL for the route registered with path, 0 disables
+// caching for it.
+func (rc *ResponseCache) SetTTL(path string, ttl time.Duration) {
+	rc.ttls.Store(path, ttl)
+}
+
+// Stats returns the current counters.
+func (rc *ResponseCache) Stats() CacheStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return CacheStats{
+		Hits:    rc.hits.Load(),
+		Misses:  rc.misses.Load(),
+		Entries: rc.lru.Len(),
+		Bytes:   rc.bytes,
+	}
+}
+
+func (rc *ResponseCache) ttl(c echo.Context) time.Duration {
+	if v, ok := rc.ttls.Load(c.Path()); ok {
+		return v.(time.Duration)
+	}
+	return rc.config.TTL
+}
+
+func (rc *ResponseCache) key(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteByte(' ')
+	b.WriteString(req.RequestURI)
+	for _, h := range rc.config.VaryHeaders {
+		b.WriteByte('\n')
+		b.WriteString(h)
+		b.WriteByte(':')
+		b.WriteString(req.Header.Get(h))
+	}
+	return b.String()
+}
+
+func (rc *ResponseCache) get(key string, now time.Time) *cacheEntry {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	el, ok := rc.entries[key]
+	if !ok {
+		return nil
+	}
+	ce := el.Value.(*cacheEntry)
+	if now.After(ce.expires) {
+		rc.remove(el)
+		return nil
+	}
+	rc.lru.MoveToFront(el)
+	return ce
+}
+
+func (rc *ResponseCache) put(ce *cacheEntry) {
+	size := int64(len(ce.body))
+	if size > rc.config.MaxBytes {
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if el, ok := rc.entries[ce.key]; ok {
+		rc.remove(el)
+	}
+	rc.entries[ce.key] = rc.lru.PushFront(ce)
+	rc.bytes += size
+
+	for rc.lru.Len() > rc.config.MaxEntries || rc.bytes > rc.config.MaxBytes {
+		rc.remove(rc.lru.Back())
+	}
+}
+
+func (rc *ResponseCache) remove(el *list.Element) {
+	ce := rc.lru.Remove(el).(*cacheEntry)
+	delete(rc.entries, ce.key)
+	rc.bytes -= int64(len(ce.body))
+}
+
+// Middleware returns the middleware serving and filling the cache.
+// Only GET/HEAD requests with a TTL > 0 are cached.
+func (rc *ResponseCache) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if rc.config.Skipper(c) || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+				return next(c)
+			}
+
+			ttl := rc.ttl(c)
+			if ttl <= 0 {
+				return next(c)
+			}
+
+			key := rc.key(req)
+			now := time.Now()
+			if ce := rc.get(key, now); ce != nil {
+				rc.hits.Add(1)
+				return ce.write(c, now)
+			}
+			rc.misses.Add(1)
+
+			res := c.Response()
+			cw := &cacheCaptureWriter{ResponseWriter: res.Writer, limit: rc.config.MaxBytes}
+			res.Writer = cw
+			defer func() { res.Writer = cw.ResponseWriter }()
+
+			if err := next(c); err != nil {
+				return err
+			}
+
+			if res.Status < 200 || res.Status >= 300 || cw.overflow ||
+				strings.Contains(res.Header().Get("Cache-Control"), "no-store") {
+				return nil
+			}
+
+			header := res.Header().Clone()
+			// the id belongs to the request which filled the cache
+			header.Del(echo.HeaderXRequestID)
+			rc.put(&cacheEntry{
+				key:     key,
+				status:  res.Status,
+				header:  header,
+				body:    cw.buf.Bytes(),
+				stored:  now,
+				expires: now.Add(ttl),
+			})
+			return nil
+		}
+	}
+}
+
+func (ce *cacheEntry) write(c echo.Context, now time.Time) error {
+	header := c.Response().Header()
+	for k, v := range ce.header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set("Age", strconv.Itoa(int(now.Sub(ce.stored).Seconds())))
+	c.Response().WriteHeader(ce.status)
+	if c.Request().Method == http.MethodHead {
+		return nil
+	}
+	_, err := c.Response().Write(ce.body)
+	return err
+}
+
+// cacheCaptureWriter copies the response body while passing it through
+type cacheCaptureWriter struct {
+	http.ResponseWriter
+	buf      bytes.Buffer
+	limit    int64
+	overflow bool
+}
+
+func (w *cacheCaptureWriter) Write(b []byte) (int, error) {
+	if !w.overflow {
+		if int64(w.buf.Len()+len(b)) > w.limit {
+			w.overflow = true
+			w.buf = bytes.Buffer{}
+		} else {
+			w.buf.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cacheCaptureWriter) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *cacheCaptureWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
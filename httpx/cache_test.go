@@ -0,0 +1,51 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCache(t *testing.T) {
+	rc := NewResponseCache(CacheConfig{MaxEntries: 1})
+	rc.SetTTL("/data/:id", time.Minute)
+
+	calls := 0
+	e := echo.New()
+	e.Use(rc.Middleware())
+	e.GET("/data/:id", func(c echo.Context) error {
+		calls++
+		return c.String(http.StatusOK, "data-"+c.Param("id"))
+	})
+	e.GET("/nocache", func(c echo.Context) error {
+		calls++
+		return c.String(http.StatusOK, "x")
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	assert.Equal(t, "data-1", get("/data/1").Body.String())
+	rec := get("/data/1")
+	assert.Equal(t, "data-1", rec.Body.String())
+	assert.Equal(t, "0", rec.Header().Get("Age"))
+	assert.Equal(t, 1, calls)
+
+	// evicts /data/1 as MaxEntries is 1
+	get("/data/2")
+	get("/data/1")
+	assert.Equal(t, 3, calls)
+
+	get("/nocache")
+	get("/nocache")
+	assert.Equal(t, 5, calls)
+
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 3, Entries: 1, Bytes: 6}, rc.Stats())
+}
@@ -0,0 +1,262 @@
+package httpx
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+const (
+	defaultBreakerFailureRatio = 0.5
+	defaultBreakerMinRequests  = 10
+	defaultBreakerWindow       = 10 * time.Second
+	defaultBreakerCooldown     = 30 * time.Second
+	defaultBreakerTrials       = 1
+)
+
+// BreakerState is the state of a circuit.
+type BreakerState string
+
+const (
+	// BreakerClosed lets requests through, counting the failures
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen answers 503 at once until the cooldown is over
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets trial requests through, a success closes the
+	// circuit, a failure opens it again
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+type (
+	// CircuitBreakerConfig defines the config for the circuit breaker
+	// middleware.
+	CircuitBreakerConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper middleware.Skipper
+
+		// Name prefixes the keys of the breaker in Stats, to tell breakers
+		// apart. Optional.
+		Name string
+
+		// KeyFunc picks the circuit of a request, e.g. the downstream it
+		// calls. Optional. Default value is the method and route path.
+		KeyFunc func(c echo.Context) string
+
+		// IsFailure tells whether a request failed, from the handler error
+		// and the response. Optional. Default value counts the 5xx.
+		IsFailure func(c echo.Context, err error) bool
+
+		// FailureRatio of the requests in Window opening the circuit, once
+		// MinRequests were seen. Optional. Default 0.5 and 10.
+		FailureRatio float64
+		MinRequests  int
+
+		// Window is the period over which failures are counted, the counts
+		// start over at each window. Optional. Default 10s.
+		Window time.Duration
+
+		// Cooldown is how long an open circuit rejects requests before letting
+		// HalfOpenRequests trial requests through. Optional. Default 30s and 1.
+		Cooldown         time.Duration
+		HalfOpenRequests int
+	}
+
+	// CircuitStats is a snapshot of a circuit, Requests and Failures are those
+	// of the current window.
+	CircuitStats struct {
+		State    BreakerState
+		Requests uint64
+		Failures uint64
+		Rejected uint64
+	}
+
+	// CircuitBreaker fails fast with 503 on the circuits whose requests keep
+	// failing, e.g. because of a flaky downstream, instead of piling them on.
+	CircuitBreaker struct {
+		config CircuitBreakerConfig
+
+		mu       sync.Mutex
+		circuits map[string]*circuit
+	}
+
+	circuit struct {
+		state       BreakerState
+		windowStart time.Time
+		requests    uint64
+		failures    uint64
+		openedAt    time.Time
+		trials      int
+		rejected    atomic.Uint64
+	}
+)
+
+// NewCircuitBreaker creates a CircuitBreaker, install it with Middleware on
+// the routes to protect, or on the whole gateway with a KeyFunc.
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = func(c echo.Context) string { return c.Request().Method + " " + c.Path() }
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = isServerFailure
+	}
+	if config.FailureRatio <= 0 {
+		config.FailureRatio = defaultBreakerFailureRatio
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = defaultBreakerMinRequests
+	}
+	if config.Window <= 0 {
+		config.Window = defaultBreakerWindow
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = defaultBreakerCooldown
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = defaultBreakerTrials
+	}
+	return &CircuitBreaker{config: config, circuits: map[string]*circuit{}}
+}
+
+// isServerFailure counts a 5xx response, or error which would be sent as one
+func isServerFailure(c echo.Context, err error) bool {
+	if err != nil {
+		status := Wrap(err).Status
+		return status == 0 || status >= http.StatusInternalServerError
+	}
+	return c.Response().Status >= http.StatusInternalServerError
+}
+
+// Middleware returns the circuit breaker middleware.
+func (cb *CircuitBreaker) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if cb.config.Skipper(c) {
+				return next(c)
+			}
+
+			key := cb.config.KeyFunc(c)
+			trial, ok := cb.allow(key)
+			if !ok {
+				return SendResp(c, StatusResp(http.StatusServiceUnavailable))
+			}
+
+			// a panic counts as a failure, not to leave a trial pending
+			failed := true
+			defer func() { cb.record(key, trial, failed) }()
+
+			err := next(c)
+			failed = cb.config.IsFailure(c, err)
+			return err
+		}
+	}
+}
+
+// allow tells whether a request of the circuit at key can go, and if it is a
+// trial of a half-open circuit
+func (cb *CircuitBreaker) allow(key string) (trial bool, ok bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cir := cb.circuits[key]
+	if cir == nil {
+		cir = &circuit{state: BreakerClosed, windowStart: time.Now()}
+		cb.circuits[key] = cir
+	}
+
+	switch cir.state {
+	case BreakerOpen:
+		if time.Since(cir.openedAt) < cb.config.Cooldown {
+			cir.rejected.Add(1)
+			return false, false
+		}
+		cir.state, cir.trials = BreakerHalfOpen, 0
+		fallthrough
+	case BreakerHalfOpen:
+		if cir.trials >= cb.config.HalfOpenRequests {
+			cir.rejected.Add(1)
+			return false, false
+		}
+		cir.trials++
+		return true, true
+	}
+	return false, true
+}
+
+func (cb *CircuitBreaker) record(key string, trial, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cir := cb.circuits[key]
+	now := time.Now()
+	if trial {
+		if cir.state != BreakerHalfOpen {
+			return
+		}
+		if failed {
+			cir.state, cir.openedAt = BreakerOpen, now
+			return
+		}
+		cir.state, cir.windowStart, cir.requests, cir.failures = BreakerClosed, now, 0, 0
+		return
+	}
+	if cir.state != BreakerClosed {
+		// settled by a trial meanwhile
+		return
+	}
+
+	if now.Sub(cir.windowStart) >= cb.config.Window {
+		cir.windowStart, cir.requests, cir.failures = now, 0, 0
+	}
+	cir.requests++
+	if failed {
+		cir.failures++
+	}
+	if cir.requests >= uint64(cb.config.MinRequests) &&
+		float64(cir.failures) >= cb.config.FailureRatio*float64(cir.requests) {
+		cir.state, cir.openedAt = BreakerOpen, now
+	}
+}
+
+// Stats returns a snapshot of the circuits seen so far, by key.
+func (cb *CircuitBreaker) Stats() map[string]CircuitStats {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	stats := make(map[string]CircuitStats, len(cb.circuits))
+	for key, cir := range cb.circuits {
+		state := cir.state
+		if state == BreakerOpen && time.Since(cir.openedAt) >= cb.config.Cooldown {
+			state = BreakerHalfOpen
+		}
+		if cb.config.Name != "" {
+			key = cb.config.Name + " " + key
+		}
+		stats[key] = CircuitStats{
+			State:    state,
+			Requests: cir.requests,
+			Failures: cir.failures,
+			Rejected: cir.rejected.Load(),
+		}
+	}
+	return stats
+}
+
+// NewCircuitBreaker creates a CircuitBreaker whose circuits are reported by
+// Stats, see NewCircuitBreaker. Install it on the routes to protect, e.g.
+//
+//	cb := agw.NewCircuitBreaker(httpx.CircuitBreakerConfig{Name: "billing"})
+//	agw.POST("/invoices", createInvoice, cb.Middleware())
+func (agw *ApiGateway) NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	cb := NewCircuitBreaker(config)
+	agw.breakersMu.Lock()
+	agw.breakers = append(agw.breakers, cb)
+	agw.breakersMu.Unlock()
+	return cb
+}
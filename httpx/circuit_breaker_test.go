@@ -0,0 +1,77 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	agw := &ApiGateway{Echo: echo.New()}
+	cb := agw.NewCircuitBreaker(CircuitBreakerConfig{
+		Name:        "billing",
+		MinRequests: 4,
+		Cooldown:    20 * time.Millisecond,
+	})
+
+	failing := true
+	agw.GET("/invoices", func(c echo.Context) error {
+		if failing {
+			return errors.New("downstream timeout")
+		}
+		return c.NoContent(http.StatusOK)
+	}, cb.Middleware())
+	agw.GET("/badrequest", func(c echo.Context) error {
+		return SendResp(c, StatusResp(http.StatusBadRequest))
+	}, cb.Middleware())
+
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		agw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	const key = "billing GET /invoices"
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, http.StatusInternalServerError, get("/invoices"))
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, get("/invoices"))
+	st := agw.Stats().Circuits[key]
+	assert.Equal(t, BreakerOpen, st.State)
+	assert.Equal(t, uint64(1), st.Rejected)
+
+	// client errors do not count
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusBadRequest, get("/badrequest"))
+	}
+	assert.Equal(t, BreakerClosed, agw.Stats().Circuits["billing GET /badrequest"].State)
+
+	// a failed trial opens the circuit again
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, BreakerHalfOpen, agw.Stats().Circuits[key].State)
+	assert.Equal(t, http.StatusInternalServerError, get("/invoices"))
+	assert.Equal(t, http.StatusServiceUnavailable, get("/invoices"))
+
+	// a successful one closes it
+	failing = false
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, get("/invoices"))
+	assert.Equal(t, http.StatusOK, get("/invoices"))
+	st = agw.Stats().Circuits[key]
+	assert.Equal(t, BreakerClosed, st.State)
+	assert.Equal(t, uint64(1), st.Requests)
+}
+
+func TestCircuitBreakerPanicTrial(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{KeyFunc: func(echo.Context) string { return "k" }, MinRequests: 1})
+	h := cb.Middleware()(func(echo.Context) error { panic("boom") })
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	assert.Panics(t, func() { _ = h(c) })
+	assert.Equal(t, BreakerOpen, cb.Stats()["k"].State)
+}
@@ -0,0 +1,151 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/labstack/echo"
+)
+
+const defaultCoalesceMaxSize = 1 << 20
+
+// coalesceKeyHeaders take part in the coalescing key besides method and URI,
+// so that the response of a user or of a representation is never shared with
+// a request of another
+var coalesceKeyHeaders = []string{
+	echo.HeaderAuthorization,
+	echo.HeaderCookie,
+	"Accept",
+	echo.HeaderAcceptEncoding,
+}
+
+type (
+	// CoalesceStats counts the requests served by LogConfig.Coalesce.
+	// Coalesced are the requests which got the response of an identical one
+	// in flight, Fallbacks those which waited for it but ran their handler as
+	// it could not be shared.
+	CoalesceStats struct {
+		Coalesced uint64
+		Fallbacks uint64
+	}
+
+	coalescer struct {
+		maxSize int64
+
+		mu    sync.Mutex
+		calls map[string]*coalescedCall
+
+		coalesced atomic.Uint64
+		fallbacks atomic.Uint64
+	}
+
+	// coalescedCall is the response of the first of identical requests, read
+	// by the others once done is closed
+	coalescedCall struct {
+		done   chan struct{}
+		shared bool
+		status int
+		header http.Header
+		body   []byte
+	}
+)
+
+func newCoalescer(maxSize int64) *coalescer {
+	if maxSize <= 0 {
+		maxSize = defaultCoalesceMaxSize
+	}
+	return &coalescer{maxSize: maxSize, calls: make(map[string]*coalescedCall)}
+}
+
+func (co *coalescer) stats() CoalesceStats {
+	return CoalesceStats{Coalesced: co.coalesced.Load(), Fallbacks: co.fallbacks.Load()}
+}
+
+func coalesceKey(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteByte(' ')
+	b.WriteString(req.RequestURI)
+	for _, h := range coalesceKeyHeaders {
+		b.WriteByte('\n')
+		b.WriteString(req.Header.Get(h))
+	}
+	return b.String()
+}
+
+// middleware runs the handler once for identical GET/HEAD requests in flight,
+// the first one, the others wait for it and get a copy of its response. A
+// response which failed, or is larger than maxSize, is not shared, the
+// waiters then run their handler each.
+func (co *coalescer) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			return next(c)
+		}
+
+		key := coalesceKey(req)
+		co.mu.Lock()
+		if call, ok := co.calls[key]; ok {
+			co.mu.Unlock()
+			select {
+			case <-call.done:
+			case <-req.Context().Done():
+				return req.Context().Err()
+			}
+			if !call.shared {
+				co.fallbacks.Add(1)
+				return next(c)
+			}
+			co.coalesced.Add(1)
+			return call.write(c)
+		}
+		call := &coalescedCall{done: make(chan struct{})}
+		co.calls[key] = call
+		co.mu.Unlock()
+
+		// the waiters are released even if the handler panics
+		defer func() {
+			co.mu.Lock()
+			delete(co.calls, key)
+			co.mu.Unlock()
+			close(call.done)
+		}()
+
+		res := c.Response()
+		cw := &cacheCaptureWriter{ResponseWriter: res.Writer, limit: co.maxSize}
+		res.Writer = cw
+		defer func() { res.Writer = cw.ResponseWriter }()
+
+		if err := next(c); err != nil {
+			return err
+		}
+		if !res.Committed || cw.overflow {
+			return nil
+		}
+		call.header = res.Header().Clone()
+		// the id belongs to the first request
+		call.header.Del(echo.HeaderXRequestID)
+		call.status = res.Status
+		call.body = cw.buf.Bytes()
+		call.shared = true
+		return nil
+	}
+}
+
+// write copies the shared response to c, each waiter gets its own header and
+// body, nothing is aliased with the first request or the other waiters
+func (call *coalescedCall) write(c echo.Context) error {
+	header := c.Response().Header()
+	for k, v := range call.header {
+		header[k] = append([]string(nil), v...)
+	}
+	c.Response().WriteHeader(call.status)
+	if c.Request().Method == http.MethodHead {
+		return nil
+	}
+	_, err := c.Response().Write(append([]byte(nil), call.body...))
+	return err
+}
@@ -0,0 +1,95 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalesce(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{
+		LogFile:  log.FileConfig{Filename: "discard"},
+		Coalesce: true,
+	}, nil)
+	require.NoError(t, err)
+
+	var (
+		calls   atomic.Int32
+		release = make(chan struct{})
+		fail    atomic.Bool
+	)
+	agw.GET("/report", func(c echo.Context) error {
+		calls.Add(1)
+		<-release
+		if fail.Load() {
+			return echo.NewHTTPError(http.StatusBadGateway)
+		}
+		c.Response().Header().Set("X-Report", "1")
+		return c.String(http.StatusOK, "expensive")
+	})
+	h := agw.Handler()
+
+	// n identical requests, the first in the handler, the others waiting
+	serve := func(n int) []*httptest.ResponseRecorder {
+		recs := make([]*httptest.ResponseRecorder, n)
+		var wg sync.WaitGroup
+		for i := range recs {
+			recs[i] = httptest.NewRecorder()
+			wg.Add(1)
+			go func(rec *httptest.ResponseRecorder) {
+				defer wg.Done()
+				h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/report", nil))
+			}(recs[i])
+			if i == 0 {
+				require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+			}
+		}
+		require.Eventually(t, func() bool { return agw.InFlight() == n }, time.Second, time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		return recs
+	}
+
+	recs := serve(4)
+	assert.Equal(t, int32(1), calls.Load())
+	ids := map[string]bool{}
+	for _, rec := range recs {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "expensive", rec.Body.String())
+		assert.Equal(t, "1", rec.Header().Get("X-Report"))
+		ids[rec.Header().Get(echo.HeaderXRequestID)] = true
+	}
+	// each waiter keeps its own request id
+	assert.Len(t, ids, 4)
+	assert.Equal(t, &CoalesceStats{Coalesced: 3}, agw.Stats().Coalesce)
+
+	// a failed response is not shared, the waiters run the handler
+	calls.Store(0)
+	release = make(chan struct{})
+	fail.Store(true)
+	recs = serve(3)
+	assert.Equal(t, int32(3), calls.Load())
+	for _, rec := range recs {
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+	}
+	assert.Equal(t, &CoalesceStats{Coalesced: 3, Fallbacks: 2}, agw.Stats().Coalesce)
+}
+
+func TestCoalesceKey(t *testing.T) {
+	a := httptest.NewRequest(http.MethodGet, "/report?x=1", nil)
+	b := httptest.NewRequest(http.MethodGet, "/report?x=1", nil)
+	assert.Equal(t, coalesceKey(a), coalesceKey(b))
+	b.Header.Set(echo.HeaderAuthorization, "Bearer other")
+	assert.NotEqual(t, coalesceKey(a), coalesceKey(b))
+	assert.NotEqual(t, coalesceKey(a), coalesceKey(httptest.NewRequest(http.MethodHead, "/report?x=1", nil)))
+}
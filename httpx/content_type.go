@@ -0,0 +1,83 @@
+package httpx
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+type (
+	// ContentTypeConfig defines the config for ContentType middleware.
+	ContentTypeConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper middleware.Skipper
+
+		// AllowedContentTypes are the media types accepted for a request body,
+		// e.g. "application/json", or "text/*" for a whole type. Parameters
+		// like charset are ignored. Empty allows everything.
+		AllowedContentTypes []string
+	}
+)
+
+// ContentTypeWithConfig returns a middleware answering 415 Unsupported Media
+// Type to a request whose body has a Content-Type not in
+// config.AllowedContentTypes. Requests without a body, and GET, HEAD and
+// OPTIONS ones, pass.
+func ContentTypeWithConfig(config ContentTypeConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	allowed := make([]string, len(config.AllowedContentTypes))
+	for i, t := range config.AllowedContentTypes {
+		allowed[i] = strings.ToLower(strings.TrimSpace(t))
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if len(allowed) == 0 || config.Skipper(c) || !hasBody(c.Request()) {
+				return next(c)
+			}
+
+			ct := c.Request().Header.Get(echo.HeaderContentType)
+			if !contentTypeAllowed(ct, allowed) {
+				return SendResp(c, echo.NewHTTPError(http.StatusUnsupportedMediaType,
+					fmt.Sprintf("unsupported content type '%s', should be one of [%s]", ct, strings.Join(allowed, ", "))))
+			}
+			return next(c)
+		}
+	}
+}
+
+// AllowContentTypes is ContentTypeWithConfig for the given types, to guard a
+// single route or group, e.g.
+//
+//	agw.POST("/users", createUser, httpx.AllowContentTypes(echo.MIMEApplicationJSON))
+func AllowContentTypes(types ...string) echo.MiddlewareFunc {
+	return ContentTypeWithConfig(ContentTypeConfig{AllowedContentTypes: types})
+}
+
+func hasBody(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	// -1 is a body of unknown length, e.g. chunked
+	return req.ContentLength != 0
+}
+
+func contentTypeAllowed(ct string, allowed []string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		if a == mt || (strings.HasSuffix(a, "/*") && strings.HasPrefix(mt, a[:len(a)-1])) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentTypeWithConfig(t *testing.T) {
+	e := echo.New()
+	e.Use(ContentTypeWithConfig(ContentTypeConfig{AllowedContentTypes: []string{"application/json", "text/*"}}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/", ok)
+	e.POST("/", ok)
+	e.PUT("/raw", ok, AllowContentTypes("application/octet-stream"))
+
+	for _, tc := range []struct {
+		method, path, ct, body string
+		want                   int
+	}{
+		{http.MethodPost, "/", "application/json; charset=utf-8", "{}", http.StatusOK},
+		{http.MethodPost, "/", "Text/Plain", "hi", http.StatusOK},
+		{http.MethodPost, "/", "application/xml", "<a/>", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "/", "", "{}", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "/", "application/xml", "", http.StatusOK},
+		{http.MethodGet, "/", "application/xml", "<a/>", http.StatusOK},
+		// the route check comes on top of the global one
+		{http.MethodPut, "/raw", "application/octet-stream", "x", http.StatusUnsupportedMediaType},
+		{http.MethodPut, "/raw", "application/json", "{}", http.StatusUnsupportedMediaType},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if tc.ct != "" {
+			req.Header.Set(echo.HeaderContentType, tc.ct)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, tc.want, rec.Code, "%s %s %q", tc.method, tc.path, tc.ct)
+		if tc.want == http.StatusUnsupportedMediaType && tc.ct != "" {
+			assert.Contains(t, rec.Body.String(), tc.ct)
+		}
+	}
+
+}
@@ -0,0 +1,134 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+// contextKeyCORSPreflight is set while the gateway CORS lets a route CORS
+// answer a preflight, to true once one did
+const contextKeyCORSPreflight = "httpx.cors_preflight"
+
+// routeCORS counts the middleware made by CORS, the gateway CORS only looks
+// for a route CORS when there is one
+var routeCORS atomic.Int32
+
+// corsHeaders are the response headers set by a CORS middleware
+var corsHeaders = []string{
+	echo.HeaderAccessControlAllowOrigin,
+	echo.HeaderAccessControlAllowMethods,
+	echo.HeaderAccessControlAllowHeaders,
+	echo.HeaderAccessControlAllowCredentials,
+	echo.HeaderAccessControlExposeHeaders,
+	echo.HeaderAccessControlMaxAge,
+}
+
+var globalCORSConfig = middleware.CORSConfig{
+	AllowOrigins:     []string{"*"},
+	ExposeHeaders:    []string{"*"},
+	AllowMethods:     []string{"*"},
+	AllowHeaders:     []string{"*"},
+	AllowCredentials: true,
+	//AllowMethods: []string{Echo.GET, Echo.PUT, Echo.POST, Echo.DELETE},
+}
+
+// CORS returns a CORS middleware for a group or a route, overriding the
+// permissive one the gateway installs for everything, e.g. to restrict the
+// origins of an internal API:
+//
+//	internal := agw.Group("/internal", httpx.CORS(middleware.CORSConfig{
+//		AllowOrigins: []string{"https://admin.example.com"},
+//	}))
+//
+// The route CORS takes precedence: the headers set by the gateway CORS are
+// dropped and replaced by its own, an origin it does not allow gets no
+// Access-Control-Allow-Origin at all. A preflight is answered by the route
+// CORS when echo routes its OPTIONS request to it, which takes an OPTIONS
+// route, e.g. internal.Match([]string{http.MethodGet, http.MethodOptions},
+// "/users", listUsers), whose handler is not called; the gateway CORS answers
+// the others. A skipped request, see CORSConfig.Skipper, keeps the gateway
+// CORS.
+func CORS(config middleware.CORSConfig) echo.MiddlewareFunc {
+	routeCORS.Add(1)
+	skipper := config.Skipper
+	if skipper == nil {
+		skipper = middleware.DefaultSkipper
+	}
+	config.Skipper = nil
+	cors := middleware.CORSWithConfig(config)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		h := cors(next)
+		return func(c echo.Context) error {
+			if skipper(c) {
+				return next(c)
+			}
+			if c.Get(contextKeyCORSPreflight) != nil {
+				c.Set(contextKeyCORSPreflight, true)
+			}
+			clearCORSHeaders(c.Response().Header())
+			return h(c)
+		}
+	}
+}
+
+// SetCORS replaces the CORS of the whole gateway, by default allowing every
+// origin, method and header with credentials, e.g. to follow the origins of
+// the tenants from the config on reload:
+//
+//	viperx.OnConfigChange(func(viperx.ConfigChange) {
+//		agw.SetCORS(middleware.CORSConfig{AllowOrigins: viperx.GetStrings("cors.origins", nil)})
+//	})
+//
+// It takes effect for the requests coming after it: each request reads the
+// config once, atomically, as it enters the CORS middleware, so a request in
+// flight finishes with the config it started with, and it is safe to call
+// while serving. The route CORS keep their own config.
+func (agw *ApiGateway) SetCORS(config middleware.CORSConfig) {
+	mw := middleware.CORSWithConfig(config)
+	agw.cors.Store(&mw)
+}
+
+// corsMiddleware is the CORS of the whole gateway, see SetCORS. Once a route
+// CORS exists, a preflight goes down the chain first, for the route CORS to
+// answer it, and gets the gateway answer if none did, see CORS.
+func (agw *ApiGateway) corsMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		global := (*agw.cors.Load())(next)
+		if routeCORS.Load() == 0 || !IsPreflight(c) {
+			return global(c)
+		}
+		c.Set(contextKeyCORSPreflight, false)
+		err := next(c)
+		if c.Get(contextKeyCORSPreflight) == true || c.Response().Committed {
+			return err
+		}
+		// not found or not allowed, no route CORS on the way
+		c.Set(contextKeyCORSPreflight, nil)
+		return global(c)
+	}
+}
+
+// clearCORSHeaders removes what the gateway CORS set, Origin of Vary included
+// as the route CORS adds it again
+func clearCORSHeaders(h http.Header) {
+	for _, k := range corsHeaders {
+		h.Del(k)
+	}
+	vary := h.Values(echo.HeaderVary)
+	kept := vary[:0]
+	for _, v := range vary {
+		if !strings.EqualFold(v, echo.HeaderOrigin) {
+			kept = append(kept, v)
+		}
+	}
+	if len(kept) == 0 {
+		h.Del(echo.HeaderVary)
+		return
+	}
+	h[echo.HeaderVary] = kept
+}
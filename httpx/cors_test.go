@@ -0,0 +1,99 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteCORS(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+	ok := func(c echo.Context) error { return c.String(http.StatusOK, "ok") }
+	agw.GET("/public/x", ok)
+	internal := agw.Group("/internal", CORS(middleware.CORSConfig{
+		AllowOrigins: []string{"https://admin.example.com"},
+		AllowMethods: []string{http.MethodGet},
+	}))
+	internal.Match([]string{http.MethodGet, http.MethodOptions}, "/x", ok)
+	h := agw.Handler()
+
+	do := func(method, path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(echo.HeaderOrigin, origin)
+		if method == http.MethodOptions {
+			req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodGet)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// the gateway CORS allows everything
+	rec := do(http.MethodGet, "/public/x", "https://any.example.com")
+	assert.Equal(t, "https://any.example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "true", rec.Header().Get(echo.HeaderAccessControlAllowCredentials))
+	rec = do(http.MethodOptions, "/public/x", "https://any.example.com")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://any.example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	// the route CORS overrides it
+	rec = do(http.MethodGet, "/internal/x", "https://any.example.com")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowCredentials))
+	assert.Equal(t, []string{echo.HeaderOrigin}, rec.Header().Values(echo.HeaderVary))
+	rec = do(http.MethodGet, "/internal/x", "https://admin.example.com")
+	assert.Equal(t, "https://admin.example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	rec = do(http.MethodOptions, "/internal/x", "https://any.example.com")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	rec = do(http.MethodOptions, "/internal/x", "https://admin.example.com")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://admin.example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, http.MethodGet, rec.Header().Get(echo.HeaderAccessControlAllowMethods))
+}
+
+func TestSetCORS(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+	started, release := make(chan struct{}), make(chan struct{})
+	agw.GET("/x", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+	agw.GET("/slow", func(c echo.Context) error {
+		close(started)
+		<-release
+		return c.String(http.StatusOK, "ok")
+	})
+	h := agw.Handler()
+
+	get := func(path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(echo.HeaderOrigin, origin)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	assert.Equal(t, "https://new.example.com", get("/x", "https://new.example.com").Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	// a request in flight keeps the config it started with
+	slow := make(chan *httptest.ResponseRecorder)
+	go func() { slow <- get("/slow", "https://new.example.com") }()
+	<-started
+	agw.SetCORS(middleware.CORSConfig{AllowOrigins: []string{"https://a.example.com"}})
+	close(release)
+	assert.Equal(t, "https://new.example.com", (<-slow).Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	assert.Empty(t, get("/x", "https://new.example.com").Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "https://a.example.com", get("/x", "https://a.example.com").Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	agw.SetCORS(middleware.CORSConfig{AllowOrigins: []string{"https://a.example.com", "https://new.example.com"}})
+	assert.Equal(t, "https://new.example.com", get("/x", "https://new.example.com").Header().Get(echo.HeaderAccessControlAllowOrigin))
+}
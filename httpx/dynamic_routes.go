@@ -0,0 +1,149 @@
+package httpx
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/labstack/echo"
+)
+
+type (
+	dynamicRoute struct {
+		method  string
+		path    string
+		handler echo.HandlerFunc
+	}
+
+	// dynamicTable is an immutable snapshot of the runtime routes, replaced
+	// as a whole by AddRoute and RemoveRoute
+	dynamicTable struct {
+		routes    map[string]dynamicRoute
+		router    *echo.Router
+		maxParams int
+	}
+
+	dynamicRoutes struct {
+		mu    sync.Mutex
+		table atomic.Pointer[dynamicTable]
+	}
+)
+
+var (
+	notFoundHandlerPtr         = reflect.ValueOf(echo.NotFoundHandler).Pointer()
+	methodNotAllowedHandlerPtr = reflect.ValueOf(echo.MethodNotAllowedHandler).Pointer()
+)
+
+// dynamicRouteKey is the key of the route of method and path, with the
+// leading slash Echo adds
+func dynamicRouteKey(method, path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return method + " " + path
+}
+
+// getRequestPath is the path Echo routes r by
+func getRequestPath(r *http.Request) string {
+	if r.URL.RawPath != "" {
+		return r.URL.RawPath
+	}
+	return r.URL.Path
+}
+
+// AddRoute registers a route at runtime, e.g. for a plugin or behind a feature
+// flag, replacing the runtime route of the same method and path if any. It is
+// safe to call while serving: the runtime routes are an immutable table
+// swapped atomically, a request in flight completes against the table it
+// started with, the next ones see the new one.
+//
+// Echo cannot remove a route from its router, so the runtime routes live in
+// a router of their own, rebuilt on each change, which has some limitations:
+//   - they are only looked up when no route registered on Echo matches,
+//     a static route of the same method and path wins;
+//   - they get the middleware of NewApiGateway and m, not the middleware
+//     added with Use afterwards;
+//   - Routes, Reverse and ServeOpenAPI do not list them.
+func (agw *ApiGateway) AddRoute(method, path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	for i := len(m) - 1; i >= 0; i-- {
+		h = m[i](h)
+	}
+	agw.dynamic.update(func(routes map[string]dynamicRoute) {
+		routes[dynamicRouteKey(method, path)] = dynamicRoute{method: method, path: path, handler: h}
+	})
+}
+
+// RemoveRoute removes a route added by AddRoute, it reports whether it was
+// there. See AddRoute for the concurrency model.
+func (agw *ApiGateway) RemoveRoute(method, path string) bool {
+	removed := false
+	agw.dynamic.update(func(routes map[string]dynamicRoute) {
+		key := dynamicRouteKey(method, path)
+		_, removed = routes[key]
+		delete(routes, key)
+	})
+	return removed
+}
+
+// update rebuilds the table from a copy of the routes modified by fn
+func (dr *dynamicRoutes) update(fn func(routes map[string]dynamicRoute)) {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	routes := map[string]dynamicRoute{}
+	if old := dr.table.Load(); old != nil {
+		for k, v := range old.routes {
+			routes[k] = v
+		}
+	}
+	fn(routes)
+	if len(routes) == 0 {
+		dr.table.Store(nil)
+		return
+	}
+
+	// a router of its own Echo, not to bump the param count of the gateway's
+	table := &dynamicTable{routes: routes, router: echo.NewRouter(echo.New())}
+	for _, r := range routes {
+		table.router.Add(r.method, r.path, r.handler)
+		table.maxParams = max(table.maxParams, strings.Count(r.path, ":")+strings.Count(r.path, "*"))
+	}
+	dr.table.Store(table)
+}
+
+// dynamicRoutesMiddleware serves the runtime routes of the requests no route
+// of Echo matched, it is the last middleware of NewApiGateway
+func (agw *ApiGateway) dynamicRoutesMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		table := agw.dynamic.table.Load()
+		if table == nil {
+			return next(c)
+		}
+		if routeMatched(c) {
+			return next(c)
+		}
+
+		handler, path, names := c.Handler(), c.Path(), c.ParamNames()
+		// Find fills the param values in place, the context is pooled and must
+		// keep them at least as long as the gateway routes need
+		values := c.ParamValues()
+		full := values[:cap(values)]
+		saved := append([]string(nil), values...)
+		if len(full) < table.maxParams {
+			full = make([]string, table.maxParams)
+		}
+		c.SetParamValues(full...)
+		table.router.Find(c.Request().Method, getRequestPath(c.Request()), c)
+		if _, ok := table.routes[dynamicRouteKey(c.Request().Method, c.Path())]; !ok {
+			copy(full, saved)
+			c.SetHandler(handler)
+			c.SetPath(path)
+			c.SetParamNames(names...)
+			c.SetParamValues(full...)
+			return next(c)
+		}
+		return c.Handler()(c)
+	}
+}
@@ -0,0 +1,94 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamicRoutes(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+	agw.GET("/static/:id", func(c echo.Context) error { return c.String(http.StatusOK, "static "+c.Param("id")) })
+	h := agw.Handler()
+
+	get := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	code, _ := get("/plugins/p1")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	agw.AddRoute(http.MethodGet, "/plugins/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Path()+" "+c.Param("id"))
+	}, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Set("X-Plugin", "1")
+			return next(c)
+		}
+	})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/plugins/p1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "/plugins/:id p1", rec.Body.String())
+	assert.Equal(t, "1", rec.Header().Get("X-Plugin"))
+
+	// a static route wins over a runtime one
+	agw.AddRoute(http.MethodGet, "/static/:id", func(c echo.Context) error { return c.String(http.StatusOK, "dynamic") })
+	code, body := get("/static/s1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "static s1", body)
+
+	// more params than any static route, the pooled context keeps working
+	agw.AddRoute(http.MethodGet, "/a/:x/:y/:z", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Param("x")+c.Param("y")+c.Param("z"))
+	})
+	code, body = get("/a/1/2/3")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "123", body)
+	code, body = get("/static/s2")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "static s2", body)
+
+	assert.True(t, agw.RemoveRoute(http.MethodGet, "/plugins/:id"))
+	assert.False(t, agw.RemoveRoute(http.MethodGet, "/plugins/:id"))
+	code, _ = get("/plugins/p1")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = get("/a/1/2/3")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestDynamicRoutesConcurrent(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+	h := agw.Handler()
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			agw.AddRoute(http.MethodGet, "/flag/:name", ok)
+			agw.RemoveRoute(http.MethodGet, "/flag/:name")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/flag/x", nil))
+			assert.Contains(t, []int{http.StatusOK, http.StatusNotFound}, rec.Code)
+		}
+	}()
+	wg.Wait()
+}
@@ -68,20 +68,27 @@ func SendResp(c echo.Context, resp error) (err error) {
 	}
 
 	if resp == nil {
-		rid := errCodeDic.NewRequestId()
-		c.Response().Header().Set(echo.HeaderXRequestID, rid)
+		c.Response().Header().Set(echo.HeaderXRequestID, currentOrNewRequestId(c))
 		return c.NoContent(http.StatusOK)
 	}
 
 	jr := Wrap(resp)
 	if jr.RequestId == "" {
-		jr.RequestId = errCodeDic.NewRequestId()
+		jr.RequestId = currentOrNewRequestId(c)
 	}
 	c.Response().Header().Set(echo.HeaderXRequestID, jr.RequestId)
 
 	return jr.cjson(c)
 }
 
+// currentOrNewRequestId keeps the id picked by RequestIdWithConfig, if any
+func currentOrNewRequestId(c echo.Context) string {
+	if id := GetRequestId(c); id != "" {
+		return id
+	}
+	return errCodeDic.NewRequestId()
+}
+
 func ServeContent(w http.ResponseWriter, req *http.Request, name string, modTime time.Time, length int64, content io.ReadSeeker) {
 	rid := errCodeDic.NewRequestId()
 	w.Header().Set(echo.HeaderXRequestID, rid)
@@ -0,0 +1,50 @@
+package httpx
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+)
+
+// wrapHTTPErrorHandler makes the HTTPErrorHandler of the Echo log the error
+// first, so that no server error goes unnoticed when the handler does not log
+// it itself
+func (agw *ApiGateway) wrapHTTPErrorHandler() {
+	next := agw.Echo.HTTPErrorHandler
+	if next == nil {
+		next = agw.Echo.DefaultHTTPErrorHandler
+	}
+	agw.Echo.HTTPErrorHandler = func(err error, c echo.Context) {
+		agw.logHandlerError(err, c)
+		next(err, c)
+	}
+}
+
+// logHandlerError logs err at the level of its status, see
+// LogConfig.ErrorLevels
+func (agw *ApiGateway) logHandlerError(err error, c echo.Context) {
+	status := errorStatus(err)
+	level := agw.errorLevels.level(status, logrus.InfoLevel)
+	if !agw.Logger.IsLevelEnabled(level) {
+		return
+	}
+	req := c.Request()
+	agw.Logger.WithFields(logrus.Fields{
+		"id":            GetRequestId(c),
+		"method":        req.Method,
+		"path":          req.URL.Path,
+		"route":         c.Path(),
+		"status":        status,
+		logrus.ErrorKey: err.Error(),
+	}).Log(level, "request failed")
+}
+
+// errorStatus is the status HTTPErrorHandler answers err with, as SendResp
+// tells it
+func errorStatus(err error) int {
+	if jr := Wrap(err); jr != nil && jr.Status != 0 {
+		return jr.Status
+	}
+	return http.StatusInternalServerError
+}
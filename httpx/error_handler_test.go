@@ -0,0 +1,71 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerErrorsLogged(t *testing.T) {
+	e := echo.New()
+	var written []error
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		written = append(written, err)
+		_ = c.NoContent(errorStatus(err))
+	}
+	agw, err := NewApiGatewayWithEcho(context.Background(), &LogConfig{
+		LogFile:     log.FileConfig{Filename: "discard"},
+		ErrorLevels: map[string]string{"5xx": "error", "4xx": "debug"},
+	}, nil, e)
+	require.NoError(t, err)
+	hook := test.NewLocal(agw.Logger.Logger)
+	agw.GET("/fail/:id", func(c echo.Context) error { return errors.New("db down") })
+	agw.GET("/missing", func(c echo.Context) error { return echo.NewHTTPError(http.StatusNotFound, "no such thing") })
+	h := agw.Handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail/1", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Len(t, written, 1)
+
+	var failed []*logrus.Entry
+	for _, en := range hook.AllEntries() {
+		if en.Message == "request failed" {
+			failed = append(failed, en)
+		}
+	}
+	require.Len(t, failed, 1)
+	assert.Equal(t, logrus.ErrorLevel, failed[0].Level)
+	assert.Equal(t, "db down", failed[0].Data[logrus.ErrorKey])
+	assert.Equal(t, http.StatusInternalServerError, failed[0].Data["status"])
+	assert.Equal(t, "/fail/1", failed[0].Data["path"])
+	assert.Equal(t, "/fail/:id", failed[0].Data["route"])
+	assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), failed[0].Data["id"])
+
+	// 4xx at debug, below the logger level
+	hook.Reset()
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Len(t, written, 2)
+	for _, en := range hook.AllEntries() {
+		assert.NotEqual(t, "request failed", en.Message)
+	}
+}
+
+func TestErrorLevelsInvalid(t *testing.T) {
+	_, err := NewApiGateway(context.Background(), &LogConfig{
+		LogFile:     log.FileConfig{Filename: "discard"},
+		ErrorLevels: map[string]string{"6xx": "error"},
+	}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	defaultETagMaxSize = 1 << 20
+
+	headerETag = "ETag"
+)
+
+// etagWriter holds back a GET response to set its ETag, or to answer 304 when
+// If-None-Match matches. The held body feeds the body_out dump too, so it is
+// buffered once. Non-2xx responses, flushed ones and those growing beyond max
+// are streamed as is.
+type etagWriter struct {
+	http.ResponseWriter
+	dump io.Writer
+	max  int64
+
+	buf         bytes.Buffer
+	status      int
+	passthrough bool
+}
+
+func newETagWriter(w http.ResponseWriter, dump io.Writer, max int64) *etagWriter {
+	if max <= 0 {
+		max = defaultETagMaxSize
+	}
+	return &etagWriter{ResponseWriter: w, dump: dump, max: max, status: http.StatusOK}
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	w.status = code
+	if code < 200 || code >= 300 {
+		w.stream()
+	}
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if !w.passthrough && int64(w.buf.Len()+len(b)) > w.max {
+		w.stream()
+	}
+	if w.passthrough {
+		if w.dump != nil {
+			_, _ = w.dump.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// stream gives up on the ETag, sending what is held so far
+func (w *etagWriter) stream() {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		if w.dump != nil {
+			_, _ = w.dump.Write(w.buf.Bytes())
+		}
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// finish sends the held response, with an ETag unless the handler set one.
+func (w *etagWriter) finish(c echo.Context) {
+	if w.passthrough {
+		return
+	}
+	res := c.Response()
+	if !res.Committed {
+		// nothing was written, leave it to the error handling
+		return
+	}
+	w.passthrough = true
+
+	header := w.Header()
+	etag := header.Get(headerETag)
+	if etag == "" {
+		sum := sha1.Sum(w.buf.Bytes())
+		etag = `"` + hex.EncodeToString(sum[:]) + `"`
+		header.Set(headerETag, etag)
+	}
+
+	if ifNoneMatch(c.Request().Header.Get("If-None-Match"), etag) {
+		header.Del(echo.HeaderContentLength)
+		header.Del(echo.HeaderContentType)
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		res.Status = http.StatusNotModified
+		res.Size = 0
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.dump != nil {
+		_, _ = w.dump.Write(w.buf.Bytes())
+	}
+	_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+}
+
+func (w *etagWriter) Flush() {
+	w.stream()
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (w *etagWriter) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+// ifNoneMatch reports whether the If-None-Match header value inm matches etag
+func ifNoneMatch(inm, etag string) bool {
+	for _, v := range strings.Split(inm, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || (v != "" && etagWeakMatch(v, etag)) {
+			return true
+		}
+	}
+	return false
+}
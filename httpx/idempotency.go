@@ -0,0 +1,239 @@
+package httpx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+	"github.com/madlabx/pkgx/errors"
+	"github.com/madlabx/pkgx/log"
+)
+
+const (
+	// HeaderIdempotencyKey names the operation a request is a try of, the
+	// same on the retries, e.g. a UUID generated by the client.
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed is set to true on a response replayed from
+	// the IdempotencyStore.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	defaultIdempotencyTTL     = 24 * time.Hour
+	defaultIdempotencyMaxSize = 1 << 20
+)
+
+type (
+	// IdempotencyConfig defines the config of Idempotency.
+	IdempotencyConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper middleware.Skipper
+
+		// Store keeps the responses. Optional. Default value a
+		// MemoryIdempotencyStore of its own.
+		Store IdempotencyStore
+
+		// TTL is how long a response is replayed for its key. Optional.
+		// Default value 24h.
+		TTL time.Duration
+
+		// MaxSize bounds the body of a stored response, a larger one is sent
+		// but not stored. Optional. Default value 1MB.
+		MaxSize int64
+	}
+
+	// IdempotentResponse is a response stored for an idempotency key.
+	IdempotentResponse struct {
+		Status int
+		Header http.Header
+		Body   []byte
+	}
+
+	// IdempotencyStore stores the responses of Idempotency by key, e.g. in
+	// Redis for the instances of a service to share them. The keys are hex
+	// encoded hashes. Get returns nil and no error for a key not stored or
+	// expired.
+	IdempotencyStore interface {
+		Get(ctx context.Context, key string) (*IdempotentResponse, error)
+		Set(ctx context.Context, key string, res *IdempotentResponse, ttl time.Duration) error
+	}
+
+	// MemoryIdempotencyStore is an in-memory IdempotencyStore, the expired
+	// entries are dropped on the way.
+	MemoryIdempotencyStore struct {
+		mu        sync.Mutex
+		entries   map[string]memoryIdempotencyEntry
+		nextSweep time.Time
+	}
+
+	memoryIdempotencyEntry struct {
+		res     *IdempotentResponse
+		expires time.Time
+	}
+)
+
+// NewMemoryIdempotencyStore creates an empty MemoryIdempotencyStore.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: make(map[string]memoryIdempotencyEntry)}
+}
+
+// Get returns the response stored for key, nil if none.
+func (s *MemoryIdempotencyStore) Get(_ context.Context, key string) (*IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(e.expires) {
+		delete(s.entries, key)
+		return nil, nil
+	}
+	return e.res, nil
+}
+
+// Set stores res for key for ttl.
+func (s *MemoryIdempotencyStore) Set(_ context.Context, key string, res *IdempotentResponse, ttl time.Duration) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.After(s.nextSweep) {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+	s.entries[key] = memoryIdempotencyEntry{res: res, expires: now.Add(ttl)}
+	return nil
+}
+
+// Idempotency returns a middleware deduplicating the retries of a request by
+// its Idempotency-Key header, for a route whose clients retry, e.g. a
+// payment:
+//
+//	agw.POST("/payments", pay, httpx.Idempotency(httpx.IdempotencyConfig{}))
+//
+// The first request with a key runs the handler and its response is stored
+// for config.TTL, the next ones get it replayed with Idempotent-Replayed:
+// true, without running the handler. The ones coming while the first is in
+// flight wait for it. A key is scoped to the method, the path and the
+// Authorization header of the request, the clients do not share them. A
+// request without the header passes.
+//
+// Only a response written by the handler below 500 is stored, a 5xx, an
+// error returned by the handler or a body over config.MaxSize is not, the
+// next try runs the handler again. The waiting is within the middleware, with
+// a store shared by several instances, two tries in flight on two instances
+// at once both run the handler.
+func Idempotency(config IdempotencyConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryIdempotencyStore()
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultIdempotencyTTL
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultIdempotencyMaxSize
+	}
+	var (
+		mu       sync.Mutex
+		inFlight = make(map[string]chan struct{})
+	)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if config.Skipper(c) || req.Header.Get(HeaderIdempotencyKey) == "" {
+				return next(c)
+			}
+			key := idempotencyKey(req)
+			ctx := req.Context()
+
+			// the store is read once the key is ours, after the first try
+			// stored its response
+			for {
+				mu.Lock()
+				done, ok := inFlight[key]
+				if !ok {
+					inFlight[key] = make(chan struct{})
+					mu.Unlock()
+					break
+				}
+				mu.Unlock()
+				select {
+				case <-done:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			defer func() {
+				mu.Lock()
+				close(inFlight[key])
+				delete(inFlight, key)
+				mu.Unlock()
+			}()
+
+			stored, err := config.Store.Get(ctx, key)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get the response of idempotency key")
+			}
+			if stored != nil {
+				return writeIdempotent(c, stored)
+			}
+
+			res := c.Response()
+			cw := &cacheCaptureWriter{ResponseWriter: res.Writer, limit: config.MaxSize}
+			res.Writer = cw
+			defer func() { res.Writer = cw.ResponseWriter }()
+
+			if err := next(c); err != nil {
+				return err
+			}
+			if !res.Committed || res.Status >= http.StatusInternalServerError || cw.overflow {
+				return nil
+			}
+			header := res.Header().Clone()
+			// the id belongs to the first try
+			header.Del(echo.HeaderXRequestID)
+			if err := config.Store.Set(ctx, key, &IdempotentResponse{
+				Status: res.Status,
+				Header: header,
+				Body:   cw.buf.Bytes(),
+			}, config.TTL); err != nil {
+				log.Errorf("failed to store the response of idempotency key, %v", err)
+			}
+			return nil
+		}
+	}
+}
+
+// idempotencyKey hashes the scope of the key with it, not to hold the
+// credentials in the store
+func idempotencyKey(req *http.Request) string {
+	h := sha256.New()
+	for _, s := range []string{req.Method, req.URL.Path, req.Header.Get(echo.HeaderAuthorization), req.Header.Get(HeaderIdempotencyKey)} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeIdempotent replays stored to c, its header and body are copied
+func writeIdempotent(c echo.Context, stored *IdempotentResponse) error {
+	header := c.Response().Header()
+	for k, v := range stored.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set(HeaderIdempotentReplayed, "true")
+	c.Response().WriteHeader(stored.Status)
+	_, err := c.Response().Write(stored.Body)
+	return err
+}
@@ -0,0 +1,133 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency(t *testing.T) {
+	e := echo.New()
+	var calls atomic.Int32
+	e.POST("/payments", func(c echo.Context) error {
+		n := calls.Add(1)
+		c.Response().Header().Set("X-Payment", strconv.Itoa(int(n)))
+		return c.String(http.StatusCreated, "payment "+strconv.Itoa(int(n)))
+	}, Idempotency(IdempotencyConfig{}))
+	e.POST("/flaky", func(c echo.Context) error {
+		calls.Add(1)
+		return c.String(http.StatusBadGateway, "later")
+	}, Idempotency(IdempotencyConfig{}))
+
+	post := func(path, key, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		req.Header.Set(echo.HeaderAuthorization, auth)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("/payments", "k1", "alice")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "payment 1", rec.Body.String())
+	assert.Empty(t, rec.Header().Get(HeaderIdempotentReplayed))
+
+	rec = post("/payments", "k1", "alice")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "payment 1", rec.Body.String())
+	assert.Equal(t, "1", rec.Header().Get("X-Payment"))
+	assert.Equal(t, "true", rec.Header().Get(HeaderIdempotentReplayed))
+	assert.EqualValues(t, 1, calls.Load())
+
+	// another key, another client or no key at all run the handler
+	assert.Equal(t, "payment 2", post("/payments", "k2", "alice").Body.String())
+	assert.Equal(t, "payment 3", post("/payments", "k1", "bob").Body.String())
+	assert.Equal(t, "payment 4", post("/payments", "", "alice").Body.String())
+	assert.Equal(t, "payment 5", post("/payments", "", "alice").Body.String())
+
+	// a 5xx is not stored
+	calls.Store(0)
+	post("/flaky", "k1", "alice")
+	rec = post("/flaky", "k1", "alice")
+	assert.Empty(t, rec.Header().Get(HeaderIdempotentReplayed))
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	e := echo.New()
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	e.POST("/payments", func(c echo.Context) error {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return c.String(http.StatusCreated, "paid")
+	}, Idempotency(IdempotencyConfig{}))
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/payments", nil)
+		req.Header.Set(HeaderIdempotencyKey, "k1")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	recs := make([]*httptest.ResponseRecorder, 4)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		recs[0] = post()
+	}()
+	<-started
+	for i := 1; i < len(recs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			recs[i] = post()
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, calls.Load())
+	replayed := 0
+	for _, rec := range recs {
+		assert.Equal(t, "paid", rec.Body.String())
+		if rec.Header().Get(HeaderIdempotentReplayed) == "true" {
+			replayed++
+		}
+	}
+	assert.Equal(t, len(recs)-1, replayed)
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	s := NewMemoryIdempotencyStore()
+	ctx := context.Background()
+	require.NoError(t, s.Set(ctx, "a", &IdempotentResponse{Status: http.StatusOK}, time.Hour))
+	require.NoError(t, s.Set(ctx, "b", &IdempotentResponse{Status: http.StatusOK}, -time.Second))
+
+	res, err := s.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.Status)
+	res, err = s.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.Nil(t, res)
+	res, err = s.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.Nil(t, res)
+}
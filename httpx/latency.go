@@ -0,0 +1,89 @@
+package httpx
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// LatencyStats summarizes the latency of the last Samples requests.
+type LatencyStats struct {
+	Samples int
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// latencySummary keeps the latency of the last requests in a ring, see
+// ServerConfig.LatencyWindow. The quantiles are exact over the window, at the
+// cost of 8 bytes per sample and a sort of the window on each stats call,
+// which is fine for windows up to some 10k samples read at a human pace. The
+// window counts requests, not time, so it spans seconds under load and may
+// hold old samples on a quiet gateway.
+type latencySummary struct {
+	mutex   sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+func newLatencySummary(sc *ServerConfig) *latencySummary {
+	if sc == nil || sc.LatencyWindow <= 0 {
+		return nil
+	}
+	return &latencySummary{samples: make([]time.Duration, sc.LatencyWindow)}
+}
+
+func (ls *latencySummary) add(d time.Duration) {
+	ls.mutex.Lock()
+	ls.samples[ls.next] = d
+	ls.next++
+	if ls.next == len(ls.samples) {
+		ls.next, ls.full = 0, true
+	}
+	ls.mutex.Unlock()
+}
+
+func (ls *latencySummary) stats() LatencyStats {
+	ls.mutex.Lock()
+	n := ls.next
+	if ls.full {
+		n = len(ls.samples)
+	}
+	sorted := append([]time.Duration(nil), ls.samples[:n]...)
+	ls.mutex.Unlock()
+
+	if n == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	quantile := func(q float64) time.Duration {
+		// nearest rank
+		i := int(q*float64(n)+0.999999) - 1
+		return sorted[min(max(i, 0), n-1)]
+	}
+	return LatencyStats{
+		Samples: n,
+		P50:     quantile(0.50),
+		P90:     quantile(0.90),
+		P99:     quantile(0.99),
+		Max:     sorted[n-1],
+	}
+}
+
+// latencyMiddleware records the latency of each request in agw.latency, set
+// up by Run from ServerConf. CORS preflight requests are left out.
+func (agw *ApiGateway) latencyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ls := agw.latency
+		if ls == nil || IsPreflight(c) {
+			return next(c)
+		}
+		start := time.Now()
+		defer func() { ls.add(time.Since(start)) }()
+		return next(c)
+	}
+}
@@ -0,0 +1,49 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencySummary(t *testing.T) {
+	ls := newLatencySummary(&ServerConfig{LatencyWindow: 100})
+	assert.Equal(t, LatencyStats{}, ls.stats())
+
+	// the window keeps the last 100 of 1..150ms
+	for i := 1; i <= 150; i++ {
+		ls.add(time.Duration(i) * time.Millisecond)
+	}
+	st := ls.stats()
+	assert.Equal(t, 100, st.Samples)
+	assert.Equal(t, 100*time.Millisecond, st.P50)
+	assert.Equal(t, 140*time.Millisecond, st.P90)
+	assert.Equal(t, 149*time.Millisecond, st.P99)
+	assert.Equal(t, 150*time.Millisecond, st.Max)
+
+	assert.Nil(t, newLatencySummary(&ServerConfig{}))
+}
+
+func TestStatsLatency(t *testing.T) {
+	agw := &ApiGateway{Echo: echo.New(), ServerConf: &ServerConfig{LatencyWindow: 8}}
+	agw.Use(agw.latencyMiddleware)
+	agw.GET("/slow", func(c echo.Context) error {
+		time.Sleep(5 * time.Millisecond)
+		return c.NoContent(http.StatusOK)
+	})
+	h := agw.Handler()
+	assert.Nil(t, (&ApiGateway{}).Stats().Latency)
+
+	for i := 0; i < 3; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}
+	st := agw.Stats().Latency
+	require.NotNil(t, st)
+	assert.Equal(t, 3, st.Samples)
+	assert.GreaterOrEqual(t, st.P50, 5*time.Millisecond)
+}
@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package httpx
+
+import (
+	"github.com/madlabx/pkgx/errors"
+)
+
+func setReusePort(_ uintptr) error {
+	return errors.New("SO_REUSEPORT is not supported on this platform")
+}
+
+func setBacklog(_ uintptr, _ int) error {
+	return errors.New("listener backlog is not supported on this platform")
+}
@@ -0,0 +1,20 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package httpx
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func setReusePort(fd uintptr) error {
+	return unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+}
+
+// setBacklog re-issues listen(2) on an already listening socket, which updates
+// the accept queue length on Linux and the BSDs.
+func setBacklog(fd uintptr, backlog int) error {
+	return syscall.Listen(int(fd), backlog)
+}
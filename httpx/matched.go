@@ -0,0 +1,44 @@
+package httpx
+
+import (
+	"reflect"
+
+	"github.com/labstack/echo"
+)
+
+// UseOnMatched adds middleware which only run for the requests a route
+// matched, after the middleware of the gateway and those of Use, e.g. an
+// authentication or a body capture not to spend on bogus paths:
+//
+//	agw.UseOnMatched(auth, audit)
+//
+// Echo runs the middleware of Use around its not found handler too, so they
+// see every request, the 404 and 405 ones included, those of UseOnMatched are
+// skipped for them and the request goes on to the 404 or 405 as if they were
+// not there. The middleware of a group or a route only see the matched
+// requests as well, UseOnMatched is for the ones of all the routes. The
+// runtime routes of AddRoute do not go through them.
+func (agw *ApiGateway) UseOnMatched(middleware ...echo.MiddlewareFunc) {
+	for _, mw := range middleware {
+		agw.Use(onMatched(mw))
+	}
+}
+
+func onMatched(mw echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		h := mw(next)
+		return func(c echo.Context) error {
+			if !routeMatched(c) {
+				return next(c)
+			}
+			return h(c)
+		}
+	}
+}
+
+// routeMatched reports whether Echo routed c to a handler, not to its not
+// found or method not allowed ones, compared by code pointer
+func routeMatched(c echo.Context) bool {
+	hp := reflect.ValueOf(c.Handler()).Pointer()
+	return hp != notFoundHandlerPtr && hp != methodNotAllowedHandlerPtr
+}
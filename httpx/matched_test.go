@@ -0,0 +1,50 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseOnMatched(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+	var all, matched []string
+	agw.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			all = append(all, c.Request().URL.Path)
+			return next(c)
+		}
+	})
+	agw.UseOnMatched(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			matched = append(matched, c.Request().URL.Path)
+			c.Response().Header().Set("X-Matched", "1")
+			return next(c)
+		}
+	})
+	agw.GET("/users/:id", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+		matched      string
+	}{
+		{http.MethodGet, "/users/1", http.StatusOK, "1"},
+		{http.MethodGet, "/nope", http.StatusNotFound, ""},
+		{http.MethodDelete, "/users/2", http.StatusMethodNotAllowed, ""},
+	} {
+		rec := httptest.NewRecorder()
+		agw.Handler().ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.want, rec.Code, tc.path)
+		assert.Equal(t, tc.matched, rec.Header().Get("X-Matched"), tc.path)
+	}
+	assert.Equal(t, []string{"/users/1", "/nope", "/users/2"}, all)
+	assert.Equal(t, []string{"/users/1"}, matched)
+}
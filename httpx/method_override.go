@@ -0,0 +1,106 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultOverrideMethods are the methods a POST may be overridden to
+var defaultOverrideMethods = []string{http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+type (
+	// MethodOverrideConfig defines the config for MethodOverrideWithConfig.
+	MethodOverrideConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper middleware.Skipper
+
+		// Header carries the method, e.g. X-HTTP-Method-Override, Form names
+		// the field of an application/x-www-form-urlencoded body carrying it,
+		// e.g. _method. The header is looked up first, at least one of them
+		// is required for the middleware to do anything.
+		Header string
+		Form   string
+
+		// Methods are the methods allowed as override, another one is ignored.
+		// Optional. Default value PUT, PATCH and DELETE.
+		Methods []string
+
+		// Logger logs each override at info with the original method.
+		// Optional. Default value none.
+		Logger *logrus.Logger
+	}
+)
+
+// MethodOverrideWithConfig returns a middleware rewriting the method of a
+// POST request to the one of config.Header or config.Form, for the clients
+// which can only send GET and POST to reach the PUT, PATCH and DELETE routes.
+// Install it with Pre, it must run before routing:
+//
+//	e.Pre(httpx.MethodOverrideWithConfig(httpx.MethodOverrideConfig{Header: "X-HTTP-Method-Override"}))
+//
+// Only a POST is overridden, and only to config.Methods. Reading the form
+// field consumes the body, the handlers find it in the parsed form then. The
+// gateway installs it from LogConfig.MethodOverrideHeader and
+// MethodOverrideForm.
+func MethodOverrideWithConfig(config MethodOverrideConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if len(config.Methods) == 0 {
+		config.Methods = defaultOverrideMethods
+	}
+	allowed := make(map[string]bool, len(config.Methods))
+	for _, m := range config.Methods {
+		allowed[strings.ToUpper(m)] = true
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Method != http.MethodPost || config.Skipper(c) {
+				return next(c)
+			}
+
+			var method string
+			if config.Header != "" {
+				method = req.Header.Get(config.Header)
+			}
+			if method == "" && config.Form != "" &&
+				strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationForm) {
+				method = req.PostFormValue(config.Form)
+			}
+			method = strings.ToUpper(strings.TrimSpace(method))
+			if !allowed[method] {
+				return next(c)
+			}
+
+			req.Method = method
+			if config.Logger != nil {
+				config.Logger.WithFields(logrus.Fields{
+					"method_original": http.MethodPost,
+					"method":          method,
+					"uri":             req.RequestURI,
+					"remote_ip":       c.RealIP(),
+				}).Info("method override")
+			}
+			return next(c)
+		}
+	}
+}
+
+// methodOverrideMiddleware is MethodOverrideWithConfig from the LogConfig, nil
+// when disabled
+func (agw *ApiGateway) methodOverrideMiddleware() echo.MiddlewareFunc {
+	if agw.LogConf.MethodOverrideHeader == "" && agw.LogConf.MethodOverrideForm == "" {
+		return nil
+	}
+	return MethodOverrideWithConfig(MethodOverrideConfig{
+		Header: agw.LogConf.MethodOverrideHeader,
+		Form:   agw.LogConf.MethodOverrideForm,
+		Logger: agw.Logger.Logger,
+	})
+}
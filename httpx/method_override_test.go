@@ -0,0 +1,71 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodOverride(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{
+		LogFile:              log.FileConfig{Filename: "discard"},
+		MethodOverrideHeader: "X-HTTP-Method-Override",
+		MethodOverrideForm:   "_method",
+		QuietStartup:         true,
+	}, nil)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	agw.Logger.SetOutput(&buf)
+	method := func(c echo.Context) error { return c.String(http.StatusOK, c.Request().Method+" "+c.FormValue("name")) }
+	agw.POST("/users/:id", method)
+	agw.PUT("/users/:id", method)
+	agw.DELETE("/users/:id", method)
+	agw.GET("/users/:id", method)
+
+	do := func(method, header string, form url.Values) string {
+		req := httptest.NewRequest(method, "/users/1", strings.NewReader(form.Encode()))
+		if form != nil {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		}
+		if header != "" {
+			req.Header.Set("X-HTTP-Method-Override", header)
+		}
+		rec := httptest.NewRecorder()
+		agw.Handler().ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	assert.Equal(t, "DELETE ", do(http.MethodPost, "delete", nil))
+	assert.Equal(t, "PUT alice", do(http.MethodPost, "", url.Values{"_method": {"PUT"}, "name": {"alice"}}))
+	// the header comes first
+	assert.Equal(t, "DELETE ", do(http.MethodPost, "DELETE", url.Values{"_method": {"PUT"}}))
+	// from POST only, to the allowed methods only
+	assert.Equal(t, "GET ", do(http.MethodGet, "DELETE", nil))
+	assert.Equal(t, "POST ", do(http.MethodPost, "GET", nil))
+	assert.Equal(t, "POST ", do(http.MethodPost, "CONNECT", nil))
+
+	assert.Contains(t, buf.String(), "method override")
+	assert.Contains(t, buf.String(), "method_original=POST")
+	assert.Contains(t, buf.String(), "method=DELETE")
+}
+
+func TestMethodOverrideDisabled(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+	agw.POST("/users", func(c echo.Context) error { return c.String(http.StatusOK, c.Request().Method) })
+
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	req.Header.Set("X-HTTP-Method-Override", http.MethodDelete)
+	rec := httptest.NewRecorder()
+	agw.Handler().ServeHTTP(rec, req)
+	assert.Equal(t, http.MethodPost, rec.Body.String())
+}
@@ -0,0 +1,42 @@
+package httpx
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+// multipartMiddleware parses the multipart forms up front with
+// ServerConf.MaxMultipartMemory, so that the 32MB Echo and net/http parse them
+// with by default does not apply, and removes the temporary files of the
+// parts spilled to disk once the handler is done, whoever parsed the form.
+func (agw *ApiGateway) multipartMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		defer func() {
+			if form := c.Request().MultipartForm; form != nil {
+				_ = form.RemoveAll()
+			}
+		}()
+
+		if maxMemory := agw.multipartMemory; maxMemory > 0 && isMultipartForm(req) {
+			if err := req.ParseMultipartForm(maxMemory); err != nil {
+				return SendResp(c, echo.NewHTTPError(http.StatusBadRequest, "invalid multipart form: "+err.Error()))
+			}
+		}
+		return next(c)
+	}
+}
+
+func newMultipartMemory(sc *ServerConfig) int64 {
+	if sc == nil {
+		return 0
+	}
+	return sc.MaxMultipartMemory
+}
+
+func isMultipartForm(req *http.Request) bool {
+	mt, _, err := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	return err == nil && mt == echo.MIMEMultipartForm
+}
@@ -0,0 +1,75 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMultipartRequest(t *testing.T, content []byte) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "upload.bin")
+	require.NoError(t, err)
+	_, _ = fw.Write(content)
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+	return req
+}
+
+func TestMultipartMemory(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+	agw.ServerConf = &ServerConfig{MaxMultipartMemory: 1024}
+
+	var spilled string
+	agw.POST("/upload", func(c echo.Context) error {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return err
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if osf, ok := f.(*os.File); ok {
+			spilled = osf.Name()
+		}
+		return c.String(http.StatusOK, fh.Filename)
+	})
+	h := agw.Handler()
+
+	// past MaxMultipartMemory the part is spilled, then removed
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newMultipartRequest(t, bytes.Repeat([]byte("x"), 4096)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "upload.bin", rec.Body.String())
+	require.NotEmpty(t, spilled)
+	_, err = os.Stat(spilled)
+	assert.True(t, os.IsNotExist(err), "temporary file %s left", spilled)
+
+	spilled = ""
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newMultipartRequest(t, []byte("small")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, spilled)
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("--x\r\nbroken"))
+	req.Header.Set(echo.HeaderContentType, "multipart/form-data; boundary=x")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,176 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/errors"
+)
+
+const defaultOpenAPIPath = "/openapi.json"
+
+// OpenAPIConfig configures the OpenAPI endpoint of ServeOpenAPI.
+type OpenAPIConfig struct {
+	// Path of the endpoint, default /openapi.json.
+	Path string
+	// Base is a hand-written OpenAPI 3 JSON document, e.g. with info,
+	// components and the schemas of some operations. The generated paths and
+	// operations are merged into it, the fields set in Base win.
+	Base []byte
+	// Middleware guards the endpoint, e.g. middleware.BasicAuth or
+	// middleware.KeyAuth, as it exposes every route of the gateway.
+	Middleware []echo.MiddlewareFunc
+}
+
+// ServeOpenAPI registers a GET endpoint serving a skeleton OpenAPI 3 document
+// of the routes registered on the gateway: paths, methods, path parameters and
+// operation ids from route names. It infers no request or response schema.
+// The document is built on each request, so routes added later show up too.
+func (agw *ApiGateway) ServeOpenAPI(config OpenAPIConfig) error {
+	if config.Path == "" {
+		config.Path = defaultOpenAPIPath
+	}
+	base := map[string]interface{}{}
+	if len(config.Base) > 0 {
+		if err := json.Unmarshal(config.Base, &base); err != nil {
+			return errors.Wrapf(err, "invalid OpenAPI base document")
+		}
+	}
+
+	agw.GET(config.Path, func(c echo.Context) error {
+		doc, err := agw.openAPIDocument(base, config.Path)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, doc)
+	}, config.Middleware...)
+	return nil
+}
+
+var openAPIMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPut: true, http.MethodPost: true, http.MethodDelete: true,
+	http.MethodOptions: true, http.MethodHead: true, http.MethodPatch: true, http.MethodTrace: true,
+}
+
+// openAPIDocument merges the routes, except the endpoint at self, into a copy
+// of base
+func (agw *ApiGateway) openAPIDocument(base map[string]interface{}, self string) (map[string]interface{}, error) {
+	// deep copy, the document is modified below
+	raw, err := json.Marshal(base)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to copy OpenAPI base document")
+	}
+	doc := map[string]interface{}{}
+	if err = json.Unmarshal(raw, &doc); err != nil {
+		return nil, errors.Wrapf(err, "failed to copy OpenAPI base document")
+	}
+
+	if _, ok := doc["openapi"]; !ok {
+		doc["openapi"] = "3.0.3"
+	}
+	if _, ok := doc["info"]; !ok {
+		doc["info"] = map[string]interface{}{"title": "API", "version": "0.0.0"}
+	}
+	paths, ok := doc["paths"].(map[string]interface{})
+	if !ok {
+		paths = map[string]interface{}{}
+		doc["paths"] = paths
+	}
+
+	routes := agw.Routes()
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	opIds := map[string]bool{}
+	for _, r := range routes {
+		if r.Path == self || !openAPIMethods[r.Method] {
+			continue
+		}
+		path, params := openAPIPath(r.Path)
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		method := strings.ToLower(r.Method)
+		op, ok := item[method].(map[string]interface{})
+		if !ok {
+			op = map[string]interface{}{}
+			item[method] = op
+		}
+
+		if _, ok := op["operationId"]; !ok {
+			op["operationId"] = uniqueOperationId(opIds, r.Name, method)
+		}
+		if _, ok := op["parameters"]; !ok && len(params) > 0 {
+			op["parameters"] = params
+		}
+		if _, ok := op["responses"]; !ok {
+			op["responses"] = map[string]interface{}{
+				"default": map[string]interface{}{"description": "response"},
+			}
+		}
+	}
+	return doc, nil
+}
+
+// openAPIPath converts an Echo path, /users/:id/*, to its OpenAPI form,
+// /users/{id}/{wildcard}, with the path parameters
+func openAPIPath(p string) (string, []interface{}) {
+	var params []interface{}
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		var name string
+		switch {
+		case strings.HasPrefix(s, ":"):
+			name = s[1:]
+		case s == "*":
+			name = "wildcard"
+		default:
+			continue
+		}
+		segs[i] = "{" + name + "}"
+		params = append(params, map[string]interface{}{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	return strings.Join(segs, "/"), params
+}
+
+// uniqueOperationId derives an operation id from the route name, the handler
+// function name by default, e.g. github.com/x/api.(*Users).Get-fm gives
+// Users.Get. A name used already is suffixed by the method, then a number.
+func uniqueOperationId(seen map[string]bool, name, method string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	name = strings.NewReplacer("(", "", ")", "", "*", "").Replace(name)
+	if name == "" {
+		name = method
+	}
+
+	id := name
+	if seen[id] {
+		id = name + "_" + method
+	}
+	for n := 2; seen[id]; n++ {
+		id = name + "_" + method + "_" + strconv.Itoa(n)
+	}
+	seen[id] = true
+	return id
+}
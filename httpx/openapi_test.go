@@ -0,0 +1,68 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getUser(c echo.Context) error { return c.NoContent(http.StatusOK) }
+
+func TestServeOpenAPI(t *testing.T) {
+	agw := &ApiGateway{Echo: echo.New()}
+	agw.GET("/users/:id", getUser)
+	agw.PUT("/users/:id", getUser)
+	agw.GET("/files/*", func(c echo.Context) error { return nil })
+	require.NoError(t, agw.ServeOpenAPI(OpenAPIConfig{
+		Base: []byte(`{"info":{"title":"users","version":"1.0"},
+			"paths":{"/users/{id}":{"get":{"summary":"get a user","operationId":"userById"}}}}`),
+		Middleware: []echo.MiddlewareFunc{middleware.KeyAuth(func(key string, c echo.Context) (bool, error) {
+			return key == "secret", nil
+		})},
+	}))
+
+	rec := httptest.NewRecorder()
+	agw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+	agw.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var doc struct {
+		OpenAPI string
+		Info    map[string]string
+		Paths   map[string]map[string]struct {
+			OperationId string
+			Summary     string
+			Parameters  []struct{ Name, In string }
+		}
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, "users", doc.Info["title"])
+	assert.NotContains(t, doc.Paths, "/openapi.json")
+
+	get := doc.Paths["/users/{id}"]["get"]
+	assert.Equal(t, "userById", get.OperationId)
+	assert.Equal(t, "get a user", get.Summary)
+	require.Len(t, get.Parameters, 1)
+	assert.Equal(t, "id", get.Parameters[0].Name)
+	assert.Equal(t, "path", get.Parameters[0].In)
+
+	assert.Equal(t, "getUser", doc.Paths["/users/{id}"]["put"].OperationId)
+	assert.Contains(t, doc.Paths, "/files/{wildcard}")
+}
+
+func TestServeOpenAPIInvalidBase(t *testing.T) {
+	agw := &ApiGateway{Echo: echo.New()}
+	assert.Error(t, agw.ServeOpenAPI(OpenAPIConfig{Base: []byte("{")}))
+}
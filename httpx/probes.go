@@ -0,0 +1,196 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	defaultLivenessPath      = "/healthz"
+	defaultReadinessPath     = "/readyz"
+	defaultReadinessInterval = time.Second
+	defaultReadinessTimeout  = 5 * time.Second
+	defaultNotReadyStatus    = http.StatusServiceUnavailable
+	defaultNotReadyType      = echo.MIMETextPlainCharsetUTF8
+)
+
+// ReadinessCheck tells whether a dependency of the gateway, e.g. a database or
+// a downstream, is usable, a nil error meaning ready.
+type ReadinessCheck func(ctx context.Context) error
+
+// ProbeConfig configures the probe endpoints of ServeProbes.
+type ProbeConfig struct {
+	// LivenessPath answers 200 as long as the gateway serves, default /healthz.
+	LivenessPath string
+	// ReadinessPath runs the readiness checks and answers 200 if they all
+	// pass, NotReadyStatus otherwise, default /readyz.
+	ReadinessPath string
+
+	// GateStartup answers NotReadyStatus to every route but the probes until
+	// all the readiness checks passed once, so that no traffic comes in while
+	// the dependencies are still starting. Once ready, the gate stays open
+	// even if a check fails later, the readiness probe reports it.
+	GateStartup bool
+	// NotReadyStatus is the status of the gated requests and of a failed
+	// readiness probe, default 503.
+	NotReadyStatus int
+	// NotReadyBody is the body of the gated requests, of NotReadyContentType,
+	// default text/plain. Empty sends the JsonResponse of NotReadyStatus.
+	NotReadyBody        []byte
+	NotReadyContentType string
+
+	// CheckInterval is the pace of running the checks until they all pass
+	// while gated, default 1s. CheckTimeout bounds each check, default 5s.
+	CheckInterval time.Duration
+	CheckTimeout  time.Duration
+}
+
+type (
+	namedCheck struct {
+		name  string
+		check ReadinessCheck
+	}
+
+	probes struct {
+		config ProbeConfig
+		ready  atomic.Bool
+		once   sync.Once
+		// ctx ends the polling and its checks, cancelled by Stop
+		ctx    context.Context
+		cancel context.CancelFunc
+	}
+)
+
+// AddReadinessCheck registers a check run by the readiness probe of
+// ServeProbes, the name is reported with its result.
+func (agw *ApiGateway) AddReadinessCheck(name string, check ReadinessCheck) {
+	agw.readinessMu.Lock()
+	defer agw.readinessMu.Unlock()
+	agw.readinessChecks = append(agw.readinessChecks, namedCheck{name: name, check: check})
+}
+
+// ServeProbes registers the liveness and readiness endpoints, and with
+// GateStartup holds back the other routes until the gateway is ready. The
+// checks are polled from Run, RunTLS or Handler on, so they can be added
+// until then.
+func (agw *ApiGateway) ServeProbes(config ProbeConfig) {
+	if config.LivenessPath == "" {
+		config.LivenessPath = defaultLivenessPath
+	}
+	if config.ReadinessPath == "" {
+		config.ReadinessPath = defaultReadinessPath
+	}
+	if config.NotReadyStatus == 0 {
+		config.NotReadyStatus = defaultNotReadyStatus
+	}
+	if config.NotReadyContentType == "" {
+		config.NotReadyContentType = defaultNotReadyType
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = defaultReadinessInterval
+	}
+	if config.CheckTimeout <= 0 {
+		config.CheckTimeout = defaultReadinessTimeout
+	}
+	p := &probes{config: config}
+	p.ctx, p.cancel = context.WithCancel(agw.ctx)
+	agw.probes = p
+
+	agw.GET(config.LivenessPath, func(c echo.Context) error {
+		return SendResp(c, nil)
+	})
+	agw.GET(config.ReadinessPath, func(c echo.Context) error {
+		results, ok := agw.runReadinessChecks(c.Request().Context())
+		status := http.StatusOK
+		if ok {
+			p.ready.Store(true)
+		} else {
+			status = config.NotReadyStatus
+		}
+		return c.JSON(status, map[string]interface{}{"ready": ok, "checks": results})
+	})
+}
+
+// Ready reports whether the readiness checks passed once, true without
+// ServeProbes or GateStartup.
+func (agw *ApiGateway) Ready() bool {
+	p := agw.probes
+	return p == nil || !p.config.GateStartup || p.ready.Load()
+}
+
+// runReadinessChecks runs every check, it returns their results by name, "ok"
+// or the error, and whether they all passed
+func (agw *ApiGateway) runReadinessChecks(ctx context.Context) (map[string]string, bool) {
+	agw.readinessMu.Lock()
+	checks := append([]namedCheck(nil), agw.readinessChecks...)
+	agw.readinessMu.Unlock()
+
+	results := make(map[string]string, len(checks))
+	ok := true
+	for _, nc := range checks {
+		cctx, cancel := context.WithTimeout(ctx, agw.probes.config.CheckTimeout)
+		err := nc.check(cctx)
+		cancel()
+		if err != nil {
+			results[nc.name] = err.Error()
+			ok = false
+		} else {
+			results[nc.name] = "ok"
+		}
+	}
+	return results, ok
+}
+
+// startReadinessPolling runs the checks in the background until they all
+// pass, which opens the startup gate, or until stopReadinessPolling
+func (agw *ApiGateway) startReadinessPolling() {
+	p := agw.probes
+	if p == nil || !p.config.GateStartup {
+		return
+	}
+	p.once.Do(func() {
+		go func() {
+			ticker := time.NewTicker(p.config.CheckInterval)
+			defer ticker.Stop()
+			for !p.ready.Load() {
+				if _, ok := agw.runReadinessChecks(p.ctx); ok {
+					p.ready.Store(true)
+					agw.Logger.Info("readiness checks passed, serving requests")
+					return
+				}
+				select {
+				case <-p.ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	})
+}
+
+// stopReadinessPolling ends the polling of startReadinessPolling, and cancels
+// the check it runs
+func (agw *ApiGateway) stopReadinessPolling() {
+	if p := agw.probes; p != nil {
+		p.cancel()
+	}
+}
+
+func (agw *ApiGateway) readinessMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		p := agw.probes
+		if agw.Ready() || c.Path() == p.config.LivenessPath || c.Path() == p.config.ReadinessPath {
+			return next(c)
+		}
+
+		if len(p.config.NotReadyBody) == 0 {
+			return SendResp(c, StatusResp(p.config.NotReadyStatus))
+		}
+		return c.Blob(p.config.NotReadyStatus, p.config.NotReadyContentType, p.config.NotReadyBody)
+	}
+}
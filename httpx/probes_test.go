@@ -0,0 +1,109 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeProbesGateStartup(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+
+	var dbUp atomic.Bool
+	agw.AddReadinessCheck("db", func(ctx context.Context) error {
+		if !dbUp.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	agw.ServeProbes(ProbeConfig{
+		GateStartup:   true,
+		NotReadyBody:  []byte("starting"),
+		CheckInterval: 10 * time.Millisecond,
+	})
+	agw.GET("/api", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello")
+	})
+	h := agw.Handler()
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := serve("/api")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "starting", rec.Body.String())
+	assert.False(t, agw.Ready())
+
+	// probes still answer
+	assert.Equal(t, http.StatusOK, serve("/healthz").Code)
+	rec = serve("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"ready":false,"checks":{"db":"connection refused"}}`, rec.Body.String())
+
+	dbUp.Store(true)
+	assert.Eventually(t, agw.Ready, time.Second, 5*time.Millisecond)
+	rec = serve("/api")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "hello", rec.Body.String())
+
+	// the gate stays open, the probe reports the failure
+	dbUp.Store(false)
+	assert.Equal(t, http.StatusOK, serve("/api").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/readyz").Code)
+}
+
+func TestServeProbesStop(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+
+	var runs atomic.Int32
+	agw.AddReadinessCheck("db", func(ctx context.Context) error {
+		runs.Add(1)
+		return errors.New("connection refused")
+	})
+	agw.ServeProbes(ProbeConfig{GateStartup: true, CheckInterval: 5 * time.Millisecond})
+	agw.Handler()
+	require.Eventually(t, func() bool { return runs.Load() > 1 }, time.Second, 5*time.Millisecond)
+
+	// the polling ends with the gateway
+	require.NoError(t, agw.Stop())
+	time.Sleep(20 * time.Millisecond)
+	n := runs.Load()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, n, runs.Load())
+	assert.False(t, agw.Ready())
+}
+
+func TestServeProbesWithoutGate(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+
+	agw.AddReadinessCheck("db", func(ctx context.Context) error { return errors.New("down") })
+	agw.ServeProbes(ProbeConfig{NotReadyStatus: http.StatusTooEarly})
+	agw.GET("/api", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	h := agw.Handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusTooEarly, rec.Code)
+	assert.True(t, agw.Ready())
+}
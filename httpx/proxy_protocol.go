@@ -0,0 +1,207 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/madlabx/pkgx/errors"
+)
+
+const (
+	defaultProxyHeaderTimeout = 5 * time.Second
+	// the longest v1 header, "PROXY TCP6 <39> <39> 65535 65535\r\n"
+	proxyV1MaxLen = 107
+)
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyProtoListener reads the PROXY protocol header, v1 or v2, the load
+// balancer sends first on each connection, so that RemoteAddr is the address
+// of the client, see ServerConfig.ProxyProtocol.
+type proxyProtoListener struct {
+	net.Listener
+	timeout time.Duration
+}
+
+func newProxyProtoListener(l net.Listener, timeout time.Duration) net.Listener {
+	if timeout <= 0 {
+		timeout = defaultProxyHeaderTimeout
+	}
+	return &proxyProtoListener{Listener: l, timeout: timeout}
+}
+
+// Accept does not read the header, not to hold back the accept loop on a slow
+// client, the header is read by the first Read or RemoteAddr of the conn.
+func (l *proxyProtoListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyConn{Conn: conn, br: bufio.NewReader(conn), timeout: l.timeout}, nil
+}
+
+type proxyConn struct {
+	net.Conn
+	br      *bufio.Reader
+	timeout time.Duration
+
+	once       sync.Once
+	err        error
+	remoteAddr net.Addr
+	localAddr  net.Addr
+}
+
+// readHeader parses the header, a malformed or missing one closes the conn,
+// rather than serving the request with the address of the load balancer
+func (c *proxyConn) readHeader() {
+	c.once.Do(func() {
+		_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		c.remoteAddr, c.localAddr, c.err = readProxyHeader(c.br)
+		if c.err != nil {
+			_ = c.Conn.Close()
+			return
+		}
+		_ = c.Conn.SetReadDeadline(time.Time{})
+	})
+}
+
+func (c *proxyConn) Read(p []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.br.Read(p)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.readHeader()
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyConn) LocalAddr() net.Addr {
+	c.readHeader()
+	if c.localAddr != nil {
+		return c.localAddr
+	}
+	return c.Conn.LocalAddr()
+}
+
+// readProxyHeader reads a v1 or v2 header from br, it returns nil addresses
+// for a header not carrying any, e.g. the health checks of the load balancer
+func readProxyHeader(br *bufio.Reader) (src, dst net.Addr, err error) {
+	first, err := br.Peek(1)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read PROXY protocol header")
+	}
+	switch first[0] {
+	case 'P':
+		return readProxyHeaderV1(br)
+	case proxyV2Signature[0]:
+		return readProxyHeaderV2(br)
+	}
+	return nil, nil, errors.Errorf("missing PROXY protocol header")
+}
+
+func readProxyHeaderV1(br *bufio.Reader) (net.Addr, net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= proxyV1MaxLen {
+			return nil, nil, errors.Errorf("PROXY protocol v1 header too long")
+		}
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to read PROXY protocol v1 header")
+		}
+		line = append(line, b)
+	}
+
+	fields := strings.Split(strings.TrimSuffix(string(line), "\r\n"), " ")
+	if len(fields) < 2 || fields[0] != "PROXY" {
+		return nil, nil, errors.Errorf("invalid PROXY protocol v1 header %q", line)
+	}
+	if fields[1] == "UNKNOWN" {
+		return nil, nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, nil, errors.Errorf("invalid PROXY protocol v1 header %q", line)
+	}
+
+	src, err := parseProxyAddrV1(fields[1], fields[2], fields[4])
+	if err != nil {
+		return nil, nil, err
+	}
+	dst, err := parseProxyAddrV1(fields[1], fields[3], fields[5])
+	if err != nil {
+		return nil, nil, err
+	}
+	return src, dst, nil
+}
+
+func parseProxyAddrV1(proto, ip, port string) (net.Addr, error) {
+	addr := &net.TCPAddr{IP: net.ParseIP(ip)}
+	if addr.IP == nil || (addr.IP.To4() != nil) != (proto == "TCP4") {
+		return nil, errors.Errorf("invalid PROXY protocol v1 address %q", ip)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, errors.Errorf("invalid PROXY protocol v1 port %q", port)
+	}
+	addr.Port = int(p)
+	return addr, nil
+}
+
+func readProxyHeaderV2(br *bufio.Reader) (net.Addr, net.Addr, error) {
+	hdr := make([]byte, 16)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read PROXY protocol v2 header")
+	}
+	if !bytes.Equal(hdr[:12], proxyV2Signature) {
+		return nil, nil, errors.Errorf("invalid PROXY protocol v2 signature")
+	}
+	if hdr[12]>>4 != 2 {
+		return nil, nil, errors.Errorf("invalid PROXY protocol version %d", hdr[12]>>4)
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(hdr[14:16]))
+	if _, err := io.ReadFull(br, payload); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read PROXY protocol v2 addresses")
+	}
+
+	switch cmd := hdr[12] & 0x0f; cmd {
+	case 0x0:
+		// LOCAL, e.g. a health check of the load balancer itself
+		return nil, nil, nil
+	case 0x1:
+		// PROXY
+	default:
+		return nil, nil, errors.Errorf("invalid PROXY protocol v2 command %d", cmd)
+	}
+
+	var ipLen int
+	switch hdr[13] >> 4 {
+	case 0x1:
+		ipLen = net.IPv4len
+	case 0x2:
+		ipLen = net.IPv6len
+	default:
+		// AF_UNSPEC or AF_UNIX, no address to take
+		return nil, nil, nil
+	}
+	if len(payload) < 2*ipLen+4 {
+		return nil, nil, errors.Errorf("PROXY protocol v2 addresses too short")
+	}
+	ports := payload[2*ipLen:]
+	src := &net.TCPAddr{IP: net.IP(payload[:ipLen]), Port: int(binary.BigEndian.Uint16(ports[0:2]))}
+	dst := &net.TCPAddr{IP: net.IP(payload[ipLen : 2*ipLen]), Port: int(binary.BigEndian.Uint16(ports[2:4]))}
+	return src, dst, nil
+}
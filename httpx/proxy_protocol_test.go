@@ -0,0 +1,97 @@
+package httpx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func proxyHeaderV2(cmd byte, src, dst *net.TCPAddr) []byte {
+	b := append([]byte(nil), proxyV2Signature...)
+	b = append(b, 0x20|cmd, 0x11, 0, 12)
+	b = append(b, src.IP.To4()...)
+	b = append(b, dst.IP.To4()...)
+	b = binary.BigEndian.AppendUint16(b, uint16(src.Port))
+	return binary.BigEndian.AppendUint16(b, uint16(dst.Port))
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	src := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 7), Port: 51234}
+	dst := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}
+
+	for name, header := range map[string]string{
+		"v1": "PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\n",
+		"v2": string(proxyHeaderV2(0x1, src, dst)),
+	} {
+		br := bufio.NewReader(strings.NewReader(header + "GET /"))
+		gotSrc, gotDst, err := readProxyHeader(br)
+		require.NoError(t, err, name)
+		assert.Equal(t, src.String(), gotSrc.String(), name)
+		assert.Equal(t, dst.String(), gotDst.String(), name)
+		rest, _ := io.ReadAll(br)
+		assert.Equal(t, "GET /", string(rest), name)
+	}
+
+	gotSrc, _, err := readProxyHeader(bufio.NewReader(strings.NewReader("PROXY TCP6 2001:db8::1 2001:db8::2 1 2\r\n")))
+	require.NoError(t, err)
+	assert.Equal(t, "[2001:db8::1]:1", gotSrc.String())
+
+	for _, header := range []string{"PROXY UNKNOWN\r\n", string(proxyHeaderV2(0x0, src, dst))} {
+		gotSrc, gotDst, err := readProxyHeader(bufio.NewReader(strings.NewReader(header)))
+		require.NoError(t, err)
+		assert.Nil(t, gotSrc)
+		assert.Nil(t, gotDst)
+	}
+
+	for _, header := range []string{
+		"GET / HTTP/1.1\r\n",
+		"PROXY TCP4 203.0.113.7 10.0.0.1 51234\r\n",
+		"PROXY TCP4 2001:db8::1 10.0.0.1 51234 443\r\n",
+		"PROXY TCP4 203.0.113.7 10.0.0.1 51234 99999\r\n",
+		"PROXY " + strings.Repeat("x", 200) + "\r\n",
+		string(proxyHeaderV2(0x1, src, dst)[:20]),
+	} {
+		_, _, err := readProxyHeader(bufio.NewReader(strings.NewReader(header)))
+		assert.Error(t, err, header)
+	}
+}
+
+func TestProxyProtoListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.RemoteAddr)
+	})}
+	go func() { _ = srv.Serve(newProxyProtoListener(l, time.Second)) }()
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = io.WriteString(conn, "PROXY TCP4 203.0.113.7 10.0.0.1 51234 80\r\nGET / HTTP/1.1\r\nHost: x\r\n\r\n")
+	require.NoError(t, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, "203.0.113.7:51234", string(body))
+
+	// no header, the connection is dropped
+	conn2, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn2.Close()
+	_, err = io.WriteString(conn2, "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+	require.NoError(t, err)
+	_ = conn2.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn2.Read(make([]byte, 1))
+	assert.Equal(t, 0, n)
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package httpx
+
+import (
+	"hash/fnv"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+	"github.com/madlabx/pkgx/log"
+)
+
+const (
+	defaultPanicStackFrames = 8
+	defaultPanicDedupWindow = time.Minute
+)
+
+type (
+	// RecoverConfig defines the config for the recover middleware.
+	RecoverConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper middleware.Skipper
+
+		// StackFrames is the number of top stack frames, runtime ones left
+		// out, which make up the panic signature. Optional. Default 8.
+		StackFrames int
+
+		// DedupWindow is the period in which panics of the same signature are
+		// reported once. Optional. Default 1 minute.
+		DedupWindow time.Duration
+
+		// OnPanic reports a recovered panic. It is called on the first panic of
+		// a signature with count 1, panics of the same signature within the
+		// DedupWindow are only counted and reported once at its end, with rec of
+		// the last one. Optional. Default logs to log.StandardLogger.
+		OnPanic func(sig string, count int, rec interface{})
+	}
+
+	panicDedup struct {
+		window  time.Duration
+		onPanic func(sig string, count int, rec interface{})
+
+		mu      sync.Mutex
+		pending map[string]*pendingPanic
+	}
+
+	pendingPanic struct {
+		count int
+		rec   interface{}
+	}
+)
+
+// RecoverWithConfig returns a middleware which recovers from panics in the
+// chain, answers them with a plain 500 and reports them through OnPanic,
+// deduplicated by stack signature.
+func RecoverWithConfig(config RecoverConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.StackFrames <= 0 {
+		config.StackFrames = defaultPanicStackFrames
+	}
+	if config.DedupWindow <= 0 {
+		config.DedupWindow = defaultPanicDedupWindow
+	}
+	if config.OnPanic == nil {
+		config.OnPanic = func(sig string, count int, rec interface{}) {
+			log.Errorf("recovered panic, sig=%s count=%d: %v", sig, count, rec)
+		}
+	}
+
+	dedup := &panicDedup{
+		window:  config.DedupWindow,
+		onPanic: config.OnPanic,
+		pending: make(map[string]*pendingPanic),
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				// the handler asked to abort the connection, let net/http do it
+				if r == http.ErrAbortHandler {
+					panic(r)
+				}
+
+				dedup.report(panicSignature(config.StackFrames), r)
+				err = SendResp(c, StatusResp(http.StatusInternalServerError))
+			}()
+
+			return next(c)
+		}
+	}
+}
+
+func (pd *panicDedup) report(sig string, rec interface{}) {
+	pd.mu.Lock()
+	if p, ok := pd.pending[sig]; ok {
+		p.count++
+		p.rec = rec
+		pd.mu.Unlock()
+		return
+	}
+	pd.pending[sig] = &pendingPanic{}
+	pd.mu.Unlock()
+
+	pd.onPanic(sig, 1, rec)
+
+	time.AfterFunc(pd.window, func() {
+		pd.mu.Lock()
+		p := pd.pending[sig]
+		delete(pd.pending, sig)
+		pd.mu.Unlock()
+
+		if p.count > 0 {
+			pd.onPanic(sig, p.count, p.rec)
+		}
+	})
+}
+
+// panicSignature hashes the function and line of the top frames of the
+// panicking goroutine, to be called from the deferred recover.
+func panicSignature(frames int) string {
+	pcs := make([]uintptr, frames+16)
+	n := runtime.Callers(3, pcs)
+	it := runtime.CallersFrames(pcs[:n])
+
+	var b strings.Builder
+	for i := 0; i < frames; {
+		f, more := it.Next()
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			b.WriteString(f.Function)
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(f.Line))
+			b.WriteByte('\n')
+			i++
+		}
+		if !more {
+			break
+		}
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(b.String()))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,59 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverDedup(t *testing.T) {
+	type report struct {
+		sig   string
+		count int
+		rec   interface{}
+	}
+	var (
+		mu      sync.Mutex
+		reports []report
+	)
+
+	e := echo.New()
+	e.Use(RecoverWithConfig(RecoverConfig{
+		DedupWindow: 50 * time.Millisecond,
+		OnPanic: func(sig string, count int, rec interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			reports = append(reports, report{sig, count, rec})
+		},
+	}))
+	e.GET("/a", func(echo.Context) error { panic("a") })
+	e.GET("/b", func(echo.Context) error { panic("b") })
+
+	for _, path := range []string{"/a", "/a", "/a", "/b"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	}
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(reports) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, reports[0].count)
+	assert.Equal(t, "a", reports[0].rec)
+	assert.Equal(t, 1, reports[1].count)
+	assert.Equal(t, "b", reports[1].rec)
+	assert.NotEqual(t, reports[0].sig, reports[1].sig)
+	// the window summary of the 2 suppressed /a panics
+	assert.Equal(t, report{reports[0].sig, 2, "a"}, reports[2])
+}
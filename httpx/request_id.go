@@ -0,0 +1,91 @@
+package httpx
+
+import (
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+const (
+	// ContextKeyRequestId is the echo.Context key holding the request id.
+	ContextKeyRequestId = "httpx.request_id"
+
+	headerTraceparent = "Traceparent"
+)
+
+type (
+	// RequestIdConfig defines the config for RequestId middleware.
+	RequestIdConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper middleware.Skipper
+
+		// Headers are the trusted request headers carrying an upstream id,
+		// checked in order, the first present one wins. For "traceparent"
+		// the trace-id part is used.
+		// Optional. Default value []string{"X-Request-ID"}.
+		Headers []string
+
+		// Generator makes a new id when none of Headers is present.
+		// Optional. Default value is the NewRequestId of the error code dictionary.
+		Generator func() string
+	}
+)
+
+// RequestIdWithConfig returns a middleware which picks the request id from
+// the configured headers or generates one, then sets it on the response
+// X-Request-ID header and the context.
+func RequestIdWithConfig(config RequestIdConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if len(config.Headers) == 0 {
+		config.Headers = []string{echo.HeaderXRequestID}
+	}
+	if config.Generator == nil {
+		config.Generator = func() string { return errCodeDic.NewRequestId() }
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			id := pickRequestId(c.Request().Header.Get, config.Headers)
+			if id == "" {
+				id = config.Generator()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+			c.Set(ContextKeyRequestId, id)
+			return next(c)
+		}
+	}
+}
+
+func pickRequestId(get func(string) string, headers []string) string {
+	for _, h := range headers {
+		v := get(h)
+		if v == "" {
+			continue
+		}
+		if strings.EqualFold(h, headerTraceparent) {
+			// version-traceid-parentid-flags
+			if parts := strings.Split(v, "-"); len(parts) == 4 {
+				return parts[1]
+			}
+			continue
+		}
+		return v
+	}
+	return ""
+}
+
+// GetRequestId returns the id set by RequestIdWithConfig, falling back to the
+// X-Request-ID request or response header.
+func GetRequestId(c echo.Context) string {
+	if id, ok := c.Get(ContextKeyRequestId).(string); ok && id != "" {
+		return id
+	}
+	return requestId(c)
+}
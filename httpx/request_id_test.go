@@ -0,0 +1,38 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIdHeaderOrder(t *testing.T) {
+	e := echo.New()
+	e.Use(RequestIdWithConfig(RequestIdConfig{
+		Headers:   []string{"X-Correlation-ID", "traceparent", echo.HeaderXRequestID},
+		Generator: func() string { return "generated" },
+	}))
+	e.GET("/", func(c echo.Context) error {
+		return SendResp(c, nil)
+	})
+
+	cases := []struct {
+		header http.Header
+		expect string
+	}{
+		{http.Header{"X-Correlation-Id": {"corr"}, "X-Request-Id": {"rid"}}, "corr"},
+		{http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, "X-Request-Id": {"rid"}}, "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{http.Header{"X-Request-Id": {"rid"}}, "rid"},
+		{http.Header{}, "generated"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header = tc.header
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, tc.expect, rec.Header().Get(echo.HeaderXRequestID))
+	}
+}
@@ -0,0 +1,55 @@
+package httpx
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/errcodex"
+	"github.com/madlabx/pkgx/errors"
+)
+
+// ContextKeyValidated is the echo.Context key holding the result stashed by a
+// RequestValidator through SetValidated.
+const ContextKeyValidated = "httpx.validated"
+
+// RequestValidator checks a request before it reaches the handler, a returned
+// error is sent as the response. Plain errors are answered with 400, those
+// carrying a status (JsonResponse, echo.HTTPError, errcodex codes) keep it.
+type RequestValidator func(c echo.Context) error
+
+// SetValidated stashes v, e.g. the parsed body, for the handler to reuse.
+func SetValidated(c echo.Context, v any) {
+	c.Set(ContextKeyValidated, v)
+}
+
+// GetValidated returns what the RequestValidator stashed with SetValidated,
+// nil if nothing.
+func GetValidated(c echo.Context) any {
+	return c.Get(ContextKeyValidated)
+}
+
+// validatorMiddleware runs agw.RequestValidator, installed after the access
+// logger so that the body dump and the rejection are logged.
+func (agw *ApiGateway) validatorMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if agw.RequestValidator == nil {
+			return next(c)
+		}
+		if err := agw.RequestValidator(c); err != nil {
+			return SendResp(c, validationError(err))
+		}
+		return next(c)
+	}
+}
+
+func validationError(err error) error {
+	var (
+		jr *JsonResponse
+		eh *echo.HTTPError
+		ec errcodex.ErrorCodeIf
+	)
+	if errors.As(err, &jr) || errors.As(err, &eh) || errors.As(err, &ec) {
+		return err
+	}
+	return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+}
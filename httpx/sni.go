@@ -0,0 +1,136 @@
+package httpx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"sync"
+
+	"github.com/madlabx/pkgx/errors"
+)
+
+// sniCertificates are the certificates added by AddCertificate, by lowercase
+// host name, "*.example.com" for a wildcard
+type sniCertificates struct {
+	mu    sync.RWMutex
+	certs map[string]*tls.Certificate
+}
+
+// AddCertificate serves cert to the TLS clients asking for host by SNI, the
+// server name of their handshake, for one gateway to serve several domains.
+// host is a name, e.g. "api.example.com", or a wildcard of one label, e.g.
+// "*.example.com", an exact name is preferred over a wildcard. The
+// certificate of RunTLS/RunMTLS is served to the other clients, or they are
+// rejected with LogConfig.SNIStrict, but for those sending no server name,
+// e.g. by IP, which crypto/tls always serves it. Each handshake with a server
+// name logs it and the certificate chosen at debug, a rejected one at warn.
+// Add the certificates before starting the server for TLSStats.CertNotAfter
+// to cover them.
+//
+// The certificate is chosen before HTTP/2 is negotiated by ALPN, which stays
+// the same for every host, h2 unless Echo.DisableHTTP2, then http/1.1. An
+// HTTP/2 client may reuse a connection for another host the certificate
+// served covers, the requests on a connection do not all have the server name
+// of its handshake then: route by the Host of the request, not by
+// Request().TLS.ServerName.
+func (agw *ApiGateway) AddCertificate(host string, cert tls.Certificate) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+		return errors.Errorf("invalid certificate host:%q", host)
+	}
+	if len(cert.Certificate) == 0 {
+		return errors.Errorf("invalid certificate of host:%s, no certificate", host)
+	}
+	if cert.Leaf == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse certificate of host:%s", host)
+		}
+		cert.Leaf = leaf
+	}
+
+	agw.sniCerts.mu.Lock()
+	defer agw.sniCerts.mu.Unlock()
+	if agw.sniCerts.certs == nil {
+		agw.sniCerts.certs = make(map[string]*tls.Certificate)
+	}
+	agw.sniCerts.certs[host] = &cert
+	return nil
+}
+
+// AddCertificateFile loads the certificate and key of host, see
+// AddCertificate.
+func (agw *ApiGateway) AddCertificateFile(host, certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load key pair of host:%s, cert:%s, key:%s", host, certFile, keyFile)
+	}
+	return agw.AddCertificate(host, cert)
+}
+
+// applySNI chooses the certificate of the handshakes of tc by their server
+// name, no-op without any certificate added
+func (agw *ApiGateway) applySNI(tc *tls.Config) {
+	if agw.sniCerts.len() == 0 {
+		return
+	}
+	strict := agw.LogConf.SNIStrict
+	tc.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+		var remoteAddr string
+		if hello.Conn != nil {
+			remoteAddr = hello.Conn.RemoteAddr().String()
+		}
+
+		cert, host := agw.sniCerts.lookup(name)
+		switch {
+		case cert != nil:
+			agw.Logger.DebugKV("tls sni", "sni", name, "cert", host, "remote_addr", remoteAddr)
+			return cert, nil
+		case strict:
+			agw.Logger.WarnKV("tls sni rejected", "sni", name, "remote_addr", remoteAddr)
+			return nil, errors.Errorf("no certificate for server name:%q", name)
+		default:
+			// nil falls back to tc.Certificates
+			agw.Logger.DebugKV("tls sni", "sni", name, "cert", "default", "remote_addr", remoteAddr)
+			return nil, nil
+		}
+	}
+}
+
+// lookup returns the certificate of name and the host it was added for, the
+// exact name first, then its wildcard
+func (sc *sniCertificates) lookup(name string) (*tls.Certificate, string) {
+	if name == "" {
+		return nil, ""
+	}
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	if cert, ok := sc.certs[name]; ok {
+		return cert, name
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		wildcard := "*" + name[i:]
+		if cert, ok := sc.certs[wildcard]; ok {
+			return cert, wildcard
+		}
+	}
+	return nil, ""
+}
+
+func (sc *sniCertificates) len() int {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return len(sc.certs)
+}
+
+// all returns the certificates added
+func (sc *sniCertificates) all() []tls.Certificate {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	certs := make([]tls.Certificate, 0, len(sc.certs))
+	for _, cert := range sc.certs {
+		certs = append(certs, *cert)
+	}
+	return certs
+}
@@ -0,0 +1,73 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSNICertificates(t *testing.T) {
+	newServer := func(strict bool) *httptest.Server {
+		agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}, SNIStrict: strict}, nil)
+		require.NoError(t, err)
+		agw.GET("/", func(c echo.Context) error { return c.String(http.StatusOK, c.Request().TLS.ServerName) })
+
+		notAfter := time.Now().Add(time.Hour)
+		def, _ := newTestCert(t, notAfter)
+		a, _ := newTestCert(t, notAfter, "a.test")
+		b, _ := newTestCert(t, notAfter, "*.b.test")
+		require.NoError(t, agw.AddCertificate("A.test.", a))
+		require.NoError(t, agw.AddCertificate("*.b.test", b))
+		require.Error(t, agw.AddCertificate("", a))
+		require.Error(t, agw.AddCertificate("a.*.test", a))
+		require.Error(t, agw.AddCertificate("c.test", tls.Certificate{}))
+
+		srv := httptest.NewUnstartedServer(agw.Handler())
+		srv.TLS = &tls.Config{Certificates: []tls.Certificate{def}}
+		agw.applySNI(srv.TLS)
+		srv.StartTLS()
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	served := func(srv *httptest.Server, name string) (string, error) {
+		conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{ServerName: name, InsecureSkipVerify: true})
+		if err != nil {
+			return "", err
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+	}
+
+	srv := newServer(false)
+	for name, want := range map[string]string{
+		"a.test":     "a.test",
+		"x.b.test":   "*.b.test",
+		"b.test":     "127.0.0.1",
+		"x.y.b.test": "127.0.0.1",
+		"":           "127.0.0.1",
+	} {
+		cn, err := served(srv, name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, cn, name)
+	}
+
+	srv = newServer(true)
+	cn, err := served(srv, "x.b.test")
+	require.NoError(t, err)
+	assert.Equal(t, "*.b.test", cn)
+	_, err = served(srv, "c.test")
+	assert.Error(t, err)
+	// crypto/tls serves the default to the clients sending no server name
+	cn, err = served(srv, "")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", cn)
+}
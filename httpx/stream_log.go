@@ -0,0 +1,142 @@
+package httpx
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+)
+
+// isStreaming tells the requests of long-lived streams: WebSocket upgrades
+// and SSE, whose EventSource always asks for text/event-stream
+func isStreaming(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket") ||
+		strings.Contains(req.Header.Get("Accept"), "text/event-stream")
+}
+
+// streamLogMiddleware logs a summary of the frames sent on a streaming
+// connection every LogConfig.StreamLogFrames frames or StreamLogInterval,
+// whichever comes first, see LogConfig.StreamLogInterval.
+func (agw *ApiGateway) streamLogMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	frames, interval := agw.LogConf.StreamLogFrames, agw.LogConf.StreamLogInterval
+	if frames <= 0 && interval <= 0 {
+		return next
+	}
+	return func(c echo.Context) error {
+		if !isStreaming(c.Request()) {
+			return next(c)
+		}
+		res := c.Response()
+		sc := &streamCounter{
+			c:         c,
+			logger:    agw.Logger.Logger,
+			maxFrames: frames,
+			interval:  interval,
+			start:     time.Now(),
+		}
+		sc.last = sc.start
+		sw := &streamWriter{ResponseWriter: res.Writer, counter: sc}
+		res.Writer = sw
+		defer func() {
+			res.Writer = sw.ResponseWriter
+			sc.done()
+		}()
+		return next(c)
+	}
+}
+
+// streamCounter counts the frames written on a stream, a Write being a frame,
+// a SSE event or a WebSocket frame as written by the usual libraries
+type streamCounter struct {
+	c         echo.Context
+	logger    *logrus.Logger
+	maxFrames int
+	interval  time.Duration
+	start     time.Time
+
+	mu                      sync.Mutex
+	last                    time.Time
+	frames, bytes           int64
+	totalFrames, totalBytes int64
+}
+
+func (sc *streamCounter) add(n int) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.frames++
+	sc.bytes += int64(n)
+	sc.totalFrames++
+	sc.totalBytes += int64(n)
+	if (sc.maxFrames > 0 && sc.frames >= int64(sc.maxFrames)) ||
+		(sc.interval > 0 && time.Since(sc.last) >= sc.interval) {
+		sc.logLocked(false)
+	}
+}
+
+// done logs the last summary once the stream is over
+func (sc *streamCounter) done() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.logLocked(true)
+}
+
+func (sc *streamCounter) logLocked(final bool) {
+	now := time.Now()
+	req := sc.c.Request()
+	msg := "stream"
+	if final {
+		msg = "stream closed"
+	}
+	sc.logger.WithFields(logrus.Fields{
+		"id":           GetRequestId(sc.c),
+		"method":       req.Method,
+		"url":          req.RequestURI,
+		"frames":       sc.frames,
+		"bytes":        sc.bytes,
+		"total_frames": sc.totalFrames,
+		"total_bytes":  sc.totalBytes,
+		"duration":     now.Sub(sc.start).String(),
+	}).Info(msg)
+	sc.frames, sc.bytes, sc.last = 0, 0, now
+}
+
+type streamWriter struct {
+	http.ResponseWriter
+	counter *streamCounter
+}
+
+func (w *streamWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.counter.add(n)
+	return n, err
+}
+
+func (w *streamWriter) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+// Hijack counts the frames written on the connection of a WebSocket
+func (w *streamWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err != nil {
+		return conn, brw, err
+	}
+	sc := &streamConn{Conn: conn, counter: w.counter}
+	return sc, bufio.NewReadWriter(brw.Reader, bufio.NewWriter(sc)), nil
+}
+
+type streamConn struct {
+	net.Conn
+	counter *streamCounter
+}
+
+func (c *streamConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.counter.add(n)
+	return n, err
+}
@@ -0,0 +1,54 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamLog(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{
+		LogFile:         log.FileConfig{Filename: "discard"},
+		StreamLogFrames: 2,
+	}, nil)
+	require.NoError(t, err)
+	hook := test.NewLocal(agw.Logger.Logger)
+
+	agw.GET("/events", func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+		for i := 0; i < 5; i++ {
+			_, _ = fmt.Fprintf(c.Response(), "data: %d\n\n", i)
+			c.Response().Flush()
+		}
+		return nil
+	})
+	h := agw.Handler()
+
+	// not a stream
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+	assert.Empty(t, hook.AllEntries())
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.True(t, rec.Flushed)
+
+	entries := hook.AllEntries()
+	require.Len(t, entries, 3)
+	for i, frames := range []int64{2, 2, 1} {
+		assert.Equal(t, frames, entries[i].Data["frames"])
+		assert.Equal(t, frames*9, entries[i].Data["bytes"])
+	}
+	assert.Equal(t, "stream closed", entries[2].Message)
+	assert.Equal(t, int64(5), entries[2].Data["total_frames"])
+	assert.NotEmpty(t, entries[2].Data["id"])
+}
@@ -0,0 +1,139 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/errors"
+)
+
+// HeaderXRequestDeadline carries the time left to serve a request, in
+// milliseconds, from one hop to the next.
+const HeaderXRequestDeadline = "X-Request-Deadline"
+
+// requestTimeout is the deadline setting of ServerConfig, applied by
+// timeoutMiddleware.
+type requestTimeout struct {
+	timeout   time.Duration
+	propagate bool
+}
+
+func newRequestTimeout(sc *ServerConfig) *requestTimeout {
+	if sc == nil || (sc.RequestTimeout <= 0 && !sc.PropagateDeadline) {
+		return nil
+	}
+	return &requestTimeout{timeout: sc.RequestTimeout, propagate: sc.PropagateDeadline}
+}
+
+// routeTimeouts is a registry of the request timeouts keyed by method and
+// route path, see SetRouteTimeout
+type routeTimeouts struct {
+	mu       sync.RWMutex
+	timeouts map[string]time.Duration
+}
+
+func (rt *routeTimeouts) set(method, path string, timeout time.Duration) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if timeout <= 0 {
+		delete(rt.timeouts, routeKey(method, path))
+		return
+	}
+	if rt.timeouts == nil {
+		rt.timeouts = make(map[string]time.Duration)
+	}
+	rt.timeouts[routeKey(method, path)] = timeout
+}
+
+// lookup matches on the registered route path, e.g. "/users/:id", not the raw URL.
+func (rt *routeTimeouts) lookup(c echo.Context) (time.Duration, bool) {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	timeout, ok := rt.timeouts[routeKey(c.Request().Method, c.Path())]
+	return timeout, ok
+}
+
+// SetRouteTimeout overrides ServerConfig.RequestTimeout for the route
+// registered with method and path, e.g. ("GET", "/report"), longer or shorter
+// than the global one, which applies to the other routes. A timeout of 0
+// removes the override. With PropagateDeadline, the deadline of the upstream
+// hop still wins when it is nearer, as the caller does not wait longer. It is
+// safe to call while serving.
+func (agw *ApiGateway) SetRouteTimeout(method, path string, timeout time.Duration) {
+	agw.routeTimeouts.set(method, path, timeout)
+}
+
+// timeoutMiddleware bounds the request context by ServerConf.RequestTimeout,
+// or the timeout of the route, see SetRouteTimeout, and, with
+// PropagateDeadline, by the deadline of the upstream hop. A handler failing
+// with the deadline exceeded before writing is answered with 503.
+func (agw *ApiGateway) timeoutMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		rt := agw.requestTimeout
+		routeTimeout, routed := agw.routeTimeouts.lookup(c)
+		if rt == nil && !routed {
+			return next(c)
+		}
+
+		var timeout time.Duration
+		if rt != nil {
+			timeout = rt.timeout
+		}
+		if routed {
+			timeout = routeTimeout
+		}
+		if rt != nil && rt.propagate {
+			if left, ok := parseDeadlineHeader(c.Request().Header.Get(HeaderXRequestDeadline)); ok &&
+				(timeout <= 0 || left < timeout) {
+				timeout = left
+			}
+		}
+		if timeout <= 0 {
+			return next(c)
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+		defer cancel()
+		c.SetRequest(c.Request().WithContext(ctx))
+
+		err := next(c)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Response().Committed &&
+			(err == nil || errors.Is(err, context.DeadlineExceeded)) {
+			return SendResp(c, StatusResp(http.StatusServiceUnavailable))
+		}
+		return err
+	}
+}
+
+func parseDeadlineHeader(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms < 0 {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// RequestDeadline returns the deadline of the request, set by the request
+// timeout of ServerConf or the client, false if there is none.
+func RequestDeadline(c echo.Context) (time.Time, bool) {
+	return c.Request().Context().Deadline()
+}
+
+// ForwardDeadline sets the X-Request-Deadline header of an outgoing request h
+// to the time left before the deadline of ctx, if any, so that the next hop
+// can bound its own work. Pass the request context of the handler.
+func ForwardDeadline(ctx context.Context, h http.Header) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	left := time.Until(deadline).Milliseconds()
+	h.Set(HeaderXRequestDeadline, strconv.FormatInt(max(left, 0), 10))
+}
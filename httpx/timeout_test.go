@@ -0,0 +1,93 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestTimeoutPropagation(t *testing.T) {
+	agw := &ApiGateway{
+		Echo:       echo.New(),
+		ServerConf: &ServerConfig{RequestTimeout: time.Second, PropagateDeadline: true},
+	}
+	agw.Use(agw.timeoutMiddleware)
+
+	var forwarded string
+	agw.GET("/wait", func(c echo.Context) error {
+		_, ok := RequestDeadline(c)
+		require.True(t, ok)
+
+		out := http.Header{}
+		ForwardDeadline(c.Request().Context(), out)
+		forwarded = out.Get(HeaderXRequestDeadline)
+
+		<-c.Request().Context().Done()
+		return c.Request().Context().Err()
+	})
+	h := agw.Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/wait", nil)
+	req.Header.Set(HeaderXRequestDeadline, "50")
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	left, err := strconv.Atoi(forwarded)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, left, 50)
+}
+
+func TestRouteTimeout(t *testing.T) {
+	agw := &ApiGateway{
+		Echo:       echo.New(),
+		ServerConf: &ServerConfig{RequestTimeout: 50 * time.Millisecond, PropagateDeadline: true},
+	}
+	agw.Use(agw.timeoutMiddleware)
+	left := func(c echo.Context) error {
+		deadline, ok := RequestDeadline(c)
+		require.True(t, ok)
+		return c.String(http.StatusOK, strconv.FormatInt(time.Until(deadline).Milliseconds(), 10))
+	}
+	agw.GET("/report/:id", left)
+	agw.GET("/users", left)
+	agw.SetRouteTimeout(http.MethodGet, "/report/:id", time.Minute)
+	h := agw.Handler()
+
+	get := func(path, deadline string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if deadline != "" {
+			req.Header.Set(HeaderXRequestDeadline, deadline)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		ms, err := strconv.Atoi(rec.Body.String())
+		require.NoError(t, err)
+		return ms
+	}
+
+	assert.Greater(t, get("/report/1", ""), 50_000)
+	assert.LessOrEqual(t, get("/users", ""), 50)
+	// the nearer deadline of the upstream hop wins
+	assert.LessOrEqual(t, get("/report/1", "2000"), 2000)
+
+	agw.SetRouteTimeout(http.MethodGet, "/report/:id", 0)
+	assert.LessOrEqual(t, get("/report/1", ""), 50)
+
+	// no global timeout, the route one applies alone
+	plain := &ApiGateway{Echo: echo.New()}
+	plain.Use(plain.timeoutMiddleware)
+	plain.GET("/report", left)
+	plain.SetRouteTimeout(http.MethodGet, "/report", time.Second)
+	rec := httptest.NewRecorder()
+	plain.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/report", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
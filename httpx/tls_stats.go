@@ -0,0 +1,118 @@
+package httpx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// TLSStats counts the TLS handshakes of a gateway started by RunTLS or
+	// RunMTLS. Handshakes are those which succeeded, by negotiated version,
+	// e.g. "TLS 1.3", in Versions and by cipher suite in Ciphers.
+	// HandshakeFailures are the connections closed before completing their
+	// handshake, e.g. a client rejecting the certificate, a protocol mismatch
+	// or a TCP probe. CertNotAfter is the earliest expiry of the serving
+	// certificates, those of AddCertificate included, to alert on before it
+	// is reached.
+	TLSStats struct {
+		Handshakes        uint64
+		HandshakeFailures uint64
+		Versions          map[string]uint64
+		Ciphers           map[string]uint64
+		CertNotAfter      time.Time
+	}
+
+	tlsStats struct {
+		handshakes atomic.Uint64
+		failures   atomic.Uint64
+		notAfter   time.Time
+
+		mu       sync.Mutex
+		versions map[string]uint64
+		ciphers  map[string]uint64
+	}
+)
+
+// observeTLS counts the handshakes of the connections s serves with tc, see
+// TLSStats. A connection is told by its first state after StateNew, once it
+// completed its handshake, for a client may still reject the certificate
+// after the server is done with it, and failed if it closes with none
+// complete.
+func (agw *ApiGateway) observeTLS(s *http.Server, tc *tls.Config) {
+	ts := &tlsStats{
+		notAfter: certNotAfter(append(tc.Certificates[:len(tc.Certificates):len(tc.Certificates)], agw.sniCerts.all()...)),
+		versions: make(map[string]uint64),
+		ciphers:  make(map[string]uint64),
+	}
+	agw.tlsStats = ts
+
+	// the connections whose handshake is counted already
+	var counted sync.Map
+	chained := s.ConnState
+	s.ConnState = func(conn net.Conn, state http.ConnState) {
+		if tc, ok := conn.(*tls.Conn); ok && state != http.StateNew {
+			if _, ok := counted.Load(conn); !ok {
+				if cs := tc.ConnectionState(); cs.HandshakeComplete {
+					counted.Store(conn, struct{}{})
+					ts.handshake(cs)
+				} else if state == http.StateClosed {
+					ts.failures.Add(1)
+				}
+			}
+			if state == http.StateClosed || state == http.StateHijacked {
+				counted.Delete(conn)
+			}
+		}
+		if chained != nil {
+			chained(conn, state)
+		}
+	}
+}
+
+func (ts *tlsStats) handshake(cs tls.ConnectionState) {
+	ts.handshakes.Add(1)
+	ts.mu.Lock()
+	ts.versions[tls.VersionName(cs.Version)]++
+	ts.ciphers[tls.CipherSuiteName(cs.CipherSuite)]++
+	ts.mu.Unlock()
+}
+
+func (ts *tlsStats) stats() TLSStats {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	s := TLSStats{
+		Handshakes:        ts.handshakes.Load(),
+		HandshakeFailures: ts.failures.Load(),
+		Versions:          make(map[string]uint64, len(ts.versions)),
+		Ciphers:           make(map[string]uint64, len(ts.ciphers)),
+		CertNotAfter:      ts.notAfter,
+	}
+	for k, v := range ts.versions {
+		s.Versions[k] = v
+	}
+	for k, v := range ts.ciphers {
+		s.Ciphers[k] = v
+	}
+	return s
+}
+
+// certNotAfter returns the earliest expiry of the leaves of certs, zero if
+// none parses
+func certNotAfter(certs []tls.Certificate) time.Time {
+	var notAfter time.Time
+	for _, cert := range certs {
+		leaf := cert.Leaf
+		if leaf == nil && len(cert.Certificate) > 0 {
+			leaf, _ = x509.ParseCertificate(cert.Certificate[0])
+		}
+		if leaf != nil && (notAfter.IsZero() || leaf.NotAfter.Before(notAfter)) {
+			notAfter = leaf.NotAfter
+		}
+	}
+	return notAfter
+}
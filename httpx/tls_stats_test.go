@@ -0,0 +1,79 @@
+package httpx
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCert(t *testing.T, notAfter time.Time, dnsNames ...string) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if len(dnsNames) > 0 {
+		tmpl.Subject.CommonName = dnsNames[0]
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestTLSStats(t *testing.T) {
+	agw, err := NewApiGateway(context.Background(), &LogConfig{LogFile: log.FileConfig{Filename: "discard"}}, nil)
+	require.NoError(t, err)
+	agw.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	assert.Nil(t, agw.Stats().TLS, "no-op for plaintext")
+
+	notAfter := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	cert, pool := newTestCert(t, notAfter)
+	srv := httptest.NewUnstartedServer(agw.Handler())
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	agw.observeTLS(srv.Config, srv.TLS)
+	srv.StartTLS()
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MaxVersion: tls.VersionTLS12}}}
+	res, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+	client.CloseIdleConnections()
+
+	// the certificate is not trusted by this one
+	_, err = http.Get(srv.URL)
+	require.Error(t, err)
+
+	require.Eventually(t, func() bool { return agw.Stats().TLS.HandshakeFailures == 1 }, time.Second, 5*time.Millisecond)
+	ts := agw.Stats().TLS
+	assert.Equal(t, uint64(1), ts.Handshakes)
+	assert.Equal(t, map[string]uint64{"TLS 1.2": 1}, ts.Versions)
+	assert.Len(t, ts.Ciphers, 1)
+	assert.True(t, notAfter.Equal(ts.CertNotAfter))
+}
@@ -0,0 +1,43 @@
+package httpx
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+// TrailingSlash controls how the gateway canonicalizes a trailing slash in
+// the request path, so that /users/ and /users reach the same route.
+type TrailingSlash string
+
+const (
+	// TrailingSlashIgnore routes the path as is
+	TrailingSlashIgnore TrailingSlash = "ignore"
+	// TrailingSlashStrip removes the trailing slash before routing
+	TrailingSlashStrip TrailingSlash = "strip"
+	// TrailingSlashAdd appends a trailing slash before routing
+	TrailingSlashAdd TrailingSlash = "add"
+	// TrailingSlashRedirect redirects a path with a trailing slash to the one
+	// without, with LogConfig.TrailingSlashRedirectCode
+	TrailingSlashRedirect TrailingSlash = "redirect"
+)
+
+// trailingSlashMiddleware returns the Echo middleware for mode, to be
+// installed with Pre so that it runs before routing, nil for
+// TrailingSlashIgnore and unknown modes. code defaults to 301, 308 keeps the
+// method and body of the redirected request.
+func trailingSlashMiddleware(mode TrailingSlash, code int) echo.MiddlewareFunc {
+	switch mode {
+	case TrailingSlashStrip:
+		return middleware.RemoveTrailingSlash()
+	case TrailingSlashAdd:
+		return middleware.AddTrailingSlash()
+	case TrailingSlashRedirect:
+		if code == 0 {
+			code = http.StatusMovedPermanently
+		}
+		return middleware.RemoveTrailingSlashWithConfig(middleware.TrailingSlashConfig{RedirectCode: code})
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/madlabx/pkgx/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrailingSlash(t *testing.T) {
+	for _, tc := range []struct {
+		mode     TrailingSlash
+		code     int
+		path     string
+		want     int
+		location string
+	}{
+		{"", 0, "/users/", http.StatusNotFound, ""},
+		{TrailingSlashIgnore, 0, "/users", http.StatusOK, ""},
+		{TrailingSlashStrip, 0, "/users/", http.StatusOK, ""},
+		{TrailingSlashAdd, 0, "/dirs", http.StatusOK, ""},
+		{TrailingSlashRedirect, 0, "/users/?page=2", http.StatusMovedPermanently, "/users?page=2"},
+		{TrailingSlashRedirect, http.StatusPermanentRedirect, "/users/", http.StatusPermanentRedirect, "/users"},
+	} {
+		agw, err := NewApiGateway(context.Background(), &LogConfig{
+			LogFile:                   log.FileConfig{Filename: "discard"},
+			TrailingSlash:             tc.mode,
+			TrailingSlashRedirectCode: tc.code,
+		}, nil)
+		require.NoError(t, err)
+		ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+		agw.GET("/users", ok)
+		agw.GET("/dirs/", ok)
+
+		rec := httptest.NewRecorder()
+		agw.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		assert.Equal(t, tc.want, rec.Code, "%s %s", tc.mode, tc.path)
+		assert.Equal(t, tc.location, rec.Header().Get(echo.HeaderLocation), "%s %s", tc.mode, tc.path)
+	}
+}
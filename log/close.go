@@ -0,0 +1,76 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"os"
+	"reflect"
+)
+
+type (
+	flusher interface{ Flush() error }
+	syncer  interface{ Sync() error }
+)
+
+// Close flushes and closes the output of the standard logger, see
+// CloseLogger.
+func Close() error {
+	return CloseLogger(StandardLogger())
+}
+
+// CloseLogger flushes and closes the output of lo, and the outputs of its
+// FanOutHook, e.g. on shutdown, so that no entry is lost. Each writer gets the
+// calls of these it implements, in order, the errors are joined:
+//   - Flush() error, e.g. a *bufio.Writer;
+//   - Sync() error, e.g. a *os.File, a lumberjackx.Logger or a FallbackWriter,
+//     committing the file to stable storage;
+//   - Close() error, e.g. a *os.File, a lumberjackx.Logger, which opens the
+//     file again on the next write, or a FallbackWriter closing its Primary.
+//
+// os.Stdout and os.Stderr are left as they are. Other writers closed by it,
+// e.g. a plain *os.File, must not be written to afterwards.
+func CloseLogger(lo *Logger) error {
+	writers := []io.Writer{lo.Out}
+	seen := func(w io.Writer) bool {
+		for _, s := range writers {
+			if reflect.TypeOf(s) == reflect.TypeOf(w) && reflect.TypeOf(w).Comparable() && s == w {
+				return true
+			}
+		}
+		return false
+	}
+	for _, hooks := range lo.Hooks {
+		for _, h := range hooks {
+			if fh, ok := h.(*FanOutHook); ok {
+				for _, o := range fh.outputs {
+					if !seen(o.Writer) {
+						writers = append(writers, o.Writer)
+					}
+				}
+			}
+		}
+	}
+
+	var errs []error
+	for _, w := range writers {
+		errs = append(errs, closeWriter(w))
+	}
+	return errors.Join(errs...)
+}
+
+func closeWriter(w io.Writer) error {
+	if w == nil || w == os.Stdout || w == os.Stderr {
+		return nil
+	}
+	var errs []error
+	if f, ok := w.(flusher); ok {
+		errs = append(errs, f.Flush())
+	}
+	if s, ok := w.(syncer); ok {
+		errs = append(errs, s.Sync())
+	}
+	if c, ok := w.(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closeRecorder struct {
+	bytes.Buffer
+	calls    []string
+	closeErr error
+}
+
+func (w *closeRecorder) Sync() error {
+	w.calls = append(w.calls, "sync")
+	return nil
+}
+
+func (w *closeRecorder) Close() error {
+	w.calls = append(w.calls, "close")
+	return w.closeErr
+}
+
+func TestCloseLogger(t *testing.T) {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	lg := New()
+	lg.SetOutput(bw)
+	lg.Info("buffered")
+	assert.Zero(t, buf.Len())
+	require.NoError(t, CloseLogger(lg))
+	assert.Contains(t, buf.String(), "buffered")
+
+	// the outputs of a FanOutHook, each closed once
+	rec, failing := &closeRecorder{}, &closeRecorder{closeErr: errors.New("disk gone")}
+	lg = New()
+	SetLoggerOutputs(lg, Output{Writer: rec}, Output{Writer: rec}, Output{Writer: failing}, Output{Writer: os.Stdout})
+	err := CloseLogger(lg)
+	assert.ErrorContains(t, err, "disk gone")
+	assert.Equal(t, []string{"sync", "close"}, rec.calls)
+	assert.Equal(t, []string{"sync", "close"}, failing.calls)
+
+	// stdout is left open
+	lg = New()
+	lg.SetOutput(os.Stdout)
+	require.NoError(t, CloseLogger(lg))
+	_, err = os.Stdout.Stat()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"io"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	disabled  atomic.Bool
+	discarded atomic.Bool
+)
+
+// Disable turns the package functions of the standard logger, Infof and the
+// like, into no-ops which return before the entry is built or formatted, for
+// benchmarks and libraries embedding the code where logging should cost
+// nothing. Panic and Fatal still panic and exit. Enable turns them back on.
+//
+// An output set to io.Discard by SetOutput takes the same fast path, unless a
+// hook, e.g. of a test, is there to see the entries. It is left as soon as the
+// output is another one, whether set by SetOutput, StandardLogger().SetOutput
+// or Out.
+func Disable() {
+	disabled.Store(true)
+}
+
+// Enable undoes Disable.
+func Enable() {
+	disabled.Store(false)
+}
+
+// Disabled reports whether the package functions are no-ops after Disable.
+func Disabled() bool {
+	return disabled.Load()
+}
+
+// enabled is logrus.IsLevelEnabled short-circuited by Disable, and by an
+// output set by SetOutput to io.Discard as long as no hook of level would see
+// the entry, the global fields one aside. discarded only tells when to look at
+// the output, which may have been changed since without SetOutput.
+func enabled(level logrus.Level) bool {
+	if disabled.Load() || !logrus.IsLevelEnabled(level) {
+		return false
+	}
+	if !discarded.Load() {
+		return true
+	}
+	std := logrus.StandardLogger()
+	if std.Out != io.Discard {
+		return true
+	}
+	for _, h := range std.Hooks[level] {
+		if _, ok := h.(globalFieldsHook); !ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultFallbackAfter = 3
+
+// FallbackWriter writes to Primary and switches to Fallback, stderr by
+// default, once FailAfter writes in a row failed, e.g. the log volume got
+// unmounted or its permissions changed, writing a warning to Fallback. With a
+// RetryInterval, Primary is tried again at that pace and used back as soon as a
+// write succeeds, otherwise the switch is for good.
+type FallbackWriter struct {
+	Primary  io.Writer
+	Fallback io.Writer
+	// FailAfter is the number of consecutive failures switching to Fallback,
+	// default 3. The failures before are returned to the caller.
+	FailAfter     int
+	RetryInterval time.Duration
+
+	mutex      sync.Mutex
+	failures   int
+	fallenBack bool
+	retryAt    time.Time
+}
+
+// NewFallbackWriter returns a FallbackWriter for primary falling back to
+// stderr, retry 0 never switches back.
+func NewFallbackWriter(primary io.Writer, retry time.Duration) *FallbackWriter {
+	return &FallbackWriter{Primary: primary, RetryInterval: retry}
+}
+
+func (w *FallbackWriter) fallback() io.Writer {
+	if w.Fallback == nil {
+		return os.Stderr
+	}
+	return w.Fallback
+}
+
+// FallenBack reports whether the writes go to Fallback.
+func (w *FallbackWriter) FallenBack() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.fallenBack
+}
+
+func (w *FallbackWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.fallenBack {
+		if w.RetryInterval <= 0 || time.Now().Before(w.retryAt) {
+			return w.fallback().Write(p)
+		}
+		if n, err := w.Primary.Write(p); err == nil {
+			w.fallenBack, w.failures = false, 0
+			_, _ = fmt.Fprintf(w.fallback(), "log: output writable again, switching back to it\n")
+			return n, nil
+		}
+		w.retryAt = time.Now().Add(w.RetryInterval)
+		return w.fallback().Write(p)
+	}
+
+	n, err := w.Primary.Write(p)
+	if err == nil {
+		w.failures = 0
+		return n, nil
+	}
+
+	w.failures++
+	failAfter := w.FailAfter
+	if failAfter <= 0 {
+		failAfter = defaultFallbackAfter
+	}
+	if w.failures < failAfter {
+		return n, err
+	}
+
+	w.fallenBack = true
+	w.retryAt = time.Now().Add(w.RetryInterval)
+	_, _ = fmt.Fprintf(w.fallback(), "log: %d writes to the output failed in a row, last error: %v, falling back\n",
+		w.failures, err)
+	return w.fallback().Write(p)
+}
+
+// Sync commits Primary to stable storage if it has a Sync method, e.g. a
+// lumberjackx.Logger.
+func (w *FallbackWriter) Sync() error {
+	if s, ok := w.Primary.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Close closes Primary if it is an io.Closer.
+func (w *FallbackWriter) Close() error {
+	if c, ok := w.Primary.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type flakyWriter struct {
+	broken bool
+	bytes.Buffer
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	if w.broken {
+		return 0, errors.New("read-only file system")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestFallbackWriter(t *testing.T) {
+	primary, fallback := &flakyWriter{}, &bytes.Buffer{}
+	w := &FallbackWriter{Primary: primary, Fallback: fallback, FailAfter: 2, RetryInterval: 10 * time.Millisecond}
+
+	_, err := w.Write([]byte("a\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a\n", primary.String())
+
+	primary.broken = true
+	_, err = w.Write([]byte("b\n"))
+	assert.Error(t, err)
+	assert.False(t, w.FallenBack())
+
+	_, err = w.Write([]byte("c\n"))
+	assert.NoError(t, err)
+	assert.True(t, w.FallenBack())
+	assert.Contains(t, fallback.String(), "falling back")
+	assert.Contains(t, fallback.String(), "c\n")
+
+	// still broken at the retry
+	time.Sleep(20 * time.Millisecond)
+	_, _ = w.Write([]byte("d\n"))
+	assert.True(t, w.FallenBack())
+
+	primary.broken = false
+	time.Sleep(20 * time.Millisecond)
+	_, err = w.Write([]byte("e\n"))
+	assert.NoError(t, err)
+	assert.False(t, w.FallenBack())
+	assert.Equal(t, "a\ne\n", primary.String())
+	assert.Contains(t, fallback.String(), "switching back")
+}
+
+func TestFallbackWriterNoRetry(t *testing.T) {
+	primary, fallback := &flakyWriter{broken: true}, &bytes.Buffer{}
+	w := &FallbackWriter{Primary: primary, Fallback: fallback, FailAfter: 1}
+
+	_, err := w.Write([]byte("a\n"))
+	assert.NoError(t, err)
+	primary.broken = false
+	_, _ = w.Write([]byte("b\n"))
+	assert.True(t, w.FallenBack())
+	assert.Empty(t, primary.String())
+}
+
+func TestFallbackWriterSync(t *testing.T) {
+	rec := &closeRecorder{}
+	w := NewFallbackWriter(rec, 0)
+	assert.NoError(t, closeWriter(w))
+	assert.Equal(t, []string{"sync", "close"}, rec.calls)
+}
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetLoggerOutputs(t *testing.T) {
+	var console, file, direct bytes.Buffer
+	lg := New()
+	SetLoggerOutputs(lg,
+		Output{Writer: &console, Formatter: &TextFormatter{DisableTimestamp: true, DisableColors: true}},
+		Output{Writer: &file, Formatter: &JSONFormatter{}})
+
+	ref := New()
+	ref.SetOutput(&direct)
+	ref.SetFormatter(&TextFormatter{DisableTimestamp: true, DisableColors: true})
+
+	logIn := func(lo *Logger) { lo.WithField("user", 42).Info("logged in") }
+	logIn(lg)
+	logIn(ref)
+	lg.Debug("dropped")
+
+	// formatted like without the hook, file:line included
+	assert.Equal(t, direct.String(), console.String())
+
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(file.Bytes(), &fields))
+	assert.Equal(t, "logged in", fields["msg"])
+	assert.Equal(t, "info", fields["level"])
+	assert.Equal(t, float64(42), fields["user"])
+	assert.Equal(t, 1, strings.Count(file.String(), "\n"))
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+var globalFields atomic.Pointer[logrus.Fields]
+
+func init() {
+	logrus.StandardLogger().AddHook(globalFieldsHook{})
+}
+
+// SetGlobalFields attaches fields to every entry of the standard logger and
+// of the loggers made by New, NewLogger included, e.g. service, env and host
+// for the log aggregation, in text and JSON alike. A field of the entry
+// itself overrides the global one of the same key. It replaces the fields set
+// before, nil removes them, and is safe to call while logging.
+//
+// The access log of httpx gets them too, at the end of its text lines.
+func SetGlobalFields(fields logrus.Fields) {
+	if len(fields) == 0 {
+		globalFields.Store(nil)
+		return
+	}
+	copied := make(logrus.Fields, len(fields))
+	for k, v := range fields {
+		copied[k] = v
+	}
+	globalFields.Store(&copied)
+}
+
+// GlobalFields returns the fields set by SetGlobalFields, nil if none. The
+// map must not be modified.
+func GlobalFields() logrus.Fields {
+	if f := globalFields.Load(); f != nil {
+		return *f
+	}
+	return nil
+}
+
+// FormatGlobalFields returns the global fields as " key=value" pairs sorted
+// by key, for lines not formatted by a logrus formatter, "" if none.
+func FormatGlobalFields() string {
+	fields := GlobalFields()
+	if len(fields) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	return b.String()
+}
+
+// globalFieldsHook adds the global fields missing from an entry, it is
+// registered before any other hook so that they see them
+type globalFieldsHook struct{}
+
+func (globalFieldsHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (globalFieldsHook) Fire(entry *logrus.Entry) error {
+	for k, v := range GlobalFields() {
+		if _, ok := entry.Data[k]; !ok {
+			entry.Data[k] = v
+		}
+	}
+	return nil
+}
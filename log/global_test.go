@@ -0,0 +1,48 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetGlobalFields(t *testing.T) {
+	SetGlobalFields(logrus.Fields{"service": "billing", "env": "prod"})
+	defer SetGlobalFields(nil)
+
+	lg, buf, _ := NewTestLogger()
+	lg.Info("started")
+	lg.WithField("env", "canary").Info("override")
+	assert.Equal(t, "level=INFO msg=started env=prod service=billing\nlevel=INFO msg=override env=canary service=billing\n",
+		buf.String())
+
+	var js bytes.Buffer
+	lg = New()
+	lg.SetOutput(&js)
+	lg.SetFormatter(&JSONFormatter{})
+	lg.Info("started")
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(js.Bytes(), &entry))
+	assert.Equal(t, "billing", entry["service"])
+	assert.Equal(t, "prod", entry["env"])
+
+	// the standard logger has them too
+	var std bytes.Buffer
+	out := logrus.StandardLogger().Out
+	logrus.SetOutput(&std)
+	defer logrus.SetOutput(out)
+	Info("std")
+	assert.Contains(t, std.String(), "service=billing")
+
+	assert.Equal(t, " env=prod service=billing", FormatGlobalFields())
+	SetGlobalFields(nil)
+	assert.Empty(t, FormatGlobalFields())
+	buf.Reset()
+	lg, buf, _ = NewTestLogger()
+	lg.Info("plain")
+	assert.Equal(t, "level=INFO msg=plain\n", buf.String())
+}
@@ -0,0 +1,64 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// JSONFormatter is logrus.JSONFormatter taking FieldKeyMap like TextFormatter,
+// to rename the standard keys time, level, msg, etc. The keys of an entry,
+// and of nested maps, are always serialized in sorted order.
+type JSONFormatter struct {
+	logrus.JSONFormatter
+
+	// FieldKeyMap renames the standard keys, see TextFormatter.FieldKeyMap.
+	// It takes precedence over JSONFormatter.FieldMap.
+	FieldKeyMap logrus.FieldMap
+
+	// Location is the zone of the timestamps, see TextFormatter.Location.
+	Location *time.Location
+
+	// MaxFieldLength and MaxMessageLength cut the values and the message, see
+	// TextFormatter.MaxFieldLength. Only the string and error values are cut,
+	// the others keep their JSON type.
+	MaxFieldLength   int
+	MaxMessageLength int
+
+	once sync.Once
+}
+
+// Format renders a single log entry
+func (f *JSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.once.Do(func() {
+		if len(f.FieldKeyMap) == 0 {
+			return
+		}
+		fm := logrus.FieldMap{}
+		for k, v := range f.JSONFormatter.FieldMap {
+			fm[k] = v
+		}
+		for k, v := range f.FieldKeyMap {
+			fm[k] = v
+		}
+		f.JSONFormatter.FieldMap = fm
+	})
+	// the entry may be formatted by others, see FanOutHook
+	copied, changed := *entry, false
+	if t := inLocation(entry.Time, f.Location); t.Location() != entry.Time.Location() {
+		copied.Time, changed = t, true
+	}
+	if f.MaxMessageLength > 0 && len(entry.Message) > f.MaxMessageLength {
+		copied.Message, changed = truncate(entry.Message, f.MaxMessageLength), true
+	}
+	if f.MaxFieldLength > 0 {
+		if data := truncateFields(entry.Data, f.MaxFieldLength); data != nil {
+			copied.Data, changed = data, true
+		}
+	}
+	if changed {
+		entry = &copied
+	}
+	return f.JSONFormatter.Format(entry)
+}
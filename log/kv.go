@@ -0,0 +1,93 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FieldKVError is the field reporting malformed key-value pairs of the KV
+// functions, instead of panicking at the call site.
+const FieldKVError = "kv_error"
+
+// KVFields turns alternating keys and values into fields, e.g.
+//
+//	log.KVFields("user", id, "attempts", n)
+//
+// A key which is not a string is formatted with fmt.Sprint. A trailing key
+// without value is kept with a nil value and reported in FieldKVError.
+func KVFields(kv ...interface{}) logrus.Fields {
+	fields := make(logrus.Fields, len(kv)/2+1)
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		if i+1 == len(kv) {
+			fields[key] = nil
+			fields[FieldKVError] = fmt.Sprintf("odd number of key-value arguments, no value for %q", key)
+			break
+		}
+		fields[key] = kv[i+1]
+	}
+	return fields
+}
+
+func logKV(lg *logrus.Logger, level logrus.Level, msg string, kv []interface{}) {
+	if lg.IsLevelEnabled(level) && (lg != logrus.StandardLogger() || enabled(level)) {
+		lg.WithFields(KVFields(kv...)).Log(level, msg)
+	}
+}
+
+// TraceKV logs msg at trace level with the key-value pairs as fields.
+func TraceKV(msg string, kv ...interface{}) {
+	logKV(logrus.StandardLogger(), logrus.TraceLevel, msg, kv)
+}
+
+// DebugKV logs msg at debug level with the key-value pairs as fields, see
+// KVFields.
+func DebugKV(msg string, kv ...interface{}) {
+	logKV(logrus.StandardLogger(), logrus.DebugLevel, msg, kv)
+}
+
+// InfoKV logs msg at info level with the key-value pairs as fields, e.g.
+//
+//	log.InfoKV("user logged in", "user", id, "method", "sso")
+func InfoKV(msg string, kv ...interface{}) {
+	logKV(logrus.StandardLogger(), logrus.InfoLevel, msg, kv)
+}
+
+// WarnKV logs msg at warn level with the key-value pairs as fields.
+func WarnKV(msg string, kv ...interface{}) {
+	logKV(logrus.StandardLogger(), logrus.WarnLevel, msg, kv)
+}
+
+// ErrorKV logs msg at error level with the key-value pairs as fields.
+func ErrorKV(msg string, kv ...interface{}) {
+	logKV(logrus.StandardLogger(), logrus.ErrorLevel, msg, kv)
+}
+
+// TraceKV is TraceKV for lo.
+func (lo *Logger) TraceKV(msg string, kv ...interface{}) {
+	logKV(lo.Logger, logrus.TraceLevel, msg, kv)
+}
+
+// DebugKV is DebugKV for lo.
+func (lo *Logger) DebugKV(msg string, kv ...interface{}) {
+	logKV(lo.Logger, logrus.DebugLevel, msg, kv)
+}
+
+// InfoKV is InfoKV for lo.
+func (lo *Logger) InfoKV(msg string, kv ...interface{}) {
+	logKV(lo.Logger, logrus.InfoLevel, msg, kv)
+}
+
+// WarnKV is WarnKV for lo.
+func (lo *Logger) WarnKV(msg string, kv ...interface{}) {
+	logKV(lo.Logger, logrus.WarnLevel, msg, kv)
+}
+
+// ErrorKV is ErrorKV for lo.
+func (lo *Logger) ErrorKV(msg string, kv ...interface{}) {
+	logKV(lo.Logger, logrus.ErrorLevel, msg, kv)
+}
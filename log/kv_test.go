@@ -0,0 +1,35 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKV(t *testing.T) {
+	lg, _, hook := NewTestLogger()
+
+	lg.InfoKV("user logged in", "user", 42, "method", "sso")
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.InfoLevel, entry.Level)
+	assert.Equal(t, "user logged in", entry.Message)
+	assert.Equal(t, logrus.Fields{"user": 42, "method": "sso"}, entry.Data)
+
+	lg.ErrorKV("odd", "user", 42, 7)
+	entry = hook.LastEntry()
+	assert.Equal(t, logrus.ErrorLevel, entry.Level)
+	assert.Equal(t, 42, entry.Data["user"])
+	assert.Contains(t, entry.Data, "7")
+	assert.Equal(t, `odd number of key-value arguments, no value for "7"`, entry.Data[FieldKVError])
+
+	lg.SetLevel(logrus.InfoLevel)
+	hook.Reset()
+	lg.DebugKV("dropped", "k", "v")
+	assert.Nil(t, hook.LastEntry())
+	lg.WarnKV("kept")
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+	assert.Empty(t, hook.LastEntry().Data)
+}
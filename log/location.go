@@ -0,0 +1,41 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+var timeLocation atomic.Pointer[time.Location]
+
+// SetTimeLocation sets the zone the timestamps are rendered in, e.g. time.UTC
+// for the logs of every region to line up, by the formatters of this package
+// whose Location is nil and by the access log of httpx, so that the
+// application and access timestamps agree. nil, the default, keeps the local
+// time of the process. The name of the rotated files of FileConfig follows
+// its LocalTime, not this zone.
+func SetTimeLocation(loc *time.Location) {
+	timeLocation.Store(loc)
+}
+
+// TimeLocation returns the zone set by SetTimeLocation, nil if none.
+func TimeLocation() *time.Location {
+	return timeLocation.Load()
+}
+
+// Now returns the current time in the zone of SetTimeLocation, for the
+// timestamps not made by a formatter to agree with the others.
+func Now() time.Time {
+	return inLocation(time.Now(), nil)
+}
+
+// inLocation returns t in loc, or in the zone of SetTimeLocation if loc is
+// nil, t unchanged if neither is set
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = timeLocation.Load()
+	}
+	if loc == nil {
+		return t
+	}
+	return t.In(loc)
+}
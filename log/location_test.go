@@ -0,0 +1,49 @@
+package log
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeLocation(t *testing.T) {
+	defer SetTimeLocation(nil)
+	tokyo := time.FixedZone("JST", 9*3600)
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, tokyo)
+	entry := &logrus.Entry{Logger: logrus.New(), Time: at, Level: logrus.InfoLevel, Message: "hi", Data: logrus.Fields{}}
+
+	text := &TextFormatter{DisableColors: true, DisableFileLine: true, TimestampFormat: time.RFC3339}
+	jsonF := &JSONFormatter{}
+	format := func() (string, string) {
+		b, err := text.Format(entry)
+		require.NoError(t, err)
+		jb, err := jsonF.Format(entry)
+		require.NoError(t, err)
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal(jb, &m))
+		return string(b), m["time"].(string)
+	}
+
+	// the zone of the entry by default
+	tb, jt := format()
+	assert.Contains(t, tb, "2024-06-01T12:00:00+09:00")
+	assert.Equal(t, "2024-06-01T12:00:00+09:00", jt)
+
+	SetTimeLocation(time.UTC)
+	tb, jt = format()
+	assert.Contains(t, tb, "2024-06-01T03:00:00Z")
+	assert.Equal(t, "2024-06-01T03:00:00Z", jt)
+	assert.Equal(t, at, entry.Time, "the entry is left alone")
+	assert.Equal(t, time.UTC, Now().Location())
+
+	// the own Location of a formatter wins
+	ny := time.FixedZone("EDT", -4*3600)
+	text.Location, jsonF.Location = ny, ny
+	tb, jt = format()
+	assert.Contains(t, tb, "2024-05-31T23:00:00-04:00")
+	assert.Equal(t, "2024-05-31T23:00:00-04:00", jt)
+}
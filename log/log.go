@@ -9,10 +9,11 @@ import (
 
 func SetOutput(out io.Writer) {
 	logrus.SetOutput(out)
+	discarded.Store(out == io.Discard)
 }
 
 func Set(out io.Writer) {
-	logrus.SetOutput(out)
+	SetOutput(out)
 }
 
 func SetLevelStr(level string) error {
@@ -44,28 +45,63 @@ func WithFields(fields logrus.Fields) *logrus.Entry {
 	return logrus.WithFields(fields)
 }
 
+// IsLevelEnabled reports whether the standard logger emits level, callers use
+// it to skip building expensive messages, e.g. dumps, which would be dropped.
+func IsLevelEnabled(level logrus.Level) bool {
+	return enabled(level)
+}
+
+// DebugFn logs the message returned by fn at debug level. fn is called only if
+// debug is enabled, unlike Debugf whose arguments are always evaluated, so the
+// cost of a disabled call is a level check.
+func DebugFn(fn func() string) {
+	if enabled(logrus.DebugLevel) {
+		logrus.Debug(fn())
+	}
+}
+
+// Trace logs at trace level, below debug, for the most verbose diagnostics,
+// e.g. the steps of a loop, enabled by SetLevelStr("trace").
+func Trace(args ...interface{}) {
+	if enabled(logrus.TraceLevel) {
+		logrus.Trace(args...)
+	}
+}
+
 func Debug(args ...interface{}) {
-	logrus.Debug(args...)
+	if enabled(logrus.DebugLevel) {
+		logrus.Debug(args...)
+	}
 }
 
 func Print(args ...interface{}) {
-	logrus.Print(args...)
+	if enabled(logrus.InfoLevel) {
+		logrus.Print(args...)
+	}
 }
 
 func Info(args ...interface{}) {
-	logrus.Info(args...)
+	if enabled(logrus.InfoLevel) {
+		logrus.Info(args...)
+	}
 }
 
 func Warn(args ...interface{}) {
-	logrus.Warn(args...)
+	if enabled(logrus.WarnLevel) {
+		logrus.Warn(args...)
+	}
 }
 
 func Warning(args ...interface{}) {
-	logrus.Warning(args...)
+	if enabled(logrus.WarnLevel) {
+		logrus.Warning(args...)
+	}
 }
 
 func Error(args ...interface{}) {
-	logrus.Error(args...)
+	if enabled(logrus.ErrorLevel) {
+		logrus.Error(args...)
+	}
 }
 
 func Panic(args ...interface{}) {
@@ -76,28 +112,46 @@ func Fatal(args ...interface{}) {
 	logrus.Fatal(args...)
 }
 
+func Tracef(format string, args ...interface{}) {
+	if enabled(logrus.TraceLevel) {
+		logrus.Tracef(format, args...)
+	}
+}
+
 func Debugf(format string, args ...interface{}) {
-	logrus.Debugf(format, args...)
+	if enabled(logrus.DebugLevel) {
+		logrus.Debugf(format, args...)
+	}
 }
 
 func Printf(format string, args ...interface{}) {
-	logrus.Printf(format, args...)
+	if enabled(logrus.InfoLevel) {
+		logrus.Printf(format, args...)
+	}
 }
 
 func Infof(format string, args ...interface{}) {
-	logrus.Infof(format, args...)
+	if enabled(logrus.InfoLevel) {
+		logrus.Infof(format, args...)
+	}
 }
 
 func Warnf(format string, args ...interface{}) {
-	logrus.Warnf(format, args...)
+	if enabled(logrus.WarnLevel) {
+		logrus.Warnf(format, args...)
+	}
 }
 
 func Warningf(format string, args ...interface{}) {
-	logrus.Warningf(format, args...)
+	if enabled(logrus.WarnLevel) {
+		logrus.Warningf(format, args...)
+	}
 }
 
 func Errorf(format string, args ...interface{}) {
-	logrus.Errorf(format, args...)
+	if enabled(logrus.ErrorLevel) {
+		logrus.Errorf(format, args...)
+	}
 }
 
 func StdoutPrintf(format string, args ...interface{}) {
@@ -112,28 +166,46 @@ func Fatalf(format string, args ...interface{}) {
 	logrus.Fatalf(format, args...)
 }
 
+func Traceln(args ...interface{}) {
+	if enabled(logrus.TraceLevel) {
+		logrus.Traceln(args...)
+	}
+}
+
 func Debugln(args ...interface{}) {
-	logrus.Debugln(args...)
+	if enabled(logrus.DebugLevel) {
+		logrus.Debugln(args...)
+	}
 }
 
 func Println(args ...interface{}) {
-	logrus.Println(args...)
+	if enabled(logrus.InfoLevel) {
+		logrus.Println(args...)
+	}
 }
 
 func Infoln(args ...interface{}) {
-	logrus.Infoln(args...)
+	if enabled(logrus.InfoLevel) {
+		logrus.Infoln(args...)
+	}
 }
 
 func Warnln(args ...interface{}) {
-	logrus.Warnln(args...)
+	if enabled(logrus.WarnLevel) {
+		logrus.Warnln(args...)
+	}
 }
 
 func Warningln(args ...interface{}) {
-	logrus.Warningln(args...)
+	if enabled(logrus.WarnLevel) {
+		logrus.Warningln(args...)
+	}
 }
 
 func Errorln(args ...interface{}) {
-	logrus.Errorln(args...)
+	if enabled(logrus.ErrorLevel) {
+		logrus.Errorln(args...)
+	}
 }
 
 func Panicln(args ...interface{}) {
@@ -145,7 +217,9 @@ func Fatalln(args ...interface{}) {
 }
 
 func Eventf(format string, args ...interface{}) {
-	logrus.Infof("--EVENT-- "+format, args...)
+	if enabled(logrus.InfoLevel) {
+		logrus.Infof("--EVENT-- "+format, args...)
+	}
 }
 
 func FatalIf(args ...interface{}) {
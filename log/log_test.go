@@ -0,0 +1,203 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func expensiveDump() string {
+	return strings.Repeat(fmt.Sprintf("%v", map[string]int{"a": 1, "b": 2}), 64)
+}
+
+func TestDebugFn(t *testing.T) {
+	defer SetLevel(logrus.GetLevel())
+	defer SetOutput(logrus.StandardLogger().Out)
+	SetOutput(new(strings.Builder))
+
+	called := false
+	fn := func() string {
+		called = true
+		return "dump"
+	}
+
+	SetLevel(logrus.InfoLevel)
+	assert.False(t, IsLevelEnabled(logrus.DebugLevel))
+	DebugFn(fn)
+	assert.False(t, called)
+
+	SetLevel(logrus.DebugLevel)
+	assert.True(t, IsLevelEnabled(logrus.DebugLevel))
+	DebugFn(fn)
+	assert.True(t, called)
+}
+
+func TestTrace(t *testing.T) {
+	defer SetLevel(logrus.GetLevel())
+	defer SetOutput(logrus.StandardLogger().Out)
+	defer SetFormatter(logrus.StandardLogger().Formatter)
+	var out strings.Builder
+	SetOutput(&out)
+	SetFormatter(&TextFormatter{DisableColors: true, DisableTimestamp: true, DisableFileLine: true})
+
+	require.NoError(t, SetLevelStr("trace"))
+	assert.True(t, IsLevelEnabled(logrus.TraceLevel))
+	Tracef("step %d", 1)
+	Trace("step 2")
+	Traceln("step", 3)
+	TraceKV("step", "n", 4)
+	Debug("debug")
+	assert.Equal(t, "TRAC step 1\nTRAC step 2\nTRAC step 3\nTRAC step n=4\nDEBU debug\n", out.String())
+
+	out.Reset()
+	SetLevel(logrus.DebugLevel)
+	Tracef("step %d", 1)
+	Trace("step 2")
+	Traceln("step", 3)
+	TraceKV("step", "n", 4)
+	Debug("debug")
+	assert.Equal(t, "DEBU debug\n", out.String())
+}
+
+// BenchmarkDebugf and BenchmarkDebugFn compare a disabled debug call, Debugf
+// pays for building its arguments while DebugFn only checks the level.
+func BenchmarkDebugf(b *testing.B) {
+	defer SetLevel(logrus.GetLevel())
+	SetLevel(logrus.InfoLevel)
+	for i := 0; i < b.N; i++ {
+		Debugf("%v", expensiveDump())
+	}
+}
+
+func BenchmarkDebugFn(b *testing.B) {
+	defer SetLevel(logrus.GetLevel())
+	SetLevel(logrus.InfoLevel)
+	for i := 0; i < b.N; i++ {
+		DebugFn(expensiveDump)
+	}
+}
+
+func TestDisable(t *testing.T) {
+	defer SetOutput(logrus.StandardLogger().Out)
+	var out strings.Builder
+	SetOutput(&out)
+
+	Disable()
+	assert.True(t, Disabled())
+	assert.False(t, IsLevelEnabled(logrus.ErrorLevel))
+	Errorf("disabled %d", 1)
+	InfoKV("disabled", "k", 1)
+	assert.Empty(t, out.String())
+	assert.Panics(t, func() { Panic("still panics") })
+
+	Enable()
+	assert.False(t, Disabled())
+	Errorf("enabled %d", 2)
+	assert.Contains(t, out.String(), "enabled 2")
+
+	// io.Discard takes the fast path, unless a hook sees the entries
+	SetOutput(io.Discard)
+	assert.False(t, IsLevelEnabled(logrus.ErrorLevel))
+	hooks := make(logrus.LevelHooks)
+	for level, hs := range logrus.StandardLogger().Hooks {
+		hooks[level] = append([]logrus.Hook(nil), hs...)
+	}
+	defer logrus.StandardLogger().ReplaceHooks(hooks)
+	hook := test.NewGlobal()
+	assert.True(t, IsLevelEnabled(logrus.ErrorLevel))
+	Errorf("hooked")
+	assert.Equal(t, "hooked", hook.LastEntry().Message)
+}
+
+func TestDiscardOutputChanged(t *testing.T) {
+	defer SetOutput(logrus.StandardLogger().Out)
+
+	// the output changed without SetOutput leaves the fast path too
+	SetOutput(io.Discard)
+	var out strings.Builder
+	logrus.StandardLogger().SetOutput(&out)
+	assert.True(t, IsLevelEnabled(logrus.ErrorLevel))
+	Errorf("set by logrus")
+	assert.Contains(t, out.String(), "set by logrus")
+
+	SetOutput(io.Discard)
+	out.Reset()
+	logrus.StandardLogger().Out = &out
+	Errorf("set by Out")
+	assert.Contains(t, out.String(), "set by Out")
+}
+
+// BenchmarkInfofDisabled shows a disabled logger costs no allocation.
+func BenchmarkInfofDisabled(b *testing.B) {
+	Disable()
+	defer Enable()
+	user, n := "alice", 42
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Infof("user %s logged in %d times", user, n)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { Infof("user %s logged in %d times", user, n) }); allocs != 0 {
+		b.Fatalf("Infof allocates %v times when disabled", allocs)
+	}
+}
+
+func TestFieldKeyMap(t *testing.T) {
+	ecs := logrus.FieldMap{
+		logrus.FieldKeyTime:  "@timestamp",
+		logrus.FieldKeyLevel: "log.level",
+		logrus.FieldKeyMsg:   "message",
+	}
+	entry := &logrus.Entry{
+		Logger:  logrus.New(),
+		Level:   logrus.InfoLevel,
+		Message: "started",
+		Data:    logrus.Fields{"port": 80},
+	}
+
+	text, err := (&TextFormatter{
+		EnableFieldKey:  true,
+		DisableFileLine: true,
+		DisableColors:   true,
+		TimestampFormat: "2006",
+		FieldKeyMap:     ecs,
+	}).Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "@timestamp=0001 log.level=INFO message=started port=80\n", string(text))
+
+	js, err := (&JSONFormatter{
+		JSONFormatter: logrus.JSONFormatter{TimestampFormat: "2006"},
+		FieldKeyMap:   ecs,
+	}).Format(entry)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"@timestamp":"0001","log.level":"info","message":"started","port":80}`, string(js))
+}
+
+func TestDeterministicFields(t *testing.T) {
+	entry := &logrus.Entry{
+		Logger:  logrus.New(),
+		Level:   logrus.InfoLevel,
+		Message: "dump",
+		Data: logrus.Fields{
+			"zeta": 1, "alpha": 2, "mid": map[string]int{"b": 1, "a": 2, "c": 3},
+			"beta": "x", "omega": []int{3, 1}, "gamma": true,
+		},
+	}
+	text := &TextFormatter{DisableTimestamp: true, DisableFileLine: true, DisableColors: true}
+	js := &JSONFormatter{JSONFormatter: logrus.JSONFormatter{DisableTimestamp: true}}
+
+	for i := 0; i < 20; i++ {
+		b, err := text.Format(entry)
+		assert.NoError(t, err)
+		assert.Equal(t, "INFO dump alpha=2 beta=x gamma=true mid=map[a:2 b:1 c:3] omega=[3 1] zeta=1\n", string(b))
+
+		b, err = js.Format(entry)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"alpha":2,"beta":"x","gamma":true,"level":"info","mid":{"a":2,"b":1,"c":3},"msg":"dump","omega":[3,1],"zeta":1}`+"\n", string(b))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"os"
+	"time"
 
 	"github.com/madlabx/pkgx/lumberjackx"
 	"github.com/sirupsen/logrus"
@@ -36,6 +37,13 @@ type FileConfig struct {
 	// Compress determines if the rotated log files should be compressed
 	// using gzip. The default is not to perform compression.
 	Compress bool `vx_default:"true"`
+
+	// Fallback switches the output to stderr when writes to the file keep
+	// failing, e.g. the log volume got unmounted, see FallbackWriter.
+	// FallbackRetry is the pace of retrying the file to switch back to it,
+	// 0 stays on stderr.
+	Fallback      bool          `vx_default:"true"`
+	FallbackRetry time.Duration `vx_default:"30s"`
 }
 
 type Logger struct {
@@ -43,7 +51,9 @@ type Logger struct {
 }
 
 func New() *Logger {
-	return &Logger{logrus.New()}
+	lg := logrus.New()
+	lg.AddHook(globalFieldsHook{})
+	return &Logger{lg}
 }
 
 func NewLogger(pCtx context.Context, cfg FileConfig) *Logger {
@@ -69,20 +79,26 @@ func SetLoggerOutput(lo *Logger, pCtx context.Context, cfg FileConfig) *Logger {
 	if lo == nil {
 		lo = New()
 	}
+	lo.SetOutput(OutputWriter(pCtx, cfg))
+	return lo
+}
 
+// OutputWriter returns the writer SetLoggerOutput sets for cfg, e.g. to be
+// the Writer of an Output.
+func OutputWriter(pCtx context.Context, cfg FileConfig) io.Writer {
 	switch cfg.Filename {
 	case "main":
-		lo.SetOutput(StandardLogger().Out)
+		return StandardLogger().Out
 	case "stdout":
-		lo.SetOutput(os.Stdout)
+		return os.Stdout
 	case "stderr":
-		lo.SetOutput(os.Stderr)
+		return os.Stderr
 	case "discard":
-		lo.SetOutput(io.Discard)
+		return io.Discard
 	case "":
-		lo.SetOutput(os.Stdout)
+		return os.Stdout
 	default:
-		lo.SetOutput(&lumberjackx.Logger{
+		var out io.Writer = &lumberjackx.Logger{
 			Ctx:        context.WithoutCancel(pCtx),
 			Filename:   cfg.Filename,
 			MaxSize:    cfg.MaxSize,    // megabytes
@@ -90,9 +106,12 @@ func SetLoggerOutput(lo *Logger, pCtx context.Context, cfg FileConfig) *Logger {
 			MaxAge:     cfg.MaxAge,     //days
 			Compress:   cfg.Compress,   // disabled by default
 			LocalTime:  cfg.LocalTime,
-		})
+		}
+		if cfg.Fallback {
+			out = NewFallbackWriter(out, cfg.FallbackRetry)
+		}
+		return out
 	}
-	return lo
 }
 
 func SetLoggerFormatter(lo *Logger, formatter logrus.Formatter) {
@@ -0,0 +1,184 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The fields an entry carries its trace and span ids in, hex encoded as in a
+// W3C traceparent, read by OTelHook when OTelHookConfig.SpanContext is nil or
+// finds none.
+const (
+	FieldKeyTraceId = "trace_id"
+	FieldKeySpanId  = "span_id"
+)
+
+type (
+	// OTelRecord is an entry in the terms of the OpenTelemetry log data model,
+	// for an OTelEmitter to export through the OTLP log pipeline.
+	OTelRecord struct {
+		Timestamp time.Time
+		// SeverityNumber is the OTel severity, 5 DEBUG, 9 INFO, 13 WARN, 17
+		// ERROR and 21 FATAL, 1 TRACE, SeverityText the logrus level.
+		SeverityNumber int
+		SeverityText   string
+		Body           string
+		// Attributes are the fields of the entry, errors as their message,
+		// plus code.function, code.filepath and code.lineno with
+		// ReportCaller.
+		Attributes map[string]interface{}
+		// TraceId and SpanId are hex encoded, "" if the entry has none.
+		TraceId string
+		SpanId  string
+	}
+
+	// OTelEmitter exports the records of an OTelHook, e.g. an adapter of a
+	// logger of the OTel log bridge API:
+	//
+	//	type otelEmitter struct{ l otellog.Logger }
+	//
+	//	func (e otelEmitter) Emit(ctx context.Context, rec log.OTelRecord) {
+	//		var r otellog.Record
+	//		r.SetTimestamp(rec.Timestamp)
+	//		r.SetSeverity(otellog.Severity(rec.SeverityNumber))
+	//		r.SetSeverityText(rec.SeverityText)
+	//		r.SetBody(otellog.StringValue(rec.Body))
+	//		for k, v := range rec.Attributes {
+	//			r.AddAttributes(otellog.String(k, fmt.Sprint(v)))
+	//		}
+	//		e.l.Emit(ctx, r)
+	//	}
+	//
+	// with otelEmitter{provider.Logger("my-service")}. The trace and span of
+	// ctx are then attached by the SDK, TraceId and SpanId are there for the
+	// emitters which do not get them from ctx.
+	OTelEmitter interface {
+		Emit(ctx context.Context, rec OTelRecord)
+	}
+
+	// OTelEmitterFunc adapts a function to OTelEmitter.
+	OTelEmitterFunc func(ctx context.Context, rec OTelRecord)
+
+	// OTelHookConfig defines the config of NewOTelHook.
+	OTelHookConfig struct {
+		// Emitter exports the records. Required.
+		Emitter OTelEmitter
+
+		// Levels are the levels exported. Optional. Default value all, the
+		// logger level filters the entries.
+		Levels []logrus.Level
+
+		// SpanContext returns the trace and span ids, hex encoded, of the
+		// context of an entry, see Entry.WithContext, e.g. from
+		// trace.SpanContextFromContext. Optional. The fields trace_id and
+		// span_id of the entry are used otherwise.
+		SpanContext func(ctx context.Context) (traceId, spanId string)
+	}
+
+	// OTelHook converts the entries of a logger to OTelRecord and emits them,
+	// an output besides the own one of the logger, e.g. its file, which keeps
+	// writing.
+	OTelHook struct {
+		config OTelHookConfig
+	}
+)
+
+func (f OTelEmitterFunc) Emit(ctx context.Context, rec OTelRecord) {
+	f(ctx, rec)
+}
+
+// NewOTelHook returns an OTelHook exporting through config.Emitter, to add to
+// a logger, e.g. the access logger of a gateway:
+//
+//	agw.Logger.AddHook(log.NewOTelHook(log.OTelHookConfig{Emitter: emitter}))
+//
+// The access log goes through the hooks with LogConfig.Structured only, the
+// text lines are written to the output directly.
+func NewOTelHook(config OTelHookConfig) *OTelHook {
+	if len(config.Levels) == 0 {
+		config.Levels = logrus.AllLevels
+	}
+	return &OTelHook{config: config}
+}
+
+// Levels returns the levels of OTelHookConfig.Levels.
+func (h *OTelHook) Levels() []logrus.Level {
+	return h.config.Levels
+}
+
+// Fire emits entry, with its context if any. A panic of the emitter is
+// reported to stderr, not to fail the logging.
+func (h *OTelHook) Fire(entry *logrus.Entry) error {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rec := h.record(ctx, entry)
+
+	defer func() {
+		if r := recover(); r != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "log: otel emitter %T panicked: %v\n", h.config.Emitter, r)
+		}
+	}()
+	h.config.Emitter.Emit(ctx, rec)
+	return nil
+}
+
+func (h *OTelHook) record(ctx context.Context, entry *logrus.Entry) OTelRecord {
+	rec := OTelRecord{
+		Timestamp:      entry.Time,
+		SeverityNumber: otelSeverity(entry.Level),
+		SeverityText:   entry.Level.String(),
+		Body:           entry.Message,
+		Attributes:     make(map[string]interface{}, len(entry.Data)+3),
+	}
+	if h.config.SpanContext != nil {
+		rec.TraceId, rec.SpanId = h.config.SpanContext(ctx)
+	}
+	for k, v := range entry.Data {
+		if s, ok := v.(string); ok && k == FieldKeyTraceId {
+			if rec.TraceId == "" {
+				rec.TraceId = s
+			}
+			continue
+		}
+		if s, ok := v.(string); ok && k == FieldKeySpanId {
+			if rec.SpanId == "" {
+				rec.SpanId = s
+			}
+			continue
+		}
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		rec.Attributes[k] = v
+	}
+	if entry.HasCaller() {
+		rec.Attributes["code.function"] = entry.Caller.Function
+		rec.Attributes["code.filepath"] = entry.Caller.File
+		rec.Attributes["code.lineno"] = entry.Caller.Line
+	}
+	return rec
+}
+
+// otelSeverity maps level to the first OTel severity number of its range
+func otelSeverity(level logrus.Level) int {
+	switch level {
+	case logrus.TraceLevel:
+		return 1
+	case logrus.DebugLevel:
+		return 5
+	case logrus.InfoLevel:
+		return 9
+	case logrus.WarnLevel:
+		return 13
+	case logrus.ErrorLevel:
+		return 17
+	default:
+		return 21
+	}
+}
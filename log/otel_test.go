@@ -0,0 +1,76 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type spanKey struct{}
+
+func TestOTelHook(t *testing.T) {
+	var recs []OTelRecord
+	var ctxs []context.Context
+	lg := New()
+	var out bytes.Buffer
+	lg.SetOutput(&out)
+	lg.AddHook(NewOTelHook(OTelHookConfig{
+		Emitter: OTelEmitterFunc(func(ctx context.Context, rec OTelRecord) {
+			ctxs = append(ctxs, ctx)
+			recs = append(recs, rec)
+		}),
+		SpanContext: func(ctx context.Context) (string, string) {
+			if ids, ok := ctx.Value(spanKey{}).([2]string); ok {
+				return ids[0], ids[1]
+			}
+			return "", ""
+		},
+	}))
+
+	lg.WithFields(logrus.Fields{
+		"user":          42,
+		logrus.ErrorKey: errors.New("boom"),
+		FieldKeyTraceId: "4bf92f3577b34da6a3ce929d0e0e4736",
+		FieldKeySpanId:  "00f067aa0ba902b7",
+	}).Warn("failed")
+
+	ctx := context.WithValue(context.Background(), spanKey{}, [2]string{"t1", "s1"})
+	lg.WithContext(ctx).Error("in span")
+	lg.Debug("dropped by the logger level")
+
+	require.Len(t, recs, 2)
+	assert.Equal(t, 13, recs[0].SeverityNumber)
+	assert.Equal(t, "warning", recs[0].SeverityText)
+	assert.Equal(t, "failed", recs[0].Body)
+	assert.Equal(t, map[string]interface{}{"user": 42, "error": "boom"}, recs[0].Attributes)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", recs[0].TraceId)
+	assert.Equal(t, "00f067aa0ba902b7", recs[0].SpanId)
+	assert.False(t, recs[0].Timestamp.IsZero())
+
+	assert.Equal(t, 17, recs[1].SeverityNumber)
+	assert.Equal(t, "t1", recs[1].TraceId)
+	assert.Equal(t, "s1", recs[1].SpanId)
+	assert.Equal(t, ctx, ctxs[1])
+
+	// an additional sink, the output keeps writing
+	assert.Contains(t, out.String(), "failed")
+	assert.Contains(t, out.String(), "in span")
+}
+
+func TestOTelHookEmitterPanic(t *testing.T) {
+	lg := New()
+	lg.SetOutput(&bytes.Buffer{})
+	lg.AddHook(NewOTelHook(OTelHookConfig{
+		Emitter: OTelEmitterFunc(func(context.Context, OTelRecord) { panic("exporter down") }),
+		Levels:  []logrus.Level{logrus.ErrorLevel},
+	}))
+	assert.NotPanics(t, func() {
+		lg.Info("not exported")
+		lg.Error("exported")
+	})
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// messageSampler keeps one in every entries of each format string
+type messageSampler struct {
+	every  atomic.Int64
+	counts sync.Map // format -> *atomic.Uint64
+}
+
+var sampler messageSampler
+
+// SampleByMessage sets the fraction of the Sampledf calls logged, per format
+// string, e.g. 0.01 logs the 1st, 101st, 201st... "cache miss for %s" while a
+// format seen a few times is always logged once. Outside (0, 1) every call is
+// logged, the default.
+//
+// logrus hooks cannot drop an entry and only see the formatted message, whose
+// arguments make each one distinct, hence the explicit Sampledf call keyed by
+// the format before interpolation. The format must be a constant, each
+// distinct one is counted for good.
+func SampleByMessage(rate float64) {
+	every := int64(1)
+	if rate > 0 && rate < 1 {
+		every = int64(math.Round(1 / rate))
+	}
+	sampler.every.Store(every)
+}
+
+// pick counts a call of format and tells whether it is logged
+func (s *messageSampler) pick(format string) bool {
+	every := s.every.Load()
+	if every <= 1 {
+		return true
+	}
+	v, ok := s.counts.Load(format)
+	if !ok {
+		v, _ = s.counts.LoadOrStore(format, new(atomic.Uint64))
+	}
+	n := v.(*atomic.Uint64).Add(1)
+	return (n-1)%uint64(every) == 0
+}
+
+// Sampledf is Infof sampled by format, see SampleByMessage.
+func Sampledf(format string, args ...interface{}) {
+	SampledLogf(logrus.InfoLevel, format, args...)
+}
+
+// SampledLogf logs at level sampled by format, see SampleByMessage. The calls
+// at a disabled level are not counted.
+func SampledLogf(level logrus.Level, format string, args ...interface{}) {
+	if enabled(level) && sampler.pick(format) {
+		logrus.StandardLogger().Logf(level, format, args...)
+	}
+}
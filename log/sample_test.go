@@ -0,0 +1,39 @@
+package log
+
+import (
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampledf(t *testing.T) {
+	defer SetLevel(logrus.GetLevel())
+	defer SetOutput(logrus.StandardLogger().Out)
+	defer SampleByMessage(1)
+	SetOutput(io.Discard)
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+	SetLevel(logrus.InfoLevel)
+
+	SampleByMessage(0.1)
+	for i := 0; i < 25; i++ {
+		Sampledf("cache miss for %d", i)
+	}
+	Sampledf("disk %s full", "/data")
+	SampledLogf(logrus.DebugLevel, "dropped %d", 1)
+
+	var msgs []string
+	for _, e := range hook.AllEntries() {
+		msgs = append(msgs, e.Message)
+	}
+	assert.Equal(t, []string{"cache miss for 0", "cache miss for 10", "cache miss for 20", "disk /data full"}, msgs)
+
+	hook.Reset()
+	SampleByMessage(1)
+	Sampledf("cache miss for %d", 25)
+	Sampledf("cache miss for %d", 26)
+	assert.Len(t, hook.AllEntries(), 2)
+}
@@ -0,0 +1,114 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SlogHandler is a slog.Handler that routes records through a logrus logger,
+// so slog based code shares output, formatter and rotation with this package.
+type SlogHandler struct {
+	logger *logrus.Logger
+	attrs  []slog.Attr
+	groups []string
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
+
+// NewSlogHandler returns a slog.Handler backed by the standard logger.
+func NewSlogHandler() slog.Handler {
+	return NewSlogHandlerWithLogger(StandardLogger())
+}
+
+// NewSlogHandlerWithLogger returns a slog.Handler backed by lg.
+func NewSlogHandlerWithLogger(lg *Logger) slog.Handler {
+	return &SlogHandler{logger: lg.Logger}
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.IsLevelEnabled(slogToLogrusLevel(level))
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make(logrus.Fields, len(h.attrs)+r.NumAttrs())
+	for _, a := range h.attrs {
+		addSlogAttr(fields, "", a)
+	}
+
+	prefix := groupPrefix(h.groups)
+	r.Attrs(func(a slog.Attr) bool {
+		addSlogAttr(fields, prefix, a)
+		return true
+	})
+
+	entry := h.logger.WithContext(ctx).WithFields(fields)
+	if !r.Time.IsZero() {
+		entry = entry.WithTime(r.Time)
+	}
+	entry.Log(slogToLogrusLevel(r.Level), r.Message)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	nh := *h
+	prefix := groupPrefix(h.groups)
+	nh.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	nh.attrs = append(nh.attrs, h.attrs...)
+	for _, a := range attrs {
+		nh.attrs = append(nh.attrs, slog.Attr{Key: prefix + a.Key, Value: a.Value})
+	}
+	return &nh
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	nh := *h
+	nh.groups = append(append([]string{}, h.groups...), name)
+	return &nh
+}
+
+func groupPrefix(groups []string) string {
+	var prefix string
+	for _, g := range groups {
+		prefix += g + "."
+	}
+	return prefix
+}
+
+// addSlogAttr flattens group attrs into dotted keys, e.g. "req.method".
+func addSlogAttr(fields logrus.Fields, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		gp := prefix
+		if a.Key != "" {
+			gp = prefix + a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addSlogAttr(fields, gp, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	fields[prefix+a.Key] = v.Any()
+}
+
+func slogToLogrusLevel(level slog.Level) logrus.Level {
+	switch {
+	case level >= slog.LevelError:
+		return logrus.ErrorLevel
+	case level >= slog.LevelWarn:
+		return logrus.WarnLevel
+	case level >= slog.LevelInfo:
+		return logrus.InfoLevel
+	case level >= slog.LevelDebug:
+		return logrus.DebugLevel
+	default:
+		return logrus.TraceLevel
+	}
+}
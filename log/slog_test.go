@@ -0,0 +1,72 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogToLogrusLevel(t *testing.T) {
+	for level, want := range map[slog.Level]logrus.Level{
+		slog.LevelError + 4: logrus.ErrorLevel,
+		slog.LevelError:     logrus.ErrorLevel,
+		slog.LevelWarn + 2:  logrus.WarnLevel,
+		slog.LevelWarn:      logrus.WarnLevel,
+		slog.LevelInfo + 1:  logrus.InfoLevel,
+		slog.LevelInfo:      logrus.InfoLevel,
+		slog.LevelDebug + 3: logrus.DebugLevel,
+		slog.LevelDebug:     logrus.DebugLevel,
+		slog.LevelDebug - 1: logrus.TraceLevel,
+	} {
+		assert.Equal(t, want, slogToLogrusLevel(level), level.String())
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	lg, _, hook := NewTestLogger()
+	sl := slog.New(NewSlogHandlerWithLogger(lg))
+
+	sl.Warn("disk full", "disk", "/data", slog.Int("free", 0))
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.WarnLevel, entry.Level)
+	assert.Equal(t, "disk full", entry.Message)
+	assert.Equal(t, logrus.Fields{"disk": "/data", "free": int64(0)}, entry.Data)
+
+	sl.With("svc", "api").WithGroup("req").With("id", "r1").
+		Info("done", "method", "GET", slog.Group("res", "status", 200))
+	entry = hook.LastEntry()
+	assert.Equal(t, logrus.Fields{
+		"svc":            "api",
+		"req.id":         "r1",
+		"req.method":     "GET",
+		"req.res.status": int64(200),
+	}, entry.Data)
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := slog.NewRecord(at, slog.LevelDebug-2, "deep", 0)
+	require.NoError(t, NewSlogHandlerWithLogger(lg).Handle(context.Background(), r))
+	assert.Equal(t, logrus.TraceLevel, hook.LastEntry().Level)
+	assert.True(t, at.Equal(hook.LastEntry().Time))
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	lg, _, hook := NewTestLogger()
+	h := NewSlogHandlerWithLogger(lg)
+	ctx := context.Background()
+
+	lg.SetLevel(logrus.WarnLevel)
+	assert.False(t, h.Enabled(ctx, slog.LevelInfo))
+	assert.True(t, h.Enabled(ctx, slog.LevelWarn))
+	assert.True(t, h.Enabled(ctx, slog.LevelError))
+	slog.New(h).Info("dropped")
+	assert.Empty(t, hook.AllEntries())
+
+	lg.SetLevel(logrus.TraceLevel)
+	assert.True(t, h.Enabled(ctx, slog.LevelDebug-4))
+}
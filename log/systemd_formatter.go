@@ -0,0 +1,79 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SystemdFormatter prefixes each line rendered by Formatter with the
+// sd-daemon <N> priority of the entry level, so that journald, reading the
+// stdout/stderr of a service, classifies the severity, e.g. "<3>" for errors.
+// Every line of a multi-line entry gets the prefix.
+type SystemdFormatter struct {
+	// Formatter renders the entry. Optional. Default value a TextFormatter
+	// without timestamp, added by journald.
+	Formatter logrus.Formatter
+
+	// defaults Formatter once, Format runs concurrently
+	once sync.Once
+}
+
+// SystemdPriority maps a level to its syslog priority, 0 emerg to 7 debug.
+func SystemdPriority(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return 0
+	case logrus.FatalLevel:
+		return 2
+	case logrus.ErrorLevel:
+		return 3
+	case logrus.WarnLevel:
+		return 4
+	case logrus.InfoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// Format renders a single log entry
+func (f *SystemdFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.once.Do(func() {
+		if f.Formatter == nil {
+			f.Formatter = &TextFormatter{DisableTimestamp: true}
+		}
+	})
+	b, err := f.Formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := []byte("<" + strconv.Itoa(SystemdPriority(entry.Level)) + ">")
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	out := make([]byte, 0, len(b)+len(lines)*len(prefix))
+	for _, line := range lines {
+		if len(line) > 0 {
+			out = append(out, prefix...)
+			out = append(out, line...)
+		}
+	}
+	return out, nil
+}
+
+// NewFormatter returns the formatter named by format: "text" or "" for
+// TextFormatter, "json" for JSONFormatter and "systemd" for SystemdFormatter.
+func NewFormatter(format string) (logrus.Formatter, error) {
+	switch format {
+	case "", "text":
+		return &TextFormatter{QuoteEmptyFields: true}, nil
+	case "json":
+		return &JSONFormatter{}, nil
+	case "systemd":
+		return &SystemdFormatter{}, nil
+	}
+	return nil, fmt.Errorf("unknown log format '%s', should be one of [text, json, systemd]", format)
+}
@@ -0,0 +1,47 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemdFormatter(t *testing.T) {
+	f := &SystemdFormatter{Formatter: &TextFormatter{DisableTimestamp: true, DisableFileLine: true, DisableColors: true}}
+
+	for level, want := range map[logrus.Level]string{
+		logrus.ErrorLevel: "<3>ERRO disk full\n<3>second line\n",
+		logrus.WarnLevel:  "<4>WARN disk full\n<4>second line\n",
+		logrus.InfoLevel:  "<6>INFO disk full\n<6>second line\n",
+		logrus.DebugLevel: "<7>DEBU disk full\n<7>second line\n",
+	} {
+		b, err := f.Format(&logrus.Entry{Logger: logrus.New(), Level: level, Message: "disk full\nsecond line"})
+		assert.NoError(t, err)
+		assert.Equal(t, want, string(b))
+	}
+
+	nf, err := NewFormatter("systemd")
+	assert.NoError(t, err)
+	assert.IsType(t, &SystemdFormatter{}, nf)
+	_, err = NewFormatter("xml")
+	assert.Error(t, err)
+}
+
+func TestSystemdFormatterConcurrent(t *testing.T) {
+	// the default Formatter is set by the first Format, run with -race
+	f := &SystemdFormatter{}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b, err := f.Format(&logrus.Entry{Logger: logrus.New(), Level: logrus.InfoLevel, Message: "up"})
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(b), "<6>"))
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,32 @@
+package log
+
+import (
+	"bytes"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// NewTestLogger returns a Logger for tests, which writes formatted entries to
+// the returned buffer and records every entry in the returned hook, so a test
+// can assert level, message and fields:
+//
+//	lg, buf, hook := log.NewTestLogger()
+//	doWork(lg)
+//	require.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+//
+// All levels are enabled.
+func NewTestLogger() (*Logger, *bytes.Buffer, *test.Hook) {
+	buf := &bytes.Buffer{}
+	lg := New()
+	lg.SetOutput(buf)
+	lg.SetLevel(logrus.TraceLevel)
+	lg.SetFormatter(&TextFormatter{
+		DisableColors:    true,
+		DisableTimestamp: true,
+		DisableFileLine:  true,
+		EnableFieldKey:   true,
+		QuoteEmptyFields: true,
+	})
+	return lg, buf, test.NewLocal(lg.Logger)
+}
@@ -0,0 +1,18 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTestLogger(t *testing.T) {
+	lg, buf, hook := NewTestLogger()
+	lg.WithField("path", "/a").Error("failed")
+
+	require.Equal(t, "level=ERRO msg=failed path=/a\n", buf.String())
+	require.Len(t, hook.Entries, 1)
+	require.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+	require.Equal(t, "/a", hook.LastEntry().Data["path"])
+}
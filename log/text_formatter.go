@@ -51,9 +51,14 @@ type TextFormatter struct {
 	// TimestampFormat to use for display when a full timestamp is printed
 	TimestampFormat string
 
-	// The fields are sorted by default for a consistent output. For applications
-	// that log extremely frequently and don't use the JSON formatter this may not
-	// be desired.
+	// Location is the zone of the timestamps, e.g. time.UTC. nil renders
+	// them in the zone of SetTimeLocation, the local time if none.
+	Location *time.Location
+
+	// The fields are sorted by default for a consistent output, map values
+	// are printed with sorted keys too, so golden-file comparisons are stable.
+	// For applications that log extremely frequently and don't use the JSON
+	// formatter this may not be desired.
 	DisableSorting bool
 
 	// Disables the truncation of the level text to 4 characters.
@@ -62,9 +67,18 @@ type TextFormatter struct {
 	// QuoteEmptyFields will wrap empty fields in quotes if true
 	QuoteEmptyFields bool
 
+	// MaxFieldLength cuts the field values longer than it, in bytes, ending
+	// them with "...(truncated)", against a payload logged whole by mistake.
+	// MaxMessageLength does it for the message. 0, the default, is unlimited.
+	MaxFieldLength   int
+	MaxMessageLength int
+
 	// Whether the logger's out is to a terminal
 	isTerminal bool
 
+	// frames added between the logger and Format, see FanOutHook
+	callerSkip int
+
 	// Show Field Keys
 	// true:  time="2020-02-22T21:33:31+08:00" level=info msg="File read done:conf/input!"
 	// false: "2020-02-22T21:42:57+08:00" info "Start all services..."
@@ -76,6 +90,20 @@ type TextFormatter struct {
 	// need quote
 	EnableQuoting bool
 
+	// FieldKeyMap renames the standard keys shown with EnableFieldKey, e.g.
+	// to match an ECS pipeline:
+	//  FieldKeyMap: logrus.FieldMap{
+	//    logrus.FieldKeyTime:  "@timestamp",
+	//    logrus.FieldKeyLevel: "log.level",
+	//    logrus.FieldKeyMsg:   "message",
+	//  }
+	// logrus.FieldKeyFile renames the file:line key "filen". Unmapped keys keep
+	// their default name.
+	FieldKeyMap logrus.FieldMap
+
+	// standard keys resolved from FieldKeyMap
+	keyTime, keyLevel, keyFile, keyMsg string
+
 	sync.Once
 }
 
@@ -87,6 +115,20 @@ func (f *TextFormatter) init(entry *logrus.Entry) {
 	if f.TimestampFormat == "" {
 		f.TimestampFormat = defaultTimestampFormat
 	}
+
+	f.keyTime, f.keyLevel, f.keyFile, f.keyMsg = "time", "level", "filen", "msg"
+	for k, v := range f.FieldKeyMap {
+		switch k {
+		case logrus.FieldKeyTime:
+			f.keyTime = v
+		case logrus.FieldKeyLevel:
+			f.keyLevel = v
+		case logrus.FieldKeyFile:
+			f.keyFile = v
+		case logrus.FieldKeyMsg:
+			f.keyMsg = v
+		}
+	}
 }
 
 func getRunTimeInfo(frame int) (file, fName string, line int, ok bool) {
@@ -128,6 +170,8 @@ func checkIfTerminal(w io.Writer) bool {
 // Convert the Level to a string. E.g. PanicLevel becomes "panic".
 func LevelToString(level logrus.Level) string {
 	switch level {
+	case logrus.TraceLevel:
+		return "TRAC"
 	case logrus.DebugLevel:
 		return "DEBU"
 	case logrus.InfoLevel:
@@ -172,15 +216,15 @@ func (f *TextFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	}
 
 	if !f.DisableTimestamp {
-		f.appendMsg(b, "time", entry.Time.Format(f.TimestampFormat))
+		f.appendMsg(b, f.keyTime, inLocation(entry.Time, f.Location).Format(f.TimestampFormat))
 	}
-	f.appendMsg(b, "level", levelStr)
+	f.appendMsg(b, f.keyLevel, levelStr)
 	if !f.DisableFileLine {
-		fl, _ := getRunTimeInfoString(9)
-		f.appendMsg(b, "filen", fl)
+		fl, _ := getRunTimeInfoString(9 + f.callerSkip)
+		f.appendMsg(b, f.keyFile, fl)
 	}
 	if entry.Message != "" {
-		f.appendMsg(b, "msg", entry.Message)
+		f.appendMsg(b, f.keyMsg, truncate(entry.Message, f.MaxMessageLength))
 	}
 	for _, key := range keys {
 		f.appendKeyValueItf(b, key, entry.Data[key])
@@ -189,10 +233,11 @@ func (f *TextFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	b.WriteByte('\n')
 	return b.Bytes(), nil
 }
+
 func (f *TextFormatter) withColored(str string, entry *logrus.Entry) string {
 	var levelColor int
 	switch entry.Level {
-	case logrus.DebugLevel:
+	case logrus.TraceLevel, logrus.DebugLevel:
 		levelColor = gray
 	case logrus.WarnLevel:
 		levelColor = yellow
@@ -265,6 +310,7 @@ func (f *TextFormatter) appendValueItf(b *bytes.Buffer, value interface{}) {
 	if !ok {
 		stringVal = fmt.Sprint(value)
 	}
+	stringVal = truncate(stringVal, f.MaxFieldLength)
 
 	if !f.needsQuoting(stringVal) {
 		b.WriteString(stringVal)
@@ -0,0 +1,51 @@
+package log
+
+import (
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// truncatedSuffix ends a value cut by MaxFieldLength or MaxMessageLength
+const truncatedSuffix = "...(truncated)"
+
+// truncate cuts s to max bytes, on a rune boundary, followed by
+// truncatedSuffix. max <= 0 keeps it whole.
+func truncate(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + truncatedSuffix
+}
+
+// truncateFields returns a copy of data with its string and error values cut
+// to max, nil if none is longer
+func truncateFields(data logrus.Fields, max int) logrus.Fields {
+	var copied logrus.Fields
+	for k, v := range data {
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = v
+		case error:
+			s = v.Error()
+		default:
+			continue
+		}
+		if len(s) <= max {
+			continue
+		}
+		if copied == nil {
+			copied = make(logrus.Fields, len(data))
+			for k, v := range data {
+				copied[k] = v
+			}
+		}
+		copied[k] = truncate(s, max)
+	}
+	return copied
+}
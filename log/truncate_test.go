@@ -0,0 +1,51 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxFieldLength(t *testing.T) {
+	payload := strings.Repeat("x", 100)
+	entry := &logrus.Entry{Logger: logrus.New(), Level: logrus.InfoLevel, Message: "message " + payload, Data: logrus.Fields{
+		"payload": payload,
+		"err":     errors.New(payload),
+		"short":   "ok",
+		"count":   123456789,
+	}}
+
+	text := &TextFormatter{DisableColors: true, DisableFileLine: true, DisableTimestamp: true, MaxFieldLength: 4, MaxMessageLength: 7}
+	b, err := text.Format(entry)
+	require.NoError(t, err)
+	assert.Equal(t, "INFO message...(truncated) count=1234...(truncated) err=xxxx...(truncated) payload=xxxx...(truncated) short=ok\n", string(b))
+
+	jb, err := (&JSONFormatter{MaxFieldLength: 4, MaxMessageLength: 7}).Format(entry)
+	require.NoError(t, err)
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(jb, &m))
+	assert.Equal(t, "message...(truncated)", m["msg"])
+	assert.Equal(t, "xxxx...(truncated)", m["payload"])
+	assert.Equal(t, "xxxx...(truncated)", m["err"])
+	assert.Equal(t, "ok", m["short"])
+	assert.EqualValues(t, 123456789, m["count"])
+	assert.Equal(t, payload, entry.Data["payload"], "the entry is left alone")
+
+	// unlimited by default
+	b, err = (&TextFormatter{DisableColors: true, DisableFileLine: true, DisableTimestamp: true}).Format(entry)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "payload="+payload)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 0))
+	assert.Equal(t, "abc", truncate("abc", 3))
+	assert.Equal(t, "ab...(truncated)", truncate("abc", 2))
+	// never in the middle of a rune
+	assert.Equal(t, "a...(truncated)", truncate("a日本", 3))
+}
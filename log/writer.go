@@ -0,0 +1,41 @@
+package log
+
+import (
+	"io"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// levelWriter logs each line written to it as an entry of logger at level
+type levelWriter struct {
+	logger *logrus.Logger
+	level  logrus.Level
+}
+
+// Writer returns an io.Writer funneling the lines written to it into the
+// standard logger at level, for libraries logging to an io.Writer, e.g.
+//
+//	srv.ErrorLog = stdlog.New(log.Writer(logrus.ErrorLevel), "", 0)
+//
+// Each line of a Write becomes one entry, empty lines are dropped.
+func Writer(level logrus.Level) io.Writer {
+	return &levelWriter{logger: logrus.StandardLogger(), level: level}
+}
+
+// LoggerWriter is Writer for lo.
+func LoggerWriter(lo *Logger, level logrus.Level) io.Writer {
+	return &levelWriter{logger: lo.Logger, level: level}
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	if !w.logger.IsLevelEnabled(w.level) {
+		return len(p), nil
+	}
+	for _, line := range strings.Split(string(p), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			w.logger.Log(w.level, line)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,32 @@
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	lo := New()
+	out := &bytes.Buffer{}
+	lo.SetOutput(out)
+	lo.SetFormatter(&TextFormatter{DisableTimestamp: true, DisableFileLine: true, DisableColors: true})
+	lo.SetLevel(logrus.WarnLevel)
+
+	_, err := LoggerWriter(lo, logrus.WarnLevel).Write([]byte("first\r\n\nsecond\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "WARN first\nWARN second\n", out.String())
+
+	out.Reset()
+	stdlog.New(LoggerWriter(lo, logrus.ErrorLevel), "http: ", 0).Print("TLS handshake error")
+	assert.Equal(t, "ERRO http: TLS handshake error\n", out.String())
+
+	out.Reset()
+	_, _ = LoggerWriter(lo, logrus.InfoLevel).Write([]byte("dropped\n"))
+	assert.Empty(t, out.String())
+
+	assert.NotNil(t, Writer(logrus.InfoLevel))
+}
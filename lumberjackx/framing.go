@@ -0,0 +1,60 @@
+package lumberjackx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Framing is the way records, one per Write, are delimited in the log file.
+type Framing string
+
+const (
+	// FramingNone writes the bytes as is.
+	FramingNone Framing = "none"
+	// FramingNewline ends each record with a newline, added if missing.
+	FramingNewline Framing = "newline"
+	// FramingLengthPrefix writes the length of each record as a 4 bytes big
+	// endian unsigned integer before it, the record is written as is. A reader
+	// loops on reading the 4 bytes then that many bytes.
+	FramingLengthPrefix Framing = "length"
+)
+
+const lengthPrefixSize = 4
+
+// frame returns p framed as a record, with the offset of p in it.
+func (f Framing) frame(p []byte) ([]byte, int, error) {
+	switch f {
+	case "", FramingNone:
+		return p, 0, nil
+	case FramingNewline:
+		if len(p) > 0 && p[len(p)-1] == '\n' {
+			return p, 0, nil
+		}
+		record := make([]byte, len(p)+1)
+		copy(record, p)
+		record[len(p)] = '\n'
+		return record, 0, nil
+	case FramingLengthPrefix:
+		if uint64(len(p)) > math.MaxUint32 {
+			return nil, 0, fmt.Errorf("write length %d exceeds the length prefix", len(p))
+		}
+		record := make([]byte, lengthPrefixSize+len(p))
+		binary.BigEndian.PutUint32(record, uint32(len(p)))
+		copy(record[lengthPrefixSize:], p)
+		return record, lengthPrefixSize, nil
+	}
+	return nil, 0, fmt.Errorf("unknown framing %q", f)
+}
+
+// framedWritten returns the bytes of p written out of the first n of its
+// record, with p at offset in it. A record not written in full is short even
+// if only its framing is missing, a newline say, for the caller not to take
+// an unterminated record for a complete one.
+func framedWritten(n, offset int, p, record []byte) int {
+	written := min(max(n-offset, 0), len(p))
+	if n < len(record) && written == len(p) {
+		written = max(len(p)-1, 0)
+	}
+	return written
+}
@@ -4,7 +4,9 @@
 package lumberjackx
 
 import (
+	"context"
 	"os"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -23,6 +25,7 @@ func TestMaintainMode(t *testing.T) {
 	f.Close()
 
 	l := &Logger{
+		Ctx:        context.Background(),
 		Filename:   filename,
 		MaxBackups: 1,
 		MaxSize:    100, // megabytes
@@ -66,6 +69,7 @@ func TestMaintainOwner(t *testing.T) {
 	f.Close()
 
 	l := &Logger{
+		Ctx:        context.Background(),
 		Filename:   filename,
 		MaxBackups: 1,
 		MaxSize:    100, // megabytes
@@ -81,8 +85,7 @@ func TestMaintainOwner(t *testing.T) {
 	err = l.Rotate()
 	isNil(err, t)
 
-	equals(555, fakeFS.files[filename].uid, t)
-	equals(666, fakeFS.files[filename].gid, t)
+	equals(fakeFile{uid: 555, gid: 666}, fakeFS.file(filename), t)
 }
 
 func TestCompressMaintainMode(t *testing.T) {
@@ -99,6 +102,7 @@ func TestCompressMaintainMode(t *testing.T) {
 	f.Close()
 
 	l := &Logger{
+		Ctx:        context.Background(),
 		Compress:   true,
 		Filename:   filename,
 		MaxBackups: 1,
@@ -149,6 +153,7 @@ func TestCompressMaintainOwner(t *testing.T) {
 	f.Close()
 
 	l := &Logger{
+		Ctx:        context.Background(),
 		Compress:   true,
 		Filename:   filename,
 		MaxBackups: 1,
@@ -172,8 +177,7 @@ func TestCompressMaintainOwner(t *testing.T) {
 	// a compressed version of the log file should now exist with the correct
 	// owner.
 	filename2 := backupFile(dir)
-	equals(555, fakeFS.files[filename2+compressSuffix].uid, t)
-	equals(666, fakeFS.files[filename2+compressSuffix].gid, t)
+	equals(fakeFile{uid: 555, gid: 666}, fakeFS.file(filename2+compressSuffix), t)
 }
 
 type fakeFile struct {
@@ -181,7 +185,9 @@ type fakeFile struct {
 	gid int
 }
 
+// fakeFS is also called by the compression goroutine
 type fakeFS struct {
+	mu    sync.Mutex
 	files map[string]fakeFile
 }
 
@@ -190,10 +196,18 @@ func newFakeFS() *fakeFS {
 }
 
 func (fs *fakeFS) Chown(name string, uid, gid int) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
 	fs.files[name] = fakeFile{uid: uid, gid: gid}
 	return nil
 }
 
+func (fs *fakeFS) file(name string) fakeFile {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.files[name]
+}
+
 func (fs *fakeFS) Stat(name string) (os.FileInfo, error) {
 	info, err := os.Stat(name)
 	if err != nil {
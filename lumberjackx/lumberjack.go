@@ -109,12 +109,41 @@ type Logger struct {
 	// using gzip. The default is not to perform compression.
 	Compress bool `json:"compress" yaml:"compress" vx_default:"true"`
 
+	// CompressConcurrency is the maximum number of backups compressed at the
+	// same time, pending ones wait in queue. It defaults to 1.
+	CompressConcurrency int `json:"compressconcurrency" yaml:"compressconcurrency" vx_default:"1"`
+
+	// CompressDelay is the number of most recent backups left uncompressed,
+	// e.g. to keep tailing them, older ones are compressed. MaxBackups and
+	// MaxAge count them like the compressed ones. It defaults to 0, compressing
+	// each backup right after rotation.
+	CompressDelay int `json:"compressdelay" yaml:"compressdelay" vx_default:"0"`
+
+	// DirMode is the permission of the directories created for Filename on
+	// first write, subject to umask. It defaults to 0755.
+	DirMode os.FileMode `json:"dirmode" yaml:"dirmode" vx_default:"0755"`
+
+	// FileMode is the permission of newly created log files, subject to umask.
+	// A rotated file keeps the mode of the one it replaces. It defaults to 0644.
+	FileMode os.FileMode `json:"filemode" yaml:"filemode" vx_default:"0644"`
+
+	// Framing delimits the records, one per Write, for shippers which cannot
+	// split on newlines because a message may hold some, see Framing. It
+	// defaults to FramingNone, writing the bytes as is.
+	Framing Framing `json:"framing" yaml:"framing" vx_default:"none"`
+
 	size int64
 	file *os.File
 	mu   sync.Mutex
 
 	millCh    chan bool
 	startMill sync.Once
+	// millPending counts queued and running mill requests, Close waits on it
+	millPending sync.WaitGroup
+	millMu      sync.Mutex
+	millStopped bool
+
+	metrics loggerMetrics
 
 	//context to control life circle of mill
 	Ctx context.Context
@@ -163,7 +192,12 @@ func (l *Logger) Write(p []byte) (n int, err error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	writeLen := int64(len(p))
+	record, offset, err := l.Framing.frame(p)
+	if err != nil {
+		return 0, err
+	}
+
+	writeLen := int64(len(record))
 	if writeLen > l.max() {
 		return 0, fmt.Errorf(
 			"write length %d exceeds maximum file size %d", writeLen, l.max(),
@@ -171,7 +205,7 @@ func (l *Logger) Write(p []byte) (n int, err error) {
 	}
 
 	if l.file == nil {
-		if err = l.openExistingOrNew(len(p)); err != nil {
+		if err = l.openExistingOrNew(len(record)); err != nil {
 			return 0, err
 		}
 	}
@@ -182,17 +216,37 @@ func (l *Logger) Write(p []byte) (n int, err error) {
 		}
 	}
 
-	n, err = l.file.Write(p)
+	n, err = l.file.Write(record)
 	l.size += int64(n)
+	l.metrics.bytesWritten.Add(uint64(n))
 
-	return n, err
+	if n < len(record) && err == nil {
+		err = io.ErrShortWrite
+	}
+	// report the bytes of p written, not counting the framing
+	return framedWritten(n, offset, p, record), err
 }
 
-// Close implements io.Closer, and closes the current logfile.
+// Close implements io.Closer, and closes the current logfile. It waits for
+// pending compression and removal of old log files, so no backup is left
+// uncompressed when the process exits cleanly.
 func (l *Logger) Close() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	return l.close()
+	err := l.close()
+	l.millPending.Wait()
+	return err
+}
+
+// Sync commits the current logfile to stable storage, if it is open. Writes
+// are not buffered by Logger, Sync only matters against a crash of the host.
+func (l *Logger) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Sync()
 }
 
 // close closes the file if it is open.
@@ -233,13 +287,13 @@ func (l *Logger) rotate() error {
 // openNew opens a new log file for writing, moving any old log file out of the
 // way.  This methods assumes the file has already been closed.
 func (l *Logger) openNew() error {
-	err := os.MkdirAll(l.dir(), 0744)
+	err := os.MkdirAll(l.dir(), l.dirMode())
 	if err != nil {
-		return fmt.Errorf("can't make directories for new logfile: %s", err)
+		return fmt.Errorf("can't make directories for new logfile: %w", err)
 	}
 
 	name := l.filename()
-	mode := os.FileMode(0644)
+	mode := l.fileMode()
 	info, err := os_Stat(name)
 	if err == nil {
 		// Copy the mode off the old logfile.
@@ -249,6 +303,7 @@ func (l *Logger) openNew() error {
 		if err := os.Rename(name, newname); err != nil {
 			return fmt.Errorf("can't rename log file: %s", err)
 		}
+		l.metrics.rotations.Add(1)
 
 		// this is a no-op anywhere but linux
 		if err := chown(name, info); err != nil {
@@ -261,7 +316,7 @@ func (l *Logger) openNew() error {
 	// just wipe out the contents.
 	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
 	if err != nil {
-		return fmt.Errorf("can't open new logfile: %s", err)
+		return fmt.Errorf("can't open new logfile: %w", err)
 	}
 	l.file = f
 	l.size = 0
@@ -304,7 +359,7 @@ func (l *Logger) openExistingOrNew(writeLen int) error {
 		return l.rotate()
 	}
 
-	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, l.fileMode())
 	if err != nil {
 		// if we fail to open the old log file for some reason, just ignore
 		// it and open a new log file.
@@ -329,14 +384,16 @@ func (l *Logger) filename() string {
 // files are removed, keeping at most l.MaxBackups files, as long as
 // none of them are older than MaxAge.
 func (l *Logger) millRunOnce() error {
-	if l.MaxBackups == 0 && l.MaxAge == 0 && !l.Compress {
-		return nil
-	}
-
 	files, err := l.oldLogFiles()
 	if err != nil {
 		return err
 	}
+	defer func() {
+		l.metrics.backups.Store(int64(len(files)))
+	}()
+	if l.MaxBackups == 0 && l.MaxAge == 0 && !l.Compress {
+		return nil
+	}
 
 	var compress, remove []logInfo
 
@@ -376,8 +433,9 @@ func (l *Logger) millRunOnce() error {
 	}
 
 	if l.Compress {
-		for _, f := range files {
-			if !strings.HasSuffix(f.Name(), compressSuffix) {
+		// files are sorted newest first, leave the CompressDelay newest plain
+		for i, f := range files {
+			if i >= l.CompressDelay && !strings.HasSuffix(f.Name(), compressSuffix) {
 				compress = append(compress, f)
 			}
 		}
@@ -389,13 +447,52 @@ func (l *Logger) millRunOnce() error {
 			err = errRemove
 		}
 	}
-	for _, f := range compress {
-		fn := filepath.Join(l.dir(), f.Name())
-		errCompress := compressLogFile(fn, fn+compressSuffix)
-		if err == nil && errCompress != nil {
-			err = errCompress
-		}
+	if errCompress := l.compressAll(compress); err == nil && errCompress != nil {
+		err = errCompress
+	}
+
+	return err
+}
+
+// compressAll compresses files with at most CompressConcurrency workers,
+// returning the first error met.
+func (l *Logger) compressAll(files []logInfo) error {
+	workers := l.CompressConcurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	workers = min(workers, len(files))
+
+	var (
+		wg    sync.WaitGroup
+		errMu sync.Mutex
+		err   error
+		queue = make(chan logInfo)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range queue {
+				fn := filepath.Join(l.dir(), f.Name())
+				start := time.Now()
+				errCompress := compressLogFile(fn, fn+compressSuffix)
+				l.metrics.compressed(time.Since(start))
+				if errCompress != nil {
+					errMu.Lock()
+					if err == nil {
+						err = errCompress
+					}
+					errMu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, f := range files {
+		queue <- f
 	}
+	close(queue)
+	wg.Wait()
 
 	return err
 }
@@ -410,10 +507,22 @@ func (l *Logger) millRun() {
 			case true:
 				// what am I going to do, log this?
 				_ = l.millRunOnce()
+				l.millPending.Done()
 			case false:
 				return
 			}
 		case <-l.Ctx.Done():
+			l.millMu.Lock()
+			l.millStopped = true
+			l.millMu.Unlock()
+
+			// drain the queued request, don't leave uncompressed backups
+			select {
+			case <-l.millCh:
+				_ = l.millRunOnce()
+				l.millPending.Done()
+			default:
+			}
 			return
 		}
 	}
@@ -429,9 +538,18 @@ func (l *Logger) mill() {
 		l.millCh = make(chan bool, 1)
 		go l.millRun()
 	})
+
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+	if l.millStopped {
+		return
+	}
+	l.millPending.Add(1)
 	select {
 	case l.millCh <- true:
 	default:
+		// a request is queued already, it covers this one
+		l.millPending.Done()
 	}
 }
 
@@ -489,6 +607,20 @@ func (l *Logger) max() int64 {
 	return int64(l.MaxSize) * int64(megabyte)
 }
 
+func (l *Logger) dirMode() os.FileMode {
+	if l.DirMode == 0 {
+		return 0755
+	}
+	return l.DirMode
+}
+
+func (l *Logger) fileMode() os.FileMode {
+	if l.FileMode == 0 {
+		return 0644
+	}
+	return l.FileMode
+}
+
 // dir returns the directory for the current filename.
 func (l *Logger) dir() string {
 	return filepath.Dir(l.filename())
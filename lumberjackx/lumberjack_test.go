@@ -0,0 +1,308 @@
+package lumberjackx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCurrentTime is the fake "current time" used by the tests, moved forward
+// by newFakeTime.
+var fakeCurrentTime = time.Now()
+
+func fakeTime() time.Time {
+	return fakeCurrentTime
+}
+
+// newFakeTime sets the fake "current time" to two days later.
+func newFakeTime() {
+	fakeCurrentTime = fakeCurrentTime.Add(time.Hour * 24 * 2)
+}
+
+// makeTempDir creates a directory with a semi-unique name in the OS temp
+// directory. It should be based on the name of the test, to keep parallel
+// tests from colliding, and must be cleaned up after the test is finished.
+func makeTempDir(name string, t testing.TB) string {
+	dir := time.Now().Format(name + backupTimeFormat)
+	dir = filepath.Join(os.TempDir(), dir)
+	isNilUp(os.Mkdir(dir, 0700), t, 1)
+	return dir
+}
+
+// logFile returns the log file name in the given directory for the current fake time.
+func logFile(dir string) string {
+	return filepath.Join(dir, "foobar.log")
+}
+
+func backupFile(dir string) string {
+	return filepath.Join(dir, "foobar-"+fakeTime().UTC().Format(backupTimeFormat)+".log")
+}
+
+// existsWithContent checks that the given file exists and has the correct content.
+func existsWithContent(path string, content []byte, t testing.TB) {
+	info, err := os.Stat(path)
+	isNilUp(err, t, 1)
+	equalsUp(int64(len(content)), info.Size(), t, 1)
+
+	b, err := os.ReadFile(path)
+	isNilUp(err, t, 1)
+	equalsUp(content, b, t, 1)
+}
+
+// notExist checks that the given file does not exist.
+func notExist(path string, t testing.TB) {
+	_, err := os.Stat(path)
+	assertUp(os.IsNotExist(err), t, 1, "expected to get os.IsNotExist, but instead got %v", err)
+}
+
+// countSuffix returns the number of files in dir with the given suffix.
+func countSuffix(dir, suffix string, t testing.TB) int {
+	files, err := os.ReadDir(dir)
+	isNilUp(err, t, 1)
+	n := 0
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), suffix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCompressManyRotations(t *testing.T) {
+	currentTime = time.Now
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressManyRotations", t)
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l := &Logger{
+		Ctx:                 ctx,
+		Filename:            logFile(dir),
+		MaxSize:             10,
+		Compress:            true,
+		CompressConcurrency: 3,
+	}
+
+	const rotations = 20
+	for i := 0; i < rotations; i++ {
+		_, err := l.Write([]byte(fmt.Sprintf("line %04d\n", i)))
+		isNil(err, t)
+		// backup names carry milliseconds, keep them unique
+		time.Sleep(2 * time.Millisecond)
+	}
+	isNil(l.Close(), t)
+
+	equals(rotations-1, countSuffix(dir, compressSuffix, t), t)
+	equals(1, countSuffix(dir, ".log", t), t)
+}
+
+func TestCreateDirAndModes(t *testing.T) {
+	currentTime = time.Now
+	megabyte = 1
+
+	dir := makeTempDir("TestCreateDirAndModes", t)
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logDir := filepath.Join(dir, "a", "b")
+	l := &Logger{
+		Ctx:      ctx,
+		Filename: logFile(logDir),
+		MaxSize:  10,
+		Compress: true,
+		DirMode:  0700,
+		FileMode: 0600,
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := l.Write([]byte(fmt.Sprintf("line %04d\n", i)))
+		isNil(err, t)
+		time.Sleep(2 * time.Millisecond)
+	}
+	isNil(l.Close(), t)
+
+	info, err := os.Stat(logDir)
+	isNil(err, t)
+	equals(os.FileMode(0700), info.Mode().Perm(), t)
+
+	info, err = os.Stat(logFile(logDir))
+	isNil(err, t)
+	equals(os.FileMode(0600), info.Mode().Perm(), t)
+
+	equals(2, countSuffix(logDir, compressSuffix, t), t)
+}
+
+func TestCreateDirPermissionError(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root ignores directory permissions")
+	}
+
+	dir := makeTempDir("TestCreateDirPermissionError", t)
+	defer os.RemoveAll(dir)
+	isNil(os.Chmod(dir, 0500), t)
+	defer os.Chmod(dir, 0700)
+
+	l := &Logger{
+		Ctx:      context.Background(),
+		Filename: logFile(filepath.Join(dir, "sub")),
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	assert(errors.Is(err, os.ErrPermission), t, "expected a permission error, got %v", err)
+	assert(strings.Contains(err.Error(), "can't make directories"), t, "unexpected error %v", err)
+}
+
+func TestCompressDelay(t *testing.T) {
+	currentTime = time.Now
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressDelay", t)
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l := &Logger{
+		Ctx:           ctx,
+		Filename:      logFile(dir),
+		MaxSize:       10,
+		MaxBackups:    4,
+		Compress:      true,
+		CompressDelay: 2,
+	}
+
+	for i := 0; i < 8; i++ {
+		_, err := l.Write([]byte(fmt.Sprintf("line %04d\n", i)))
+		isNil(err, t)
+		time.Sleep(2 * time.Millisecond)
+	}
+	isNil(l.Close(), t)
+
+	// 4 backups kept, the 2 newest plain, plus the current file
+	equals(2, countSuffix(dir, compressSuffix, t), t)
+	equals(3, countSuffix(dir, ".log", t), t)
+
+	files, err := l.oldLogFiles()
+	isNil(err, t)
+	equals(4, len(files), t)
+	for i, f := range files {
+		equals(i >= 2, strings.HasSuffix(f.Name(), compressSuffix), t)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	currentTime = time.Now
+	megabyte = 1
+
+	dir := makeTempDir("TestMetrics", t)
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l := &Logger{
+		Ctx:        ctx,
+		Filename:   logFile(dir),
+		MaxSize:    10,
+		MaxBackups: 3,
+		Compress:   true,
+	}
+
+	const writes = 6
+	for i := 0; i < writes; i++ {
+		_, err := l.Write([]byte(fmt.Sprintf("line %04d\n", i)))
+		isNil(err, t)
+		time.Sleep(2 * time.Millisecond)
+	}
+	isNil(l.Close(), t)
+
+	m := l.Metrics()
+	equals(uint64(writes-1), m.Rotations, t)
+	equals(uint64(writes*10), m.BytesWritten, t)
+	equals(uint64(writes-1), m.Compressions, t)
+	assert(m.CompressTime >= m.LastCompressTime && m.LastCompressTime > 0, t,
+		"unexpected compress times %v, %v", m.CompressTime, m.LastCompressTime)
+	equals(int64(3), m.Backups, t)
+}
+
+func TestFraming(t *testing.T) {
+	currentTime = time.Now
+	megabyte = 1
+
+	for _, tc := range []struct {
+		framing Framing
+		want    string
+	}{
+		{FramingNone, "a\nb\nc"},
+		{FramingNewline, "a\nb\nc\n"},
+		{FramingLengthPrefix, "\x00\x00\x00\x02a\n\x00\x00\x00\x03b\nc"},
+	} {
+		dir := makeTempDir("TestFraming"+string(tc.framing), t)
+
+		l := &Logger{Ctx: context.Background(), Filename: logFile(dir), MaxSize: 100, Framing: tc.framing}
+		n, err := l.Write([]byte("a\n"))
+		isNil(err, t)
+		equals(2, n, t)
+		n, err = l.Write([]byte("b\nc"))
+		isNil(err, t)
+		equals(3, n, t)
+		isNil(l.Close(), t)
+
+		existsWithContent(logFile(dir), []byte(tc.want), t)
+		os.RemoveAll(dir)
+	}
+
+	_, err := (&Logger{Framing: "csv"}).Write([]byte("a"))
+	notNil(err, t)
+}
+
+func TestFramedWritten(t *testing.T) {
+	p := []byte("abc")
+	for _, tc := range []struct {
+		framing Framing
+		n, want int
+	}{
+		{FramingNone, 3, 3},
+		{FramingNone, 2, 2},
+		{FramingNewline, 4, 3},
+		// the record lost only its newline, it is still short
+		{FramingNewline, 3, 2},
+		{FramingNewline, 1, 1},
+		{FramingLengthPrefix, 7, 3},
+		{FramingLengthPrefix, 6, 2},
+		{FramingLengthPrefix, 2, 0},
+	} {
+		record, offset, err := tc.framing.frame(p)
+		isNil(err, t)
+		equals(tc.want, framedWritten(tc.n, offset, p, record), t)
+	}
+}
+
+func TestSync(t *testing.T) {
+	currentTime = fakeTime
+
+	dir := makeTempDir("TestSync", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Ctx: context.Background(), Filename: logFile(dir)}
+	defer l.Close()
+	// nothing open yet
+	isNil(l.Sync(), t)
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	isNil(l.Sync(), t)
+	existsWithContent(logFile(dir), b, t)
+}
@@ -0,0 +1,50 @@
+package lumberjackx
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LoggerMetrics is a snapshot of the counters of a Logger.
+type LoggerMetrics struct {
+	// Rotations counts the log files moved aside as backups.
+	Rotations uint64
+	// BytesWritten counts the bytes written to log files.
+	BytesWritten uint64
+	// Compressions counts the backups compressed, successfully or not.
+	Compressions uint64
+	// CompressTime is the total time spent compressing, LastCompressTime the
+	// time of the latest compression.
+	CompressTime     time.Duration
+	LastCompressTime time.Duration
+	// Backups is the number of backups found by the latest cleanup run.
+	Backups int64
+}
+
+type loggerMetrics struct {
+	rotations        atomic.Uint64
+	bytesWritten     atomic.Uint64
+	compressions     atomic.Uint64
+	compressTime     atomic.Int64
+	lastCompressTime atomic.Int64
+	backups          atomic.Int64
+}
+
+func (m *loggerMetrics) compressed(d time.Duration) {
+	m.compressions.Add(1)
+	m.compressTime.Add(int64(d))
+	m.lastCompressTime.Store(int64(d))
+}
+
+// Metrics returns the current counters, cheap enough to be polled by a
+// metrics exporter.
+func (l *Logger) Metrics() LoggerMetrics {
+	return LoggerMetrics{
+		Rotations:        l.metrics.rotations.Load(),
+		BytesWritten:     l.metrics.bytesWritten.Load(),
+		Compressions:     l.metrics.compressions.Load(),
+		CompressTime:     time.Duration(l.metrics.compressTime.Load()),
+		LastCompressTime: time.Duration(l.metrics.lastCompressTime.Load()),
+		Backups:          l.metrics.backups.Load(),
+	}
+}
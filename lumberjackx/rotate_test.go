@@ -8,11 +8,13 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+
+	"github.com/madlabx/pkgx/lumberjackx"
 )
 
 // Example of how to rotate in response to SIGHUP.
 func ExampleLogger_Rotate() {
-	l := &lumberjack.Logger{}
+	l := &lumberjackx.Logger{}
 	log.SetOutput(l)
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGHUP)
@@ -0,0 +1,54 @@
+package viperx
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// cachedValue is a value read at a config generation
+type cachedValue[T any] struct {
+	generation uint64
+	val        T
+}
+
+// newCached returns get with its result cached until the next config load
+func newCached[T any](get func() T) func() T {
+	var cur atomic.Pointer[cachedValue[T]]
+	return func() T {
+		// the generation is taken before reading, a load in between makes the
+		// next call read again
+		gen := vx.generation.Load()
+		if cv := cur.Load(); cv != nil && cv.generation == gen {
+			return cv.val
+		}
+		cv := &cachedValue[T]{generation: gen, val: get()}
+		cur.Store(cv)
+		return cv.val
+	}
+}
+
+// NewCachedString returns a getter of GetString(name, def) for hot paths,
+// e.g. a feature flag read by every request. The value is cached until the
+// config is loaded again by ReadInConfig, ReadFrom, LoadAndMerge, Reload or
+// WatchConfig, a call costs an atomic load then. Values set with viper.Set,
+// flags or env changes are not noticed until the next load.
+//
+// The getter is safe for concurrent use.
+func NewCachedString(name string, def string) func() string {
+	return newCached(func() string { return GetString(name, def) })
+}
+
+// NewCachedInt is NewCachedString for GetInt.
+func NewCachedInt(name string, def int) func() int {
+	return newCached(func() int { return GetInt(name, def) })
+}
+
+// NewCachedBool is NewCachedString for GetBool.
+func NewCachedBool(name string, def bool) func() bool {
+	return newCached(func() bool { return GetBool(name, def) })
+}
+
+// NewCachedDuration is NewCachedString for GetDuration.
+func NewCachedDuration(name string, def time.Duration) func() time.Duration {
+	return newCached(func() time.Duration { return GetDuration(name, def) })
+}
@@ -0,0 +1,49 @@
+package viperx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedGetters(t *testing.T) {
+	require.NoError(t, ReadBytes([]byte("cachetest:\n  mode: fast\n  workers: 4\n  beta: true\n  ttl: 1m\n"), "yaml"))
+
+	mode := NewCachedString("cachetest.mode", "safe")
+	workers := NewCachedInt("cachetest.workers", 1)
+	beta := NewCachedBool("cachetest.beta", false)
+	ttl := NewCachedDuration("cachetest.ttl", time.Second)
+	missing := NewCachedString("cachetest.missing", "def")
+
+	assert.Equal(t, "fast", mode())
+	assert.Equal(t, 4, workers())
+	assert.True(t, beta())
+	assert.Equal(t, time.Minute, ttl())
+	assert.Equal(t, "def", missing())
+
+	require.NoError(t, ReadBytes([]byte("cachetest:\n  mode: slow\n  workers: 8\n"), "yaml"))
+	assert.Equal(t, "slow", mode())
+	assert.Equal(t, 8, workers())
+	assert.False(t, beta())
+	assert.Equal(t, time.Second, ttl())
+}
+
+// BenchmarkGetString and BenchmarkCachedString compare a per-request read,
+// GetString takes the lock and walks viper's maps while the cached getter
+// only checks the generation.
+func BenchmarkGetString(b *testing.B) {
+	_ = ReadBytes([]byte("cachetest:\n  mode: fast\n"), "yaml")
+	for i := 0; i < b.N; i++ {
+		_ = GetString("cachetest.mode", "safe")
+	}
+}
+
+func BenchmarkCachedString(b *testing.B) {
+	_ = ReadBytes([]byte("cachetest:\n  mode: fast\n"), "yaml")
+	mode := NewCachedString("cachetest.mode", "safe")
+	for i := 0; i < b.N; i++ {
+		_ = mode()
+	}
+}
@@ -0,0 +1,89 @@
+package viperx
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const encryptedPrefix = "enc:"
+
+// Decryptor returns the plaintext of ciphertext, the value of a key after
+// its "enc:" prefix, e.g. by a call to a KMS, see SetDecryptor.
+type Decryptor func(ciphertext string) (string, error)
+
+// decryption decrypts the enc: values for the getters, caching the
+// plaintexts until the next config load
+type decryption struct {
+	decryptor  Decryptor
+	generation *atomic.Uint64
+
+	mu    sync.Mutex
+	gen   uint64
+	plain map[string]string
+}
+
+// SetDecryptor registers d to decrypt the string values prefixed with "enc:",
+// for the secrets to stay encrypted at rest in the config file:
+//
+//	db.password: "enc:AQICAHh..."  # ciphertext of a KMS, SOPS...
+//
+//	viperx.SetDecryptor(func(ciphertext string) (string, error) {
+//		return kmsDecrypt(ctx, ciphertext)
+//	})
+//	pass, err := viperx.GetSecret("db.password")
+//
+// The value is decrypted on read by GetString, GetEnum and GetSecret, those
+// of the snapshots included, and the plaintext cached until the config is
+// loaded again, e.g. by Reload, not to call d on every read. The ciphertext is
+// never returned: GetSecret fails if d does or if no decryptor is set, the
+// getters without an error return their default then. d is called with the
+// config locked for reading, it must not call the getters. nil unregisters
+// it.
+func (o *ViperX) SetDecryptor(d Decryptor) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if d == nil {
+		o.decryption = nil
+		return
+	}
+	o.decryption = &decryption{decryptor: d, generation: &o.generation}
+}
+
+// decrypt returns the plaintext of val, the enc: value of name
+func (d *decryption) decrypt(name, val string) (string, error) {
+	if d == nil {
+		return "", fmt.Errorf("%s is encrypted, no decryptor set", name)
+	}
+	ciphertext := strings.TrimPrefix(val, encryptedPrefix)
+
+	gen := d.generation.Load()
+	d.mu.Lock()
+	if d.gen != gen || d.plain == nil {
+		// a load may have changed the ciphertexts or their keys
+		d.gen, d.plain = gen, make(map[string]string)
+	}
+	plain, ok := d.plain[ciphertext]
+	d.mu.Unlock()
+	if ok {
+		return plain, nil
+	}
+
+	plain, err := d.decryptor(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", name, err)
+	}
+	d.mu.Lock()
+	if d.gen == gen {
+		d.plain[ciphertext] = plain
+	}
+	d.mu.Unlock()
+	return plain, nil
+}
+
+// SetDecryptor registers d to decrypt the "enc:" values, see
+// ViperX.SetDecryptor.
+func SetDecryptor(d Decryptor) {
+	vx.SetDecryptor(d)
+}
@@ -0,0 +1,79 @@
+package viperx
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetDecryptor(t *testing.T) {
+	o := &ViperX{v: viper.New()}
+	o.v.Set("db.password", "enc:terces")
+	o.v.Set("db.level", "enc:OFNI")
+	o.v.Set("db.broken", "enc:!")
+	o.v.Set("db.host", "localhost")
+
+	// no decryptor, the ciphertext is not returned
+	assert.Equal(t, "def", o.Snapshot().GetString("db.password", "def"))
+
+	var calls atomic.Int32
+	o.SetDecryptor(func(ciphertext string) (string, error) {
+		calls.Add(1)
+		if ciphertext == "!" {
+			return "", errors.New("bad ciphertext")
+		}
+		r := []rune(ciphertext)
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return strings.ToLower(string(r)), nil
+	})
+
+	cv := o.Snapshot()
+	assert.Equal(t, "secret", cv.GetString("db.password", ""))
+	assert.Equal(t, "secret", cv.GetString("db.password", ""))
+	assert.EqualValues(t, 1, calls.Load(), "cached")
+	level, err := cv.GetEnum("db.level", []string{"debug", "info"}, "debug")
+	require.NoError(t, err)
+	assert.Equal(t, "info", level)
+	assert.Equal(t, "localhost", cv.GetString("db.host", ""))
+	assert.Equal(t, "def", cv.GetString("db.broken", "def"))
+
+	// a load drops the cache
+	calls.Store(0)
+	o.generation.Add(1)
+	assert.Equal(t, "secret", o.Snapshot().GetString("db.password", ""))
+	assert.EqualValues(t, 1, calls.Load())
+
+	o.SetDecryptor(nil)
+	assert.Equal(t, "", o.Snapshot().GetString("db.password", ""))
+}
+
+func TestGetSecretEncrypted(t *testing.T) {
+	viper.Set("secrettest.enc", "enc:abc")
+	_, err := GetSecret("secrettest.enc")
+	assert.ErrorContains(t, err, "no decryptor")
+
+	SetDecryptor(func(ciphertext string) (string, error) {
+		if ciphertext != "abc" {
+			return "", errors.New("bad ciphertext")
+		}
+		return "plain", nil
+	})
+	t.Cleanup(func() { SetDecryptor(nil) })
+
+	got, err := GetSecret("secrettest.enc")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", got)
+	assert.Equal(t, "plain", GetString("secrettest.enc", ""))
+
+	viper.Set("secrettest.enc", "enc:xyz")
+	_, err = GetSecret("secrettest.enc")
+	assert.ErrorContains(t, err, "failed to decrypt secrettest.enc: bad ciphertext")
+	assert.Equal(t, "def", GetString("secrettest.enc", "def"))
+}
@@ -0,0 +1,162 @@
+package viperx
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tagDefault is the default tag read by SetDefaultsFromStruct besides
+// vx_default
+const tagDefault = "default"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// SetDefaultsFromStruct registers the defaults of the fields of v, a struct or
+// a pointer to one, from their default or vx_default tags, under the dotted
+// key of each field below prefix, for the defaults to stay next to the fields
+// which use them:
+//
+//	type Config struct {
+//		Server struct {
+//			Port    int           `default:"8080"`
+//			Timeout time.Duration `default:"30s"`
+//		}
+//		Tags []string `default:"a,b"`
+//	}
+//
+//	err := viperx.SetDefaultsFromStruct("app", &Config{})
+//
+// registers app.server.port 8080, app.server.timeout 30s and app.tags [a b],
+// which the getters and Unmarshal then return where the config sets nothing.
+// The keys follow the mapstructure tags like BindAllFlags, nested structs are
+// walked, squashed ones without a level. A default is
+// parsed into the type of its field, string, bool, the integers and floats,
+// time.Duration and []string separated by commas, the other types are
+// registered as the tag string for viper to cast on read. A field without a
+// tag registers nothing, a default which does not parse fails. A struct
+// reached again below itself, e.g. a linked Node, is not walked again.
+func (o *ViperX) SetDefaultsFromStruct(prefix string, v interface{}) error {
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return fmt.Errorf("invalid defaults, should be a struct, got %T", v)
+	}
+
+	var parts []string
+	if prefix != "" {
+		parts = []string{prefix}
+	}
+	defaults := map[string]interface{}{}
+	if err := collectDefaults(defaults, rt, defaultMapStructureTagName, map[reflect.Type]bool{}, parts...); err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for key, val := range defaults {
+		o.v.SetDefault(key, val)
+	}
+	return nil
+}
+
+// collectDefaults adds the defaults of the fields of rt to defaults, visiting
+// are the struct types being walked, one met again, e.g. the Next *Node of a
+// Node, is skipped not to recurse forever
+func collectDefaults(defaults map[string]interface{}, rt reflect.Type, tagName string, visiting map[reflect.Type]bool, parts ...string) error {
+	visiting[rt] = true
+	defer delete(visiting, rt)
+	for i := 0; i < rt.NumField(); i++ {
+		t := rt.Field(i)
+		if !t.IsExported() {
+			continue
+		}
+		fieldName := parseTypeName(t, tagName)
+		ft := t.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}) {
+			if visiting[ft] {
+				continue
+			}
+			next := parts
+			if len(fieldName) > 0 {
+				next = append(parts[:len(parts):len(parts)], fieldName)
+			}
+			if err := collectDefaults(defaults, ft, tagName, visiting, next...); err != nil {
+				return err
+			}
+			continue
+		}
+
+		def, ok := t.Tag.Lookup(tagDefault)
+		if !ok {
+			if def, ok = t.Tag.Lookup(tagViperXFieldDefault); !ok {
+				continue
+			}
+		}
+		key := strings.Join(append(parts[:len(parts):len(parts)], fieldName), ".")
+		val, err := parseDefault(def, ft)
+		if err != nil {
+			return fmt.Errorf("invalid default of %s: %w", key, err)
+		}
+		defaults[key] = val
+	}
+	return nil
+}
+
+// parseDefault parses def into a value of type rt, def itself for the types
+// not handled
+func parseDefault(def string, rt reflect.Type) (interface{}, error) {
+	if rt == durationType {
+		return time.ParseDuration(strings.TrimSpace(def))
+	}
+	switch rt.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(strings.TrimSpace(def))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(def), 0, rt.Bits())
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ValueOf(n).Convert(rt).Interface(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(def), 0, rt.Bits())
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ValueOf(n).Convert(rt).Interface(), nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(def), rt.Bits())
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ValueOf(f).Convert(rt).Interface(), nil
+	case reflect.Slice:
+		if rt.Elem().Kind() != reflect.String {
+			return def, nil
+		}
+		if def == "" {
+			return []string{}, nil
+		}
+		items := strings.Split(def, ",")
+		for i := range items {
+			items[i] = strings.TrimSpace(items[i])
+		}
+		return items, nil
+	default:
+		return def, nil
+	}
+}
+
+// SetDefaultsFromStruct registers the defaults of the fields of v from their
+// tags, see ViperX.SetDefaultsFromStruct.
+func SetDefaultsFromStruct(prefix string, v interface{}) error {
+	return vx.SetDefaultsFromStruct(prefix, v)
+}
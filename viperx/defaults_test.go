@@ -0,0 +1,82 @@
+package viperx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type defaultsTestConfig struct {
+	Name   string `default:"svc"`
+	Server struct {
+		Port    int           `default:"8080"`
+		Timeout time.Duration `default:"30s"`
+		TLS     bool          `mapstructure:"tls" default:"true"`
+		Ratio   float64       `default:"0.5"`
+	}
+	Limits *struct {
+		Burst uint `vx_default:"10"`
+	}
+	Common struct {
+		Region string `default:"eu"`
+	} `mapstructure:",squash"`
+	Tags    []string `default:"a, b"`
+	NoTag   string
+	private string `default:"x"`
+}
+
+func TestSetDefaultsFromStruct(t *testing.T) {
+	o := &ViperX{v: viper.New()}
+	require.NoError(t, o.SetDefaultsFromStruct("app", &defaultsTestConfig{}))
+	o.v.SetConfigType("yaml")
+	require.NoError(t, o.v.ReadConfig(strings.NewReader("app:\n  server:\n    port: 9090\n")))
+
+	assert.Equal(t, "svc", o.v.Get("app.name"))
+	assert.Equal(t, 9090, o.v.GetInt("app.server.port"), "the config wins")
+	assert.Equal(t, 30*time.Second, o.v.Get("app.server.timeout"))
+	assert.Equal(t, true, o.v.Get("app.server.tls"))
+	assert.Equal(t, 0.5, o.v.Get("app.server.ratio"))
+	assert.Equal(t, uint(10), o.v.Get("app.limits.burst"))
+	assert.Equal(t, "eu", o.v.Get("app.region"))
+	assert.Equal(t, []string{"a", "b"}, o.v.Get("app.tags"))
+	assert.False(t, o.v.IsSet("app.notag"))
+	assert.False(t, o.v.IsSet("app.private"))
+
+	var cfg struct{ App defaultsTestConfig }
+	require.NoError(t, o.v.Unmarshal(&cfg))
+	assert.Equal(t, "svc", cfg.App.Name)
+	assert.Equal(t, 9090, cfg.App.Server.Port)
+	assert.Equal(t, 30*time.Second, cfg.App.Server.Timeout)
+	assert.Equal(t, []string{"a", "b"}, cfg.App.Tags)
+}
+
+func TestSetDefaultsFromStructInvalid(t *testing.T) {
+	o := &ViperX{v: viper.New()}
+	assert.Error(t, o.SetDefaultsFromStruct("", 1))
+	err := o.SetDefaultsFromStruct("", &struct {
+		Port int `default:"http"`
+	}{})
+	assert.ErrorContains(t, err, "invalid default of Port")
+}
+
+type defaultsTestNode struct {
+	Name     string `default:"root"`
+	Next     *defaultsTestNode
+	Children []defaultsTestNode
+	Meta     struct {
+		Parent *defaultsTestNode
+		Depth  int `default:"1"`
+	}
+}
+
+func TestSetDefaultsFromStructRecursive(t *testing.T) {
+	o := &ViperX{v: viper.New()}
+	require.NoError(t, o.SetDefaultsFromStruct("tree", &defaultsTestNode{}))
+	assert.Equal(t, "root", o.v.Get("tree.name"))
+	assert.Equal(t, 1, o.v.Get("tree.meta.depth"))
+	assert.False(t, o.v.IsSet("tree.next.name"))
+}
@@ -0,0 +1,97 @@
+package viperx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KeyDoc documents a config key, see Describe.
+type KeyDoc struct {
+	Key         string
+	Type        string
+	Description string
+	Default     interface{}
+}
+
+// Describe registers the metadata of key, e.g.
+//
+//	viperx.Describe("db.pool_size", "int", "connections kept open", 10)
+//
+// which Docs and DocsMarkdown list, e.g. for an ops runbook. It is metadata
+// only, def is not set as viper default, and a key described again is
+// replaced. Describing keys is optional, DescribedKeys feeds ValidateKeys so
+// the described keys are the allowed ones.
+func (o *ViperX) Describe(key, typ, description string, def interface{}) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	key = strings.ToLower(key)
+	if o.keyDocs == nil {
+		o.keyDocs = map[string]KeyDoc{}
+	}
+	o.keyDocs[key] = KeyDoc{Key: key, Type: typ, Description: description, Default: def}
+}
+
+// Docs returns the described keys, sorted by key.
+func (o *ViperX) Docs() []KeyDoc {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	docs := make([]KeyDoc, 0, len(o.keyDocs))
+	for _, d := range o.keyDocs {
+		docs = append(docs, d)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Key < docs[j].Key })
+	return docs
+}
+
+// DescribedKeys returns the described keys, sorted, e.g.
+// ValidateKeys(DescribedKeys()) rejects the keys nobody described.
+func (o *ViperX) DescribedKeys() []string {
+	docs := o.Docs()
+	keys := make([]string, len(docs))
+	for i, d := range docs {
+		keys[i] = d.Key
+	}
+	return keys
+}
+
+// DocsMarkdown renders Docs as a markdown table of key, type, default and
+// description.
+func (o *ViperX) DocsMarkdown() string {
+	var b strings.Builder
+	b.WriteString("| Key | Type | Default | Description |\n")
+	b.WriteString("|-----|------|---------|-------------|\n")
+	for _, d := range o.Docs() {
+		def := ""
+		if d.Default != nil {
+			def = "`" + fmt.Sprint(d.Default) + "`"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n",
+			d.Key, escapeMarkdownCell(d.Type), escapeMarkdownCell(def), escapeMarkdownCell(d.Description))
+	}
+	return b.String()
+}
+
+func escapeMarkdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
+
+// Describe registers the metadata of key, see ViperX.Describe.
+func Describe(key, typ, description string, def interface{}) {
+	vx.Describe(key, typ, description, def)
+}
+
+// Docs returns the described keys, see ViperX.Docs.
+func Docs() []KeyDoc {
+	return vx.Docs()
+}
+
+// DescribedKeys returns the described keys, see ViperX.DescribedKeys.
+func DescribedKeys() []string {
+	return vx.DescribedKeys()
+}
+
+// DocsMarkdown renders the described keys, see ViperX.DocsMarkdown.
+func DocsMarkdown() string {
+	return vx.DocsMarkdown()
+}
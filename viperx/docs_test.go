@@ -0,0 +1,33 @@
+package viperx
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	o := &ViperX{v: viper.New()}
+	o.Describe("db.Pool_Size", "int", "connections kept open", 10)
+	o.Describe("db.dsn", "string", "data source, e.g. host=a|b", nil)
+	o.Describe("log.level", "string", "one of debug, info", "info")
+	o.Describe("log.level", "string", "one of debug, info, warn", "info")
+
+	docs := o.Docs()
+	require.Len(t, docs, 3)
+	assert.Equal(t, KeyDoc{Key: "db.dsn", Type: "string", Description: "data source, e.g. host=a|b"}, docs[0])
+	assert.Equal(t, "db.pool_size", docs[1].Key)
+	assert.Equal(t, "one of debug, info, warn", docs[2].Description)
+
+	assert.Equal(t, "| Key | Type | Default | Description |\n"+
+		"|-----|------|---------|-------------|\n"+
+		"| `db.dsn` | string |  | data source, e.g. host=a\\|b |\n"+
+		"| `db.pool_size` | int | `10` | connections kept open |\n"+
+		"| `log.level` | string | `info` | one of debug, info, warn |\n", o.DocsMarkdown())
+
+	o.v.Set("db.pool_size", 20)
+	o.v.Set("db.timeout", "1s")
+	assert.EqualError(t, o.ValidateKeys(o.DescribedKeys()), "unknown configure items:\n  db.timeout\n")
+}
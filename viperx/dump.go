@@ -0,0 +1,178 @@
+package viperx
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Redacted replaces the value of secret keys in Dump.
+const Redacted = "******"
+
+// DefaultSecretKeys are redacted by Dump unless SetSecretKeys is called.
+var DefaultSecretKeys = []string{"password", "secret", "token"}
+
+// ChangeKind tells how a key differs between two configs.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is a key which differs between two configs, Old is nil for an added
+// key and New is nil for a removed one.
+type Change struct {
+	Key  string
+	Kind ChangeKind
+	Old  interface{}
+	New  interface{}
+}
+
+// Diff compares two configs, e.g. the Dump before and after a Reload, and
+// returns the differing keys sorted. Nested maps are compared key by key under
+// dotted paths, other values as a whole.
+func Diff(old, new map[string]interface{}) []Change {
+	oldFlat, newFlat := flatten(old), flatten(new)
+
+	var changes []Change
+	for k, ov := range oldFlat {
+		nv, ok := newFlat[k]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Key: k, Kind: ChangeRemoved, Old: ov})
+		case !reflect.DeepEqual(ov, nv):
+			changes = append(changes, Change{Key: k, Kind: ChangeChanged, Old: ov, New: nv})
+		}
+	}
+	for k, nv := range newFlat {
+		if _, ok := oldFlat[k]; !ok {
+			changes = append(changes, Change{Key: k, Kind: ChangeAdded, New: nv})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+func flatten(m map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			key := strings.ToLower(prefix + k)
+			if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+				walk(key+".", sub)
+				continue
+			}
+			flat[key] = v
+		}
+	}
+	walk("", m)
+	return flat
+}
+
+// SetSecretKeys replaces the keys redacted by Dump. An entry is a dotted key,
+// e.g. "db.password", a section with the ".*" suffix, e.g. "vault.*", or a
+// bare name matching the last part of any key, e.g. "token".
+// Matching is case-insensitive.
+func (o *ViperX) SetSecretKeys(keys ...string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.secretKeys = append([]string{}, keys...)
+}
+
+// Dump returns the effective config as nested maps, like viper.AllSettings,
+// with the values of secret keys replaced by Redacted.
+func (o *ViperX) Dump() map[string]interface{} {
+	settings, secrets := o.settings()
+	redact("", settings, secrets)
+	return settings
+}
+
+// settings returns the effective config unredacted, with the secret keys
+func (o *ViperX) settings() (map[string]interface{}, []string) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	secrets := o.secretKeys
+	if secrets == nil {
+		secrets = DefaultSecretKeys
+	}
+	return o.v.AllSettings(), secrets
+}
+
+// redactChanges replaces the values of the changes of secret keys, or of keys
+// below a secret section, by Redacted, as Dump would
+func redactChanges(changes []Change, secrets []string) {
+	for i, c := range changes {
+		if !isSecretPath(c.Key, secrets) {
+			continue
+		}
+		if c.Old != nil {
+			changes[i].Old = Redacted
+		}
+		if c.New != nil {
+			changes[i].New = Redacted
+		}
+	}
+}
+
+// isSecretPath reports whether key or one of its sections is secret
+func isSecretPath(key string, secrets []string) bool {
+	for {
+		if isSecretKey(key, secrets) {
+			return true
+		}
+		i := strings.LastIndex(key, ".")
+		if i < 0 {
+			return false
+		}
+		key = key[:i]
+	}
+}
+
+func redact(prefix string, m map[string]interface{}, secrets []string) {
+	for k, v := range m {
+		key := prefix + k
+		if isSecretKey(key, secrets) {
+			m[k] = Redacted
+			continue
+		}
+		if sub, ok := v.(map[string]interface{}); ok {
+			redact(key+".", sub, secrets)
+		}
+	}
+}
+
+func isSecretKey(key string, secrets []string) bool {
+	key = strings.ToLower(key)
+	last := key[strings.LastIndex(key, ".")+1:]
+	for _, s := range secrets {
+		s = strings.ToLower(s)
+		switch {
+		case strings.HasSuffix(s, keyWildcardSuffix):
+			if strings.HasPrefix(key, strings.TrimSuffix(s, "*")) {
+				return true
+			}
+		case strings.Contains(s, "."):
+			if key == s {
+				return true
+			}
+		case last == s:
+			return true
+		}
+	}
+	return false
+}
+
+// SetSecretKeys replaces the keys redacted by Dump, see ViperX.SetSecretKeys.
+func SetSecretKeys(keys ...string) {
+	vx.SetSecretKeys(keys...)
+}
+
+// Dump returns the effective config with secrets redacted, see ViperX.Dump.
+func Dump() map[string]interface{} {
+	return vx.Dump()
+}
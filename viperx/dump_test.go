@@ -0,0 +1,33 @@
+package viperx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpAndDiff(t *testing.T) {
+	o := &ViperX{v: viper.New()}
+	o.v.SetConfigType("yaml")
+	require.NoError(t, o.v.ReadConfig(strings.NewReader(
+		"db:\n  host: a\n  password: p1\n  port: 1\nvault:\n  key: k\nlog:\n  level: info\n")))
+	o.SetSecretKeys("password", "vault.*")
+
+	before := o.Dump()
+	assert.Equal(t, Redacted, before["db"].(map[string]interface{})["password"])
+	assert.Equal(t, Redacted, before["vault"].(map[string]interface{})["key"])
+	assert.Equal(t, "a", before["db"].(map[string]interface{})["host"])
+
+	require.NoError(t, o.v.ReadConfig(strings.NewReader(
+		"db:\n  host: b\n  password: p2\nvault:\n  key: k2\nlog:\n  level: info\n  file: x.log\n")))
+	after := o.Dump()
+
+	assert.Equal(t, []Change{
+		{Key: "db.host", Kind: ChangeChanged, Old: "a", New: "b"},
+		{Key: "db.port", Kind: ChangeRemoved, Old: 1},
+		{Key: "log.file", Kind: ChangeAdded, New: "x.log"},
+	}, Diff(before, after))
+}
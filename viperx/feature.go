@@ -0,0 +1,73 @@
+package viperx
+
+import (
+	"hash/fnv"
+	"slices"
+	"strings"
+)
+
+// IsFeatureEnabled tells whether the feature flag at key is on for subjectID,
+// e.g. a user or tenant id, for gradual rollouts driven by the config. A flag
+// is a section
+//
+//	features:
+//	  new_checkout:
+//	    enabled: true     # off for everyone if false or missing
+//	    percentage: 20    # share of the subjects in [0, 100], 100 if missing
+//	    allow: [u-1, u-2] # always on for these, percentage aside
+//	    deny: [u-3]       # always off for these, allow aside
+//
+// read by IsFeatureEnabled("features.new_checkout", userID), or a plain
+// boolean, "new_checkout: true", on or off for everyone. A flag not set, or
+// with a percentage which is not an integer, is off.
+//
+// The rollout is deterministic: the subject is hashed with key into a bucket
+// in [0, 100) and is in if the bucket is below the percentage, so a subject
+// keeps its answer across calls and instances, raising the percentage only
+// adds subjects, and the flags roll out to independent subsets. The flag is
+// read at each call, a reload applies at once.
+func (o *ViperX) IsFeatureEnabled(key string, subjectID string) bool {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	if !o.v.IsSet(key) {
+		return false
+	}
+	if _, ok := o.v.Get(key).(map[string]interface{}); !ok {
+		return o.v.GetBool(key)
+	}
+	if !o.v.GetBool(key + ".enabled") {
+		return false
+	}
+	if slices.Contains(o.v.GetStringSlice(key+".deny"), subjectID) {
+		return false
+	}
+	if slices.Contains(o.v.GetStringSlice(key+".allow"), subjectID) {
+		return true
+	}
+	percentage := 100
+	if o.v.IsSet(key + ".percentage") {
+		p, ok := toInt(o.v.Get(key + ".percentage"))
+		if !ok {
+			return false
+		}
+		percentage = p
+	}
+	return featureBucket(key, subjectID) < percentage
+}
+
+// featureBucket hashes subjectID, salted with the lower-cased key as viper
+// keys are case-insensitive, into [0, 100)
+func featureBucket(key, subjectID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(key)))
+	h.Write([]byte{0})
+	h.Write([]byte(subjectID))
+	return int(h.Sum32() % 100)
+}
+
+// IsFeatureEnabled tells whether the feature flag at key is on for subjectID,
+// see ViperX.IsFeatureEnabled.
+func IsFeatureEnabled(key string, subjectID string) bool {
+	return vx.IsFeatureEnabled(key, subjectID)
+}
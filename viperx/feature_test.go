@@ -0,0 +1,50 @@
+package viperx
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsFeatureEnabled(t *testing.T) {
+	require.NoError(t, ReadBytes([]byte(`
+featuretest:
+  off:
+    enabled: false
+    allow: [u-1]
+  all:
+    enabled: true
+  plain: true
+  rollout:
+    enabled: true
+    percentage: 30
+    allow: [u-1]
+    deny: [u-2]
+  bad:
+    enabled: true
+    percentage: lots
+`), "yaml"))
+
+	assert.False(t, IsFeatureEnabled("featuretest.missing", "u-1"))
+	assert.False(t, IsFeatureEnabled("featuretest.off", "u-1"))
+	assert.True(t, IsFeatureEnabled("featuretest.all", "anyone"))
+	assert.True(t, IsFeatureEnabled("featuretest.plain", "anyone"))
+	assert.False(t, IsFeatureEnabled("featuretest.bad", "anyone"))
+
+	// allow and deny win over the percentage
+	assert.True(t, IsFeatureEnabled("featuretest.rollout", "u-1"))
+	assert.False(t, IsFeatureEnabled("featuretest.rollout", "u-2"))
+
+	in := 0
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("user-%d", i)
+		on := IsFeatureEnabled("featuretest.rollout", id)
+		assert.Equal(t, on, IsFeatureEnabled("featuretest.rollout", id), "deterministic")
+		if on {
+			in++
+		}
+	}
+	assert.InDelta(t, 300, in, 60)
+}
@@ -120,6 +120,7 @@ func (o *ViperX) parse(fs *pflag.FlagSet, rt reflect.Type, tagName string, parts
 			if err = vx.v.BindPFlag(keyPath, fs.Lookup(flags.Name)); err != nil {
 				return err
 			}
+			o.recordFlag(keyPath, fs.Lookup(flags.Name))
 			vx.v.SetDefault(keyPath, flags.Default)
 
 			if flags.Must == "true" && flags.Default == "" {
@@ -0,0 +1,118 @@
+package viperx
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const keyWildcardSuffix = ".*"
+
+// ValidateKeys checks every key known to viper against the allowed list and
+// returns an error listing the unknown ones, so that misspelled keys fail at
+// startup instead of silently falling back to defaults.
+//
+// Keys are dotted paths, e.g. "log.level". An entry ending with ".*" allows any
+// key below that section, e.g. "tenants.*" allows "tenants.a.size".
+// Matching is case-insensitive, as viper lower-cases all keys.
+func (o *ViperX) ValidateKeys(allowed []string) error {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	exact := make(map[string]bool, len(allowed))
+	var prefixes []string
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if strings.HasSuffix(a, keyWildcardSuffix) {
+			prefixes = append(prefixes, strings.TrimSuffix(a, "*"))
+		} else {
+			exact[a] = true
+		}
+	}
+
+	var unknown []string
+	for _, key := range o.v.AllKeys() {
+		if exact[key] || hasAnyPrefix(key, prefixes) {
+			continue
+		}
+		unknown = append(unknown, key)
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+	return errors.New("unknown configure items:\n  " + strings.Join(unknown, "\n  ") + "\n")
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateKeys checks the loaded config against allowed keys, see ViperX.ValidateKeys.
+func ValidateKeys(allowed []string) error {
+	return vx.ValidateKeys(allowed)
+}
+
+// KeysFromStruct derives the allowed key list from cfg, using the same naming
+// rules as BindAllFlags. Map fields are allowed with a wildcard suffix so that
+// dynamic sections may hold any sub key. Unexported fields are left out, a
+// time.Time is a key of its own and a struct reached again below itself, e.g.
+// a linked Node, is not walked again.
+func KeysFromStruct(cfg any, opts ...viper.DecoderConfigOption) []string {
+	rt := reflect.TypeOf(cfg)
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	return collectKeys(rt, getMapStructureTagName(opts...), map[reflect.Type]bool{})
+}
+
+// collectKeys returns the keys of the fields of rt, visiting are the struct
+// types being walked, one met again, e.g. the Next *Node of a Node, is skipped
+// not to recurse forever
+func collectKeys(rt reflect.Type, tagName string, visiting map[reflect.Type]bool, parts ...string) []string {
+	visiting[rt] = true
+	defer delete(visiting, rt)
+
+	var keys []string
+	for i := 0; i < rt.NumField(); i++ {
+		t := rt.Field(i)
+		if !t.IsExported() {
+			continue
+		}
+		fieldName := parseTypeName(t, tagName)
+		ft := t.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		key := strings.Join(append(parts[:len(parts):len(parts)], fieldName), ".")
+
+		switch {
+		case ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}):
+			if visiting[ft] {
+				continue
+			}
+			if len(fieldName) == 0 {
+				keys = append(keys, collectKeys(ft, tagName, visiting, parts...)...)
+			} else {
+				keys = append(keys, collectKeys(ft, tagName, visiting, append(parts[:len(parts):len(parts)], fieldName)...)...)
+			}
+		case ft.Kind() == reflect.Map:
+			keys = append(keys, key+keyWildcardSuffix)
+		default:
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,69 @@
+package viperx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateKeys(t *testing.T) {
+	o := &ViperX{v: viper.New()}
+	o.v.SetConfigType("yaml")
+	require.NoError(t, o.v.ReadConfig(strings.NewReader(`
+log:
+  level: info
+  levle: debug
+tenants:
+  a:
+    size: 1
+`)))
+
+	err := o.ValidateKeys([]string{"log.level", "tenants.*"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "log.levle")
+	require.NotContains(t, err.Error(), "tenants")
+
+	require.NoError(t, o.ValidateKeys([]string{"Log.Level", "log.levle", "tenants.*"}))
+}
+
+func TestKeysFromStruct(t *testing.T) {
+	type Config struct {
+		Log struct {
+			Level string
+		}
+		Tenants map[string]int
+		Port    int `mapstructure:"port"`
+	}
+
+	require.Equal(t, []string{"Log.Level", "Tenants.*", "port"}, KeysFromStruct(&Config{}))
+}
+
+type keysTestNode struct {
+	Name string
+	Next *keysTestNode
+	Meta struct {
+		Parent *keysTestNode
+		Depth  int
+	}
+}
+
+func TestKeysFromStructLeaves(t *testing.T) {
+	type Config struct {
+		Since   time.Time
+		Expires *time.Time
+		Tree    keysTestNode
+		private int
+	}
+
+	// the Node below itself is not walked again
+	keys := KeysFromStruct(&Config{})
+	require.Equal(t, []string{"Since", "Expires", "Tree.Name", "Tree.Meta.Depth"}, keys)
+
+	o := &ViperX{v: viper.New()}
+	o.v.SetConfigType("yaml")
+	require.NoError(t, o.v.ReadConfig(strings.NewReader("since: 2024-01-02T03:04:05Z\ntree:\n  name: root\n")))
+	require.NoError(t, o.ValidateKeys(keys))
+}
@@ -0,0 +1,99 @@
+package viperx
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// ProfilesKey is the top-level key holding the profiles of a config, see
+// UseProfile.
+const ProfilesKey = "profiles"
+
+// UseProfile activates the profile name, the sub-tree profiles.<name> of the
+// config merged over the rest of it, e.g. with
+//
+//	db:
+//	  host: localhost
+//	profiles:
+//	  prod:
+//	    db:
+//	      host: db.internal
+//
+// UseProfile("prod") makes db.host db.internal. The profile is applied by the
+// config loads, ReadInConfig, ReadFrom, LoadAndMerge, Reload and WatchConfig,
+// a load whose config lacks it fails. "" deactivates it.
+//
+// The profile merges into the config layer: it wins over the base config,
+// with LoadAndMerge over the merge of all the files, a profile may be set in
+// any of them, and loses to Set, flags and env, the defaults losing to it.
+// GetWithSource reports its keys as SourceFile.
+//
+// Called once a config is loaded, it reloads the config files in use, for the
+// change to take effect, or for a config read by ReadFrom merges it over the
+// current config, the keys of a previous profile which the new one does not
+// set staying then.
+func (o *ViperX) UseProfile(name string) error {
+	o.mutex.Lock()
+	prev := o.profile
+	o.profile = name
+	o.mutex.Unlock()
+
+	err := o.applyProfileChange(name)
+	if err != nil {
+		o.mutex.Lock()
+		o.profile = prev
+		o.mutex.Unlock()
+	}
+	return err
+}
+
+func (o *ViperX) applyProfileChange(name string) error {
+	if files, _, _ := o.configSources(); len(files) > 0 {
+		o.reloadMu.Lock()
+		defer o.reloadMu.Unlock()
+		_, err := o.reload()
+		return err
+	}
+	if o.generation.Load() == 0 {
+		// applied by the first load
+		return nil
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if err := applyProfile(o.v, name); err != nil {
+		return err
+	}
+	o.generation.Add(1)
+	return nil
+}
+
+// ActiveProfile returns the profile set by UseProfile, "" if none.
+func (o *ViperX) ActiveProfile() string {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	return o.profile
+}
+
+// applyProfile merges the profile name of v over its config
+func applyProfile(v *viper.Viper, name string) error {
+	if name == "" {
+		return nil
+	}
+	key := ProfilesKey + "." + name
+	if !v.InConfig(key) {
+		return fmt.Errorf("profile %q not found in config", name)
+	}
+	return v.MergeConfigMap(v.GetStringMap(key))
+}
+
+// UseProfile activates the config profile name, see ViperX.UseProfile.
+func UseProfile(name string) error {
+	return vx.UseProfile(name)
+}
+
+// ActiveProfile returns the config profile in use, see ViperX.UseProfile.
+func ActiveProfile() string {
+	return vx.ActiveProfile()
+}
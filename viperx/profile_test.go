@@ -0,0 +1,60 @@
+package viperx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseProfile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+db:
+  host: localhost
+  port: 5432
+log:
+  level: debug
+profiles:
+  prod:
+    db:
+      host: db.internal
+    log:
+      level: warn
+  staging:
+    db:
+      host: db.staging
+`), 0600))
+
+	o := &ViperX{v: viper.New()}
+	// before the load, applied by it
+	require.NoError(t, o.UseProfile("prod"))
+	require.NoError(t, o.LoadAndMerge(file))
+	assert.Equal(t, "prod", o.ActiveProfile())
+	assert.Equal(t, "db.internal", o.v.GetString("db.host"))
+	assert.Equal(t, 5432, o.v.GetInt("db.port"))
+	assert.Equal(t, "warn", o.v.GetString("log.level"))
+
+	// env wins over the profile
+	t.Setenv("TEST_PROFILE_LOG_LEVEL", "error")
+	require.NoError(t, o.v.BindEnv("log.level", "TEST_PROFILE_LOG_LEVEL"))
+	assert.Equal(t, "error", o.v.GetString("log.level"))
+	t.Setenv("TEST_PROFILE_LOG_LEVEL", "")
+
+	// switching reloads, nothing of prod is left
+	gen := o.Generation()
+	require.NoError(t, o.UseProfile("staging"))
+	assert.Equal(t, gen+1, o.Generation())
+	assert.Equal(t, "db.staging", o.v.GetString("db.host"))
+	assert.Equal(t, "debug", o.v.GetString("log.level"))
+
+	assert.ErrorContains(t, o.UseProfile("qa"), `profile "qa" not found`)
+	assert.Equal(t, "staging", o.ActiveProfile())
+	assert.Equal(t, "db.staging", o.v.GetString("db.host"))
+
+	require.NoError(t, o.UseProfile(""))
+	assert.Equal(t, "localhost", o.v.GetString("db.host"))
+}
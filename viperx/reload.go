@@ -0,0 +1,427 @@
+package viperx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// ReloadValidator checks a staged config before Reload swaps it in.
+// staged holds only the content of the config file, with the profile of
+// UseProfile merged, flags, env and defaults are applied on top of it after
+// the swap.
+type ReloadValidator func(staged *viper.Viper) error
+
+// AddReloadValidator registers fn to be run by every Reload.
+func (o *ViperX) AddReloadValidator(fn ReloadValidator) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.reloadValidators = append(o.reloadValidators, fn)
+}
+
+// OnReloadError sets the callback invoked when a Reload fails, the previous
+// config stays in effect.
+func (o *ViperX) OnReloadError(fn func(err error)) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.onReloadError = fn
+}
+
+// DefaultReloadWindow is the window WatchConfig coalesces the changes of the
+// config files within, see SetReloadWindow.
+const DefaultReloadWindow = 100 * time.Millisecond
+
+// Generation returns the number of configs loaded by ReadInConfig, ReadFrom,
+// LoadAndMerge or swapped in by Reload, readers may compare it to notice a
+// change. It only grows, once per reload which succeeded, a failed one leaves
+// it as is.
+//
+// It is incremented under the write lock along with the swap, so a reader
+// seeing the new generation sees the new config, and before the OnConfigChange
+// callbacks of the reload are called.
+func (o *ViperX) Generation() uint64 {
+	return o.generation.Load()
+}
+
+// SetReloadWindow sets the window WatchConfig coalesces the changes within:
+// the first change of a config file starts it, the files changing until it
+// ends, e.g. several files saved together or the multiple events an editor
+// produces for one save, are reloaded once, with one OnConfigChange call.
+// Zero is for DefaultReloadWindow, it applies to the next WatchConfig.
+func (o *ViperX) SetReloadWindow(d time.Duration) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.reloadWindow = d
+}
+
+func (o *ViperX) getReloadWindow() time.Duration {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	if o.reloadWindow <= 0 {
+		return DefaultReloadWindow
+	}
+	return o.reloadWindow
+}
+
+// Reload re-reads the config file in use. The new content is parsed into a
+// staging viper and checked by the registered validators first, only if both
+// pass it replaces the current config, else the current one is kept, the
+// OnReloadError callback invoked and the error returned.
+//
+// The swap happens under the write lock, readers going through the getters of
+// this package always see one fully-consistent generation, never a partial one.
+func (o *ViperX) Reload() error {
+	o.reloadMu.Lock()
+	defer o.reloadMu.Unlock()
+	_, err := o.reload()
+	if err != nil {
+		o.reloadFailed(err)
+	}
+	return err
+}
+
+// ConfigChange reports a change of the watched config files, Files are the
+// files which changed within the reload window, File the first of them,
+// Changes the keys of the effective config affected, values of secret keys
+// redacted like in Dump. Generation is the generation of the reload.
+type ConfigChange struct {
+	File       string
+	Files      []string
+	Changes    []Change
+	Generation uint64
+}
+
+type changeHandler struct {
+	fn    func(ev ConfigChange)
+	files []string
+}
+
+// OnConfigChange registers fn to be called by WatchConfig after a change of
+// one of files, or of any config file if none is given, was reloaded, e.g. to
+// reload only the subsystem whose file changed. A change not affecting the
+// effective config, e.g. overridden by a file merged later, is not reported.
+// fn is called once per reload, however many of files changed within the
+// reload window, see SetReloadWindow.
+func (o *ViperX) OnConfigChange(fn func(ev ConfigChange), files ...string) {
+	for i, f := range files {
+		files[i] = filepath.Clean(f)
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.changeHandlers = append(o.changeHandlers, changeHandler{fn: fn, files: files})
+}
+
+// LoadAndMerge loads files in order, each one overriding the keys of those
+// before, e.g. app.yaml, logging.yaml then secrets.yaml, the format of each
+// coming from its extension. Like Reload, the merged config is staged and
+// checked by the validators first. Reload and WatchConfig then re-merge all of
+// them, so the effective config stays consistent whichever file changes.
+func (o *ViperX) LoadAndMerge(files ...string) error {
+	if len(files) == 0 {
+		return errors.New("no config file to load")
+	}
+	o.reloadMu.Lock()
+	defer o.reloadMu.Unlock()
+	_, err := o.load(append([]string(nil), files...), "", true)
+	return err
+}
+
+// configSources returns the files making up the config, with the format
+// forced by SetConfigType, if any, and whether they were merged by
+// LoadAndMerge
+func (o *ViperX) configSources() ([]string, string, bool) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	if len(o.configFiles) > 0 {
+		return o.configFiles, "", true
+	}
+	if file := o.v.ConfigFileUsed(); file != "" {
+		return []string{file}, o.configType, false
+	}
+	return nil, "", false
+}
+
+func (o *ViperX) reload() (uint64, error) {
+	files, format, merged := o.configSources()
+	if len(files) == 0 {
+		return 0, errors.New("no config file in use")
+	}
+	return o.load(files, format, merged)
+}
+
+type configSource struct {
+	file    string
+	format  string
+	content []byte
+}
+
+// load stages the merge of files, checks it with the validators and swaps it
+// in, format overrides the extensions of files if set. merged files are kept
+// for the next reloads. It returns the generation of the swapped in config.
+func (o *ViperX) load(files []string, format string, merged bool) (uint64, error) {
+	sources := make([]configSource, len(files))
+	for i, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return 0, err
+		}
+		f := format
+		if f == "" {
+			f = strings.TrimPrefix(filepath.Ext(file), ".")
+		}
+		sources[i] = configSource{file: file, format: f, content: b}
+	}
+
+	profile := o.ActiveProfile()
+	staged := viper.New()
+	if err := mergeSources(staged, sources); err != nil {
+		return 0, err
+	}
+	if err := applyProfile(staged, profile); err != nil {
+		return 0, err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for _, fn := range o.reloadValidators {
+		if err := fn(staged); err != nil {
+			return 0, fmt.Errorf("invalid config %s: %w", strings.Join(files, ", "), err)
+		}
+	}
+
+	if err := mergeSources(o.v, sources); err != nil {
+		return 0, err
+	}
+	if err := applyProfile(o.v, profile); err != nil {
+		return 0, err
+	}
+	if merged {
+		o.configFiles = files
+	}
+	return o.generation.Add(1), nil
+}
+
+func mergeSources(v *viper.Viper, sources []configSource) error {
+	for i, src := range sources {
+		v.SetConfigType(src.format)
+		read := v.MergeConfig
+		if i == 0 {
+			read = v.ReadConfig
+		}
+		if err := read(bytes.NewReader(src.content)); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", src.file, err)
+		}
+	}
+	return nil
+}
+
+// WatchConfig calls Reload whenever one of the config files in use changes,
+// the merged ones of LoadAndMerge included, until ctx is done, then the
+// OnConfigChange callbacks. The changes within the reload window are
+// coalesced into one reload, see SetReloadWindow. Failures are reported to
+// the OnReloadError callback.
+// viper's own WatchConfig is not used as it reads the file in place, without
+// staging.
+func (o *ViperX) WatchConfig(ctx context.Context) error {
+	files, _, _ := o.configSources()
+	if len(files) == 0 {
+		return errors.New("no config file in use")
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// watch the directories, editors and k8s configmaps replace the files
+	realFiles := make(map[string]string, len(files))
+	for _, file := range files {
+		file = filepath.Clean(file)
+		if err = w.Add(filepath.Dir(file)); err != nil {
+			_ = w.Close()
+			return err
+		}
+		realFiles[file], _ = filepath.EvalSymlinks(file)
+	}
+
+	window := o.getReloadWindow()
+	go func() {
+		defer w.Close()
+		// the files changed in the current window, in order, flush is nil
+		// while no window is open
+		var (
+			pending []string
+			flush   <-chan time.Time
+		)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-flush:
+				o.reloadChanged(pending)
+				pending, flush = nil, nil
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				for file, realFile := range realFiles {
+					curFile, _ := filepath.EvalSymlinks(file)
+					changed := filepath.Clean(ev.Name) == file && ev.Op&(fsnotify.Write|fsnotify.Create) != 0
+					if curFile == "" || (!changed && curFile == realFile) {
+						continue
+					}
+					realFiles[file] = curFile
+					if !slices.Contains(pending, file) {
+						pending = append(pending, file)
+					}
+					if flush == nil {
+						flush = time.After(window)
+					}
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				o.reloadFailed(err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// ReloadOnSignal reloads the config files in use whenever sig, typically
+// SIGHUP, is received, until ctx is done, for the filesystems on which
+// WatchConfig misses the changes, e.g. some overlay ones of the containers:
+//
+//	viperx.ReloadOnSignal(ctx, syscall.SIGHUP)
+//
+// The reload is the one of WatchConfig, staged and validated, then the
+// OnConfigChange callbacks of the files in use are called with the keys
+// affected, failures go to the OnReloadError callback. Both can be used
+// together: the reloads, theirs and those of Reload, are serialized, each one
+// reads the files, swaps the config and calls back before the next starts.
+func (o *ViperX) ReloadOnSignal(ctx context.Context, sig os.Signal) error {
+	files, _, _ := o.configSources()
+	if len(files) == 0 {
+		return errors.New("no config file in use")
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, sig)
+	go func() {
+		defer signal.Stop(sigCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigCh:
+				files, _, _ := o.configSources()
+				cleaned := make([]string, len(files))
+				for i, file := range files {
+					cleaned[i] = filepath.Clean(file)
+				}
+				o.reloadChanged(cleaned)
+			}
+		}
+	}()
+	return nil
+}
+
+// reloadChanged reloads after files changed and reports the affected keys
+func (o *ViperX) reloadChanged(files []string) {
+	o.reloadMu.Lock()
+	// diffed unredacted, a change of a secret key is a change too
+	before, _ := o.settings()
+	gen, err := o.reload()
+	if err != nil {
+		o.reloadMu.Unlock()
+		o.reloadFailed(err)
+		return
+	}
+	after, secrets := o.settings()
+	changes := Diff(before, after)
+	redactChanges(changes, secrets)
+	o.mutex.RLock()
+	handlers := o.changeHandlers
+	o.mutex.RUnlock()
+	// the handlers run unlocked, they may Reload or UseProfile
+	o.reloadMu.Unlock()
+	if len(changes) == 0 {
+		return
+	}
+
+	ev := ConfigChange{File: files[0], Files: files, Changes: changes, Generation: gen}
+	for _, h := range handlers {
+		if len(h.files) == 0 || slices.ContainsFunc(files, func(f string) bool { return slices.Contains(h.files, f) }) {
+			h.fn(ev)
+		}
+	}
+}
+
+func (o *ViperX) reloadFailed(err error) {
+	o.mutex.RLock()
+	fn := o.onReloadError
+	o.mutex.RUnlock()
+	if fn != nil {
+		fn(err)
+	}
+}
+
+// AddReloadValidator registers fn to be run by every Reload.
+func AddReloadValidator(fn ReloadValidator) {
+	vx.AddReloadValidator(fn)
+}
+
+// OnReloadError sets the callback invoked when a watched reload fails.
+func OnReloadError(fn func(err error)) {
+	vx.OnReloadError(fn)
+}
+
+// Reload re-reads the config file in use, see ViperX.Reload.
+func Reload() error {
+	return vx.Reload()
+}
+
+// WatchConfig reloads the config files in use on change, see ViperX.WatchConfig.
+func WatchConfig(ctx context.Context) error {
+	return vx.WatchConfig(ctx)
+}
+
+// ReloadOnSignal reloads the config files in use on sig, see
+// ViperX.ReloadOnSignal.
+func ReloadOnSignal(ctx context.Context, sig os.Signal) error {
+	return vx.ReloadOnSignal(ctx, sig)
+}
+
+// Generation returns the number of configs loaded, see ViperX.Generation.
+func Generation() uint64 {
+	return vx.Generation()
+}
+
+// SetReloadWindow sets the window WatchConfig coalesces the changes within,
+// see ViperX.SetReloadWindow.
+func SetReloadWindow(d time.Duration) {
+	vx.SetReloadWindow(d)
+}
+
+// LoadAndMerge loads and merges files in order, see ViperX.LoadAndMerge.
+func LoadAndMerge(files ...string) error {
+	return vx.LoadAndMerge(files...)
+}
+
+// OnConfigChange registers fn to be called on a watched change of files, see
+// ViperX.OnConfigChange.
+func OnConfigChange(fn func(ev ConfigChange), files ...string) {
+	vx.OnConfigChange(fn, files...)
+}
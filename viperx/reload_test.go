@@ -0,0 +1,182 @@
+package viperx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadRollback(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("db:\n  port: 5432\n"), 0600))
+
+	o := &ViperX{v: viper.New()}
+	o.v.SetConfigFile(file)
+	require.NoError(t, o.v.ReadInConfig())
+
+	var reported error
+	o.OnReloadError(func(err error) { reported = err })
+	o.AddReloadValidator(func(staged *viper.Viper) error {
+		if staged.GetInt("db.port") == 0 {
+			return errors.New("db.port is required")
+		}
+		return nil
+	})
+
+	// malformed yaml
+	require.NoError(t, os.WriteFile(file, []byte("db:\n  port: [\n"), 0600))
+	assert.Error(t, o.Reload())
+	assert.Error(t, reported)
+	assert.Equal(t, 5432, o.v.GetInt("db.port"))
+
+	// parses, but fails validation
+	reported = nil
+	require.NoError(t, os.WriteFile(file, []byte("db:\n  prot: 5433\n"), 0600))
+	assert.ErrorContains(t, o.Reload(), "db.port is required")
+	assert.Error(t, reported)
+	assert.Equal(t, 5432, o.v.GetInt("db.port"))
+	assert.Equal(t, uint64(0), o.Generation())
+
+	require.NoError(t, os.WriteFile(file, []byte("db:\n  port: 5433\n"), 0600))
+	require.NoError(t, o.Reload())
+	assert.Equal(t, 5433, o.v.GetInt("db.port"))
+	assert.Equal(t, uint64(1), o.Generation())
+}
+
+func TestLoadAndMergeWatch(t *testing.T) {
+	dir := t.TempDir()
+	app, logging := filepath.Join(dir, "app.yaml"), filepath.Join(dir, "logging.yaml")
+	require.NoError(t, os.WriteFile(app, []byte("db:\n  port: 5432\nlog:\n  level: info\n"), 0600))
+	require.NoError(t, os.WriteFile(logging, []byte("log:\n  level: warn\n"), 0600))
+
+	o := &ViperX{v: viper.New()}
+	require.NoError(t, o.LoadAndMerge(app, logging))
+	assert.Equal(t, "warn", o.v.GetString("log.level"))
+	assert.Equal(t, 5432, o.v.GetInt("db.port"))
+
+	appEvents, logEvents := make(chan ConfigChange, 4), make(chan ConfigChange, 4)
+	o.OnConfigChange(func(ev ConfigChange) { appEvents <- ev }, app)
+	o.OnConfigChange(func(ev ConfigChange) { logEvents <- ev }, logging)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, o.WatchConfig(ctx))
+
+	require.NoError(t, os.WriteFile(logging, []byte("log:\n  level: debug\n"), 0600))
+	select {
+	case ev := <-logEvents:
+		assert.Equal(t, logging, ev.File)
+		assert.Equal(t, []Change{{Key: "log.level", Kind: ChangeChanged, Old: "warn", New: "debug"}}, ev.Changes)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported for logging.yaml")
+	}
+	assert.Equal(t, "debug", o.v.GetString("log.level"))
+	assert.Equal(t, 5432, o.v.GetInt("db.port"))
+
+	// a change overridden by logging.yaml is not reported, the rest of it is
+	require.NoError(t, os.WriteFile(app, []byte("db:\n  port: 5433\nlog:\n  level: error\n"), 0600))
+	select {
+	case ev := <-appEvents:
+		assert.Equal(t, []Change{{Key: "db.port", Kind: ChangeChanged, Old: 5432, New: 5433}}, ev.Changes)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported for app.yaml")
+	}
+	assert.Equal(t, "debug", o.v.GetString("log.level"))
+	assert.Empty(t, logEvents)
+}
+
+func TestWatchConfigCoalesces(t *testing.T) {
+	dir := t.TempDir()
+	app, logging := filepath.Join(dir, "app.yaml"), filepath.Join(dir, "logging.yaml")
+	require.NoError(t, os.WriteFile(app, []byte("db:\n  port: 5432\n"), 0600))
+	require.NoError(t, os.WriteFile(logging, []byte("log:\n  level: warn\n"), 0600))
+
+	o := &ViperX{v: viper.New()}
+	require.NoError(t, o.LoadAndMerge(app, logging))
+	assert.Equal(t, uint64(1), o.Generation())
+
+	events := make(chan ConfigChange, 4)
+	o.OnConfigChange(func(ev ConfigChange) {
+		// the generation is bumped before the callbacks
+		assert.Equal(t, ev.Generation, o.Generation())
+		events <- ev
+	})
+	o.SetReloadWindow(300 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, o.WatchConfig(ctx))
+
+	require.NoError(t, os.WriteFile(app, []byte("db:\n  port: 5433\n"), 0600))
+	require.NoError(t, os.WriteFile(logging, []byte("log:\n  level: debug\n"), 0600))
+	select {
+	case ev := <-events:
+		assert.Equal(t, app, ev.File)
+		assert.Equal(t, []string{app, logging}, ev.Files)
+		assert.Len(t, ev.Changes, 2)
+		assert.Equal(t, uint64(2), ev.Generation)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected second reload %+v", ev)
+	case <-time.After(500 * time.Millisecond):
+	}
+	assert.Equal(t, uint64(2), o.Generation())
+}
+
+func TestConfigChangeHandlerReloads(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("db:\n  port: 5432\n"), 0600))
+
+	o := &ViperX{v: viper.New()}
+	require.NoError(t, o.LoadAndMerge(file))
+	var handlerErrs []error
+	o.OnConfigChange(func(ConfigChange) {
+		handlerErrs = append(handlerErrs, o.Reload(), o.UseProfile(""))
+	})
+
+	require.NoError(t, os.WriteFile(file, []byte("db:\n  port: 5433\n"), 0600))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		o.reloadChanged([]string{file})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler deadlocked")
+	}
+	assert.Equal(t, []error{nil, nil}, handlerErrs)
+	assert.Equal(t, 5433, o.v.GetInt("db.port"))
+}
+
+func TestConfigChangeSecret(t *testing.T) {
+	dir := t.TempDir()
+	app, secrets := filepath.Join(dir, "app.yaml"), filepath.Join(dir, "secrets.yaml")
+	require.NoError(t, os.WriteFile(app, []byte("db:\n  port: 5432\n"), 0600))
+	require.NoError(t, os.WriteFile(secrets, []byte("db:\n  password: old\nvault:\n  auth:\n    role: a\n"), 0600))
+
+	o := &ViperX{v: viper.New()}
+	o.SetSecretKeys("password", "vault.*")
+	require.NoError(t, o.LoadAndMerge(app, secrets))
+	var events []ConfigChange
+	o.OnConfigChange(func(ev ConfigChange) { events = append(events, ev) }, secrets)
+
+	require.NoError(t, os.WriteFile(secrets, []byte("db:\n  password: new\nvault:\n  auth:\n    role: b\n"), 0600))
+	o.reloadChanged([]string{secrets})
+	require.Len(t, events, 1, "a change of secrets only is reported")
+	assert.Equal(t, []Change{
+		{Key: "db.password", Kind: ChangeChanged, Old: Redacted, New: Redacted},
+		{Key: "vault.auth.role", Kind: ChangeChanged, Old: Redacted, New: Redacted},
+	}, events[0].Changes)
+	assert.Equal(t, "new", o.v.GetString("db.password"))
+}
@@ -0,0 +1,60 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package viperx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadOnSignal(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("db:\n  port: 5432\n"), 0600))
+
+	o := &ViperX{v: viper.New()}
+	require.NoError(t, o.LoadAndMerge(file))
+	events := make(chan ConfigChange, 4)
+	o.OnConfigChange(func(ev ConfigChange) { events <- ev }, file)
+	failed := make(chan error, 4)
+	o.OnReloadError(func(err error) { failed <- err })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, o.ReloadOnSignal(ctx, syscall.SIGHUP))
+
+	require.NoError(t, os.WriteFile(file, []byte("db:\n  port: 5433\n"), 0600))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	select {
+	case ev := <-events:
+		assert.Equal(t, file, ev.File)
+		assert.Equal(t, []Change{{Key: "db.port", Kind: ChangeChanged, Old: 5432, New: 5433}}, ev.Changes)
+		assert.Equal(t, uint64(2), ev.Generation)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload on signal")
+	}
+
+	// a bad file keeps the config, reported like a watched reload
+	require.NoError(t, os.WriteFile(file, []byte("db:\n  port: [\n"), 0600))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	select {
+	case err := <-failed:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload error on signal")
+	}
+	assert.Equal(t, 5433, o.v.GetInt("db.port"))
+}
+
+func TestReloadOnSignalNoFile(t *testing.T) {
+	o := &ViperX{v: viper.New()}
+	assert.Error(t, o.ReloadOnSignal(context.Background(), syscall.SIGHUP))
+}
@@ -0,0 +1,50 @@
+package viperx
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	secretFilePrefix = "file:"
+	secretEnvPrefix  = "env:"
+)
+
+// GetSecret retrieves a sensitive value, resolving an indirection to keep it
+// out of the config file:
+//
+//	db.password: "file:/run/secrets/db_password"  # content of the file
+//	db.password: "env:DB_PASSWORD"                # value of the variable
+//	db.password: "enc:AQICAHh..."                 # decrypted, see SetDecryptor
+//
+// The trailing newline of a file, as left by most editors and secret mounts,
+// is trimmed. Other values are returned literally.
+// It returns an error if the key is not set, the file cannot be read, the
+// variable is not set or the value cannot be decrypted.
+func GetSecret(name string) (string, error) {
+	vx.mutex.RLock()
+	val, d := vx.v.GetString(name), vx.decryption
+	vx.mutex.RUnlock()
+	switch {
+	case len(val) == 0:
+		return "", fmt.Errorf("secret %s is not set", name)
+	case strings.HasPrefix(val, secretFilePrefix):
+		path := strings.TrimPrefix(val, secretFilePrefix)
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case strings.HasPrefix(val, encryptedPrefix):
+		return d.decrypt(name, val)
+	case strings.HasPrefix(val, secretEnvPrefix):
+		env := strings.TrimPrefix(val, secretEnvPrefix)
+		s, ok := os.LookupEnv(env)
+		if !ok {
+			return "", fmt.Errorf("secret %s: environment variable %s is not set", name, env)
+		}
+		return s, nil
+	}
+	return val, nil
+}
@@ -0,0 +1,40 @@
+package viperx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_password")
+	require.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0600))
+	t.Setenv("SECRETTEST_TOKEN", "tok")
+
+	viper.Set("secrettest.file", "file:"+path)
+	viper.Set("secrettest.env", "env:SECRETTEST_TOKEN")
+	viper.Set("secrettest.literal", "plain")
+	viper.Set("secrettest.missingfile", "file:"+path+".missing")
+	viper.Set("secrettest.missingenv", "env:SECRETTEST_MISSING")
+
+	for key, want := range map[string]string{
+		"secrettest.file":    "s3cret",
+		"secrettest.env":     "tok",
+		"secrettest.literal": "plain",
+	} {
+		got, err := GetSecret(key)
+		assert.NoError(t, err, key)
+		assert.Equal(t, want, got, key)
+	}
+
+	for _, key := range []string{"secrettest.missingfile", "secrettest.missingenv", "secrettest.unset"} {
+		_, err := GetSecret(key)
+		assert.ErrorContains(t, err, key)
+	}
+	_, err := GetSecret("secrettest.missingfile")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
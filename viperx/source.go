@@ -0,0 +1,97 @@
+package viperx
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Sources reported by GetWithSource, from the highest precedence to the lowest.
+const (
+	SourceOverride = "override"
+	SourceFlag     = "flag"
+	SourceEnv      = "env"
+	SourceFile     = "file"
+	SourceDefault  = "default"
+)
+
+// GetWithSource returns the resolved value of key along with the layer it
+// comes from, one of the Source constants, or "" if key is not set. It is a
+// diagnostic helper, e.g. for a config dump endpoint, the getters are
+// unchanged.
+//
+// Only overrides made through Set and env bound by BindEnvs are told apart,
+// a value set on the underlying viper directly is reported with the layer
+// beneath.
+func (o *ViperX) GetWithSource(key string) (interface{}, string) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	key = strings.ToLower(key)
+	value := o.v.Get(key)
+	if _, ok := o.overrides[key]; ok {
+		return value, SourceOverride
+	}
+	if f, ok := o.flags[key]; ok && f.Changed {
+		return value, SourceFlag
+	}
+	if o.envSet(key) {
+		return value, SourceEnv
+	}
+	if o.v.InConfig(key) {
+		return value, SourceFile
+	}
+	if o.v.IsSet(key) {
+		return value, SourceDefault
+	}
+	return nil, ""
+}
+
+// Set overrides the value of key, taking precedence over every other source.
+func (o *ViperX) Set(key string, value interface{}) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.overrides == nil {
+		o.overrides = make(map[string]struct{})
+	}
+	o.overrides[strings.ToLower(key)] = struct{}{}
+	o.v.Set(key, value)
+}
+
+func (o *ViperX) recordFlag(key string, f *pflag.Flag) {
+	if f == nil {
+		return
+	}
+	if o.flags == nil {
+		o.flags = make(map[string]*pflag.Flag)
+	}
+	o.flags[strings.ToLower(key)] = f
+}
+
+// envSet mirrors the env lookup of viper's AutomaticEnv
+func (o *ViperX) envSet(key string) bool {
+	if !o.automaticEnv {
+		return false
+	}
+	envKey := key
+	if o.envReplacer != nil {
+		envKey = o.envReplacer.Replace(envKey)
+	}
+	if o.envPrefix != "" {
+		envKey = o.envPrefix + "_" + envKey
+	}
+	v, ok := os.LookupEnv(strings.ToUpper(envKey))
+	return ok && v != ""
+}
+
+// GetWithSource returns the resolved value of key and its source, see
+// ViperX.GetWithSource.
+func GetWithSource(key string) (interface{}, string) {
+	return vx.GetWithSource(key)
+}
+
+// Set overrides the value of key, see ViperX.Set.
+func Set(key string, value interface{}) {
+	vx.Set(key, value)
+}
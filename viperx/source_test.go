@@ -0,0 +1,43 @@
+package viperx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetWithSource(t *testing.T) {
+	t.Setenv("SRCTEST_DB_HOST", "env-host")
+
+	o := &ViperX{v: viper.New()}
+	o.v.SetDefault("db.user", "root")
+	o.v.SetDefault("db.port", 3306)
+	o.v.SetConfigType("yaml")
+	require.NoError(t, o.v.ReadConfig(strings.NewReader("db:\n  port: 5432\n  host: file-host\n  name: app\n")))
+	o.BindEnvs("SRCTEST", ".", "_")
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.String("db.name", "", "")
+	require.NoError(t, fs.Parse([]string{"--db.name=flag-name"}))
+	o.recordFlag("db.name", fs.Lookup("db.name"))
+	require.NoError(t, o.v.BindPFlag("db.name", fs.Lookup("db.name")))
+
+	o.Set("db.timeout", "5s")
+
+	for key, want := range map[string][2]interface{}{
+		"db.user":    {"root", SourceDefault},
+		"db.port":    {5432, SourceFile},
+		"db.host":    {"env-host", SourceEnv},
+		"db.name":    {"flag-name", SourceFlag},
+		"db.timeout": {"5s", SourceOverride},
+		"db.missing": {nil, ""},
+	} {
+		value, source := o.GetWithSource(key)
+		assert.Equal(t, want[0], value, key)
+		assert.Equal(t, want[1], source, key)
+	}
+}
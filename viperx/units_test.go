@@ -0,0 +1,48 @@
+package viperx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBytes(t *testing.T) {
+	for in, want := range map[string]int64{
+		"512":     512,
+		"512B":    512,
+		"100MB":   100_000_000,
+		"100mb":   100_000_000,
+		"10k":     10_000,
+		"1.5 GiB": 3 << 29,
+		"2KiB":    2048,
+		" 1TB ":   1_000_000_000_000,
+	} {
+		got, err := ParseBytes(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"", "MB", "-1MB", "10XB", "1.2.3KB", "99999999999TB"} {
+		_, err := ParseBytes(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestGetBytesAndDuration(t *testing.T) {
+	viper.Set("unitstest.size", "64MiB")
+	viper.Set("unitstest.rawsize", 1024)
+	viper.Set("unitstest.badsize", "lots")
+	viper.Set("unitstest.timeout", "1m30s")
+	viper.Set("unitstest.badtimeout", "soon")
+
+	assert.Equal(t, int64(64<<20), GetBytes("unitstest.size", 1))
+	assert.Equal(t, int64(1024), GetBytes("unitstest.rawsize", 1))
+	assert.Equal(t, int64(1), GetBytes("unitstest.badsize", 1))
+	assert.Equal(t, int64(1), GetBytes("unitstest.missing", 1))
+
+	assert.Equal(t, 90*time.Second, GetDuration("unitstest.timeout", time.Second))
+	assert.Equal(t, time.Second, GetDuration("unitstest.badtimeout", time.Second))
+	assert.Equal(t, time.Second, GetDuration("unitstest.missing", time.Second))
+}
@@ -0,0 +1,90 @@
+package viperx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUpstream struct {
+	Name    string
+	URL     string
+	Timeout time.Duration
+	Tags    []string
+}
+
+func TestUnmarshalKey(t *testing.T) {
+	want := []testUpstream{
+		{Name: "users", URL: "http://users.internal", Timeout: 2 * time.Second, Tags: []string{"a", "b"}},
+		{Name: "orders", URL: "http://orders.internal", Timeout: time.Minute},
+	}
+
+	require.NoError(t, ReadBytes([]byte(`
+unmarshaltest:
+  upstreams:
+    - name: users
+      url: http://users.internal
+      timeout: 2s
+      tags: a,b
+    - name: orders
+      url: http://orders.internal
+      timeout: 1m
+`), "yaml"))
+	var got []testUpstream
+	require.NoError(t, UnmarshalKey("unmarshaltest.upstreams", &got))
+	assert.Equal(t, want, got)
+
+	require.NoError(t, ReadBytes([]byte(`
+[[unmarshaltest.upstreams]]
+name = "users"
+url = "http://users.internal"
+timeout = "2s"
+tags = ["a", "b"]
+
+[[unmarshaltest.upstreams]]
+name = "orders"
+url = "http://orders.internal"
+timeout = "1m"
+`), "toml"))
+	got = nil
+	require.NoError(t, UnmarshalKey("unmarshaltest.upstreams", &got))
+	assert.Equal(t, want, got)
+
+	// not set, out is left alone
+	require.NoError(t, UnmarshalKey("unmarshaltest.missing", &got))
+	assert.Equal(t, want, got)
+}
+
+func TestUnmarshalKeyErrors(t *testing.T) {
+	require.NoError(t, ReadBytes([]byte(`
+unmarshaltest:
+  upstreams:
+    - name: users
+      timeout: 2s
+    - name: orders
+      timeout: soon
+  extra:
+    - name: users
+      retries: 3
+  single: users
+`), "yaml"))
+
+	var got []testUpstream
+	err := UnmarshalKey("unmarshaltest.upstreams", &got)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid entry 1 of unmarshaltest.upstreams:")
+	assert.Contains(t, err.Error(), "soon")
+
+	// lenient by default, strict with ErrorUnused
+	require.NoError(t, UnmarshalKey("unmarshaltest.extra", &got))
+	err = UnmarshalKey("unmarshaltest.extra", &got, func(c *mapstructure.DecoderConfig) { c.ErrorUnused = true })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid entry 0 of unmarshaltest.extra:")
+	assert.Contains(t, err.Error(), "retries")
+
+	assert.EqualError(t, UnmarshalKey("unmarshaltest.single", &got),
+		"invalid value of unmarshaltest.single, should be a list, got string")
+}
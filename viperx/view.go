@@ -0,0 +1,312 @@
+package viperx
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ConfigView is a read-only snapshot of the config, taken by Snapshot, with
+// the getters of the package.
+type ConfigView struct {
+	v          *viper.Viper
+	generation uint64
+	decryption *decryption
+}
+
+// Snapshot returns the config as of now, every layer resolved, for the reads
+// of several related keys to be coherent, e.g. the host and the port of a
+// backend, which read by the package getters one after the other may come
+// from both sides of a reload:
+//
+//	cfg := viperx.Snapshot()
+//	addr := net.JoinHostPort(cfg.GetString("db.host", "localhost"), cfg.GetString("db.port", "5432"))
+//
+// The snapshot does not see the loads, Set calls or env changes which come
+// after it, on purpose, take a new one to see them, e.g. one per request.
+// Taking it copies the settings, the live getters stay the cheaper way to
+// read a single key. It is safe for concurrent use.
+func (o *ViperX) Snapshot() ConfigView {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	v := viper.New()
+	// the settings are a fresh copy, the live config is left alone
+	_ = v.MergeConfigMap(o.v.AllSettings())
+	return ConfigView{v: v, generation: o.generation.Load(), decryption: o.decryption}
+}
+
+// Generation is the config generation the snapshot was taken at, see
+// ViperX.Generation.
+func (cv ConfigView) Generation() uint64 {
+	return cv.generation
+}
+
+// IsSet reports whether key is set in the snapshot.
+func (cv ConfigView) IsSet(key string) bool {
+	return cv.v.IsSet(key)
+}
+
+// Get returns the value of key in the snapshot, nil if not set.
+func (cv ConfigView) Get(key string) interface{} {
+	return cv.v.Get(key)
+}
+
+// Exists is Exists on the snapshot.
+func (cv ConfigView) Exists(key string) bool {
+	return cv.v.IsSet(key)
+}
+
+// TypeOf is TypeOf on the snapshot.
+func (cv ConfigView) TypeOf(key string) string {
+	return typeOf(cv.v.Get(key))
+}
+
+// typeOf names the kind of a config value, see TypeOf
+func typeOf(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return ""
+	case time.Duration:
+		return "duration"
+	case time.Time:
+		return "time"
+	}
+	switch reflect.TypeOf(val).Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Map:
+		return "map"
+	case reflect.Slice, reflect.Array:
+		return "slice"
+	default:
+		return reflect.TypeOf(val).Kind().String()
+	}
+}
+
+// GetString is GetString on the snapshot.
+func (cv ConfigView) GetString(name string, def string) string {
+	rst := cv.v.GetString(name)
+	if strings.HasPrefix(rst, encryptedPrefix) {
+		// def rather than the ciphertext, GetSecret tells the error
+		plain, err := cv.decryption.decrypt(name, rst)
+		if err != nil {
+			return def
+		}
+		rst = plain
+	}
+	if len(rst) == 0 {
+		return def
+	}
+
+	return rst
+}
+
+// GetEnum is GetEnum on the snapshot.
+func (cv ConfigView) GetEnum(name string, allowed []string, def string) (string, error) {
+	val := cv.GetString(name, "")
+	if len(val) == 0 {
+		return def, nil
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(val, a) {
+			return a, nil
+		}
+	}
+	return def, fmt.Errorf("invalid value '%s' of %s, should be one of [%s]", val, name, strings.Join(allowed, ", "))
+}
+
+// GetStrings is GetStrings on the snapshot.
+func (cv ConfigView) GetStrings(name string, def []string) []string {
+	if !cv.v.IsSet(name) {
+		return def
+	}
+	return cv.v.GetStringSlice(name)
+}
+
+// GetInt is GetInt on the snapshot.
+func (cv ConfigView) GetInt(name string, def int) int {
+	if !cv.v.IsSet(name) {
+		return def
+	}
+	return cv.v.GetInt(name)
+}
+
+// GetInt64 is GetInt64 on the snapshot.
+func (cv ConfigView) GetInt64(name string, def int64) int64 {
+	if !cv.v.IsSet(name) {
+		return def
+	}
+	return cv.v.GetInt64(name)
+}
+
+// GetBool is GetBool on the snapshot.
+func (cv ConfigView) GetBool(name string, def bool) bool {
+	if !cv.v.IsSet(name) {
+		return def
+	}
+	return cv.v.GetBool(name)
+}
+
+// GetFloat64 is GetFloat64 on the snapshot.
+func (cv ConfigView) GetFloat64(name string, def float64) float64 {
+	if !cv.v.IsSet(name) {
+		return def
+	}
+	return cv.v.GetFloat64(name)
+}
+
+// GetDuration is GetDuration on the snapshot.
+func (cv ConfigView) GetDuration(name string, def time.Duration) time.Duration {
+	if !cv.v.IsSet(name) {
+		return def
+	}
+	if d, ok := toDuration(cv.v.Get(name)); ok {
+		return d
+	}
+	return def
+}
+
+// GetIntInRange is GetIntInRange on the snapshot.
+func (cv ConfigView) GetIntInRange(name string, min, max, def int) (int, error) {
+	if !cv.v.IsSet(name) {
+		return def, nil
+	}
+	val := cv.v.Get(name)
+	n, ok := toInt(val)
+	if !ok || n < min || n > max {
+		return def, fmt.Errorf("invalid value '%v' of %s, should be an integer in [%d, %d]", val, name, min, max)
+	}
+	return n, nil
+}
+
+// GetDurationInRange is GetDurationInRange on the snapshot.
+func (cv ConfigView) GetDurationInRange(name string, min, max, def time.Duration) (time.Duration, error) {
+	if !cv.v.IsSet(name) {
+		return def, nil
+	}
+	val := cv.v.Get(name)
+	d, ok := toDuration(val)
+	if !ok || d < min || d > max {
+		return def, fmt.Errorf("invalid value '%v' of %s, should be a duration in [%v, %v]", val, name, min, max)
+	}
+	return d, nil
+}
+
+// GetBytes is GetBytes on the snapshot.
+func (cv ConfigView) GetBytes(name string, def int64) int64 {
+	if !cv.v.IsSet(name) {
+		return def
+	}
+	switch val := cv.v.Get(name).(type) {
+	case string:
+		n, err := ParseBytes(val)
+		if err != nil {
+			return def
+		}
+		return n
+	default:
+		rv := reflect.ValueOf(val)
+		switch {
+		case rv.CanInt() && rv.Int() >= 0:
+			return rv.Int()
+		case rv.CanUint():
+			return int64(rv.Uint())
+		case rv.CanFloat() && rv.Float() >= 0:
+			return int64(rv.Float())
+		}
+		return def
+	}
+}
+
+// GetIP is GetIP on the snapshot.
+func (cv ConfigView) GetIP(name string, def net.IP) net.IP {
+	if !cv.v.IsSet(name) {
+		return def
+	}
+	switch val := cv.v.Get(name).(type) {
+	case net.IP:
+		if len(val) == 0 {
+			return def
+		}
+		return val
+	case string:
+		if ip := net.ParseIP(strings.TrimSpace(val)); ip != nil {
+			return ip
+		}
+	}
+	return def
+}
+
+// GetURL is GetURL on the snapshot.
+func (cv ConfigView) GetURL(name string, def *url.URL) *url.URL {
+	if !cv.v.IsSet(name) {
+		return def
+	}
+	val, ok := cv.v.Get(name).(string)
+	if !ok {
+		return def
+	}
+	u, err := url.Parse(strings.TrimSpace(val))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return def
+	}
+	return u
+}
+
+// GetTime is GetTime on the snapshot.
+func (cv ConfigView) GetTime(name string, layout string, def time.Time) time.Time {
+	if !cv.v.IsSet(name) {
+		return def
+	}
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	switch val := cv.v.Get(name).(type) {
+	case time.Time:
+		return val
+	case string:
+		t, err := time.Parse(layout, strings.TrimSpace(val))
+		if err != nil {
+			return def
+		}
+		return t
+	}
+	return def
+}
+
+// GetStringMap is GetStringMap on the snapshot.
+func (cv ConfigView) GetStringMap(name string, def map[string]interface{}) map[string]interface{} {
+	if !cv.v.IsSet(name) {
+		return def
+	}
+	return copyStringMap(cv.v.GetStringMap(name))
+}
+
+// copyStringMap copies m and its nested maps, viper returns its own
+func copyStringMap(m map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if sub, ok := v.(map[string]interface{}); ok {
+			v = copyStringMap(sub)
+		}
+		c[k] = v
+	}
+	return c
+}
+
+// Snapshot returns the config as of now, see ViperX.Snapshot.
+func Snapshot() ConfigView {
+	return vx.Snapshot()
+}
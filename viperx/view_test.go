@@ -0,0 +1,55 @@
+package viperx
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	require.NoError(t, ReadBytes([]byte("viewtest:\n  host: a.internal\n  port: 1\n  timeout: 2s\n  tags: [x, y]\n"), "yaml"))
+	Set("viewtest.override", "set")
+
+	cfg := Snapshot()
+	assert.Equal(t, Generation(), cfg.Generation())
+	assert.Equal(t, "a.internal", cfg.GetString("viewtest.host", ""))
+	assert.Equal(t, 1, cfg.GetInt("viewtest.port", 0))
+	assert.Equal(t, 2*time.Second, cfg.GetDuration("viewtest.timeout", 0))
+	assert.Equal(t, []string{"x", "y"}, cfg.GetStrings("viewtest.tags", nil))
+	assert.Equal(t, "set", cfg.GetString("viewtest.override", ""))
+	assert.Equal(t, "def", cfg.GetString("viewtest.missing", "def"))
+	assert.True(t, cfg.IsSet("viewtest.host"))
+	assert.False(t, cfg.IsSet("viewtest.missing"))
+
+	// later loads are not seen by the snapshot
+	require.NoError(t, ReadBytes([]byte("viewtest:\n  host: b.internal\n  port: 2\n"), "yaml"))
+	assert.Equal(t, "b.internal", GetString("viewtest.host", ""))
+	assert.Equal(t, "a.internal", cfg.GetString("viewtest.host", ""))
+	assert.Equal(t, 1, cfg.GetInt("viewtest.port", 0))
+	assert.Less(t, cfg.Generation(), Generation())
+}
+
+func TestSnapshotConsistent(t *testing.T) {
+	load := func(i int) error {
+		return ReadBytes([]byte(fmt.Sprintf("viewtest:\n  a: %d\n  b: %d\n", i, i)), "yaml")
+	}
+	require.NoError(t, load(0))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 100; i++ {
+			assert.NoError(t, load(i))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		cfg := Snapshot()
+		assert.Equal(t, cfg.GetInt("viewtest.a", -1), cfg.GetInt("viewtest.b", -2))
+	}
+	wg.Wait()
+}
@@ -4,12 +4,21 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
 	"os"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
@@ -18,8 +27,37 @@ type ViperX struct {
 	v         *viper.Viper
 	mustList  []*vxFlags
 	rangeList []*vxFlags
-	mutex     sync.Mutex
+	mutex     sync.RWMutex
 	//flags *pflag.FlagSet
+
+	configType       string
+	reloadValidators []ReloadValidator
+	onReloadError    func(err error)
+	generation       atomic.Uint64
+	reloadWindow     time.Duration
+	// serializes the loads of LoadAndMerge, Reload, UseProfile, WatchConfig
+	// and ReloadOnSignal
+	reloadMu sync.Mutex
+	// decrypts the enc: values, see SetDecryptor
+	decryption *decryption
+	// profile merged over the config, see UseProfile
+	profile string
+	// files merged by LoadAndMerge, in order
+	configFiles    []string
+	changeHandlers []changeHandler
+
+	// bookkeeping for GetWithSource
+	flags        map[string]*pflag.Flag
+	overrides    map[string]struct{}
+	envPrefix    string
+	envReplacer  *strings.Replacer
+	automaticEnv bool
+
+	// keys redacted by Dump, nil for DefaultSecretKeys
+	secretKeys []string
+
+	// key metadata registered by Describe
+	keyDocs map[string]KeyDoc
 }
 
 var (
@@ -59,6 +97,9 @@ func (o *ViperX) BindEnvs(prefix, keyDelimiter, envDelimiter string) {
 	o.v.AutomaticEnv() // automatically override values with those from the environment
 	o.v.SetEnvPrefix(prefix)
 	o.v.SetEnvKeyReplacer(strings.NewReplacer(keyDelimiter, envDelimiter))
+	o.automaticEnv = true
+	o.envPrefix = prefix
+	o.envReplacer = strings.NewReplacer(keyDelimiter, envDelimiter)
 
 	prefix = prefix + "_"
 
@@ -159,9 +200,70 @@ func BindEnvs(prefix, keyDelimiter, envDelimiter string) {
 // Unmarshal decodes the configuration into a struct using viper.Unmarshal.
 // It accepts any type of rawVal where configuration data will be stored, and opts for decoder options.
 func Unmarshal(cfg any, opts ...viper.DecoderConfigOption) (err error) {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
 	return vx.v.Unmarshal(cfg, opts...)
 }
 
+// UnmarshalKey decodes the value at key into out, e.g. a list of tables like
+//
+//	upstreams:
+//	  - name: users
+//	    url: http://users.internal
+//	    timeout: 2s
+//
+// into a *[]Upstream, in YAML, TOML, [[upstreams]], or JSON alike. The
+// entries of a list are decoded one by one, with the hooks of viper, durations
+// as "2s" and comma-separated strings as slices, so that a malformed one is
+// reported by its index, e.g. "invalid entry 1 of upstreams: ...". opts
+// configure the decoder like for Unmarshal, e.g. to reject unknown fields.
+// Another out is decoded like viper.UnmarshalKey. It returns nil and leaves
+// out untouched if key is not set.
+func UnmarshalKey(key string, out any, opts ...viper.DecoderConfigOption) error {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	if !vx.v.IsSet(key) {
+		return nil
+	}
+	val := vx.v.Get(key)
+
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Slice {
+		return vx.v.UnmarshalKey(key, out, opts...)
+	}
+	entries := reflect.ValueOf(val)
+	if entries.Kind() != reflect.Slice {
+		return fmt.Errorf("invalid value of %s, should be a list, got %T", key, val)
+	}
+	slice := reflect.MakeSlice(rv.Elem().Type(), entries.Len(), entries.Len())
+	for i := 0; i < entries.Len(); i++ {
+		if err := decodeEntry(entries.Index(i).Interface(), slice.Index(i).Addr().Interface(), opts); err != nil {
+			return fmt.Errorf("invalid entry %d of %s: %w", i, key, err)
+		}
+	}
+	rv.Elem().Set(slice)
+	return nil
+}
+
+func decodeEntry(in, out any, opts []viper.DecoderConfigOption) error {
+	c := &mapstructure.DecoderConfig{
+		Result:           out,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	d, err := mapstructure.NewDecoder(c)
+	if err != nil {
+		return err
+	}
+	return d.Decode(in)
+}
+
 // BindAllFlags 添加cfg结构体中vx_flag标记的Flag，并返回完整的FlagSet
 // （推荐）若未定义name，name解析为cfg结构体成员名，多级使用"."相连
 // 否则，解析为name
@@ -209,21 +311,31 @@ func InitConfigFile(cfgFile, cfgFilePath, cfgFileName, cfgFileType string) error
 			vx.v.SetConfigName(cfgFileName)
 		}
 		if cfgFileType != "" {
-			vx.v.SetConfigType(cfgFileType)
+			SetConfigType(cfgFileType)
 		}
 	}
 
 	// If a config file is found, read it in.
-	if err := vx.v.ReadInConfig(); err != nil {
+	if err := ReadInConfig(); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func BindPFlag(key string, flag *pflag.Flag) error { return vx.v.BindPFlag(key, flag) }
+func BindPFlag(key string, flag *pflag.Flag) error {
+	vx.mutex.Lock()
+	defer vx.mutex.Unlock()
+	vx.recordFlag(key, flag)
+	return vx.v.BindPFlag(key, flag)
+}
 
-func BindPFlags(flags *pflag.FlagSet) error { return vx.v.BindPFlags(flags) }
+func BindPFlags(flags *pflag.FlagSet) error {
+	vx.mutex.Lock()
+	defer vx.mutex.Unlock()
+	flags.VisitAll(func(f *pflag.Flag) { vx.recordFlag(f.Name, f) })
+	return vx.v.BindPFlags(flags)
+}
 
 func ConfigFileUsed() string {
 	return vx.v.ConfigFileUsed()
@@ -246,65 +358,285 @@ func SetConfigName(in string) {
 
 // SetConfigType sets the type of the configuration file.
 func SetConfigType(in string) {
+	vx.mutex.Lock()
+	defer vx.mutex.Unlock()
+	vx.configType = in
 	vx.v.SetConfigType(in)
 }
 
 func ReadInConfig() error {
-	return vx.v.ReadInConfig()
+	vx.mutex.Lock()
+	defer vx.mutex.Unlock()
+	if err := vx.v.ReadInConfig(); err != nil {
+		return err
+	}
+	if err := applyProfile(vx.v, vx.profile); err != nil {
+		return err
+	}
+	vx.generation.Add(1)
+	return nil
+}
+
+// ReadFrom loads config from r instead of a file, format is one of viper's
+// supported types, e.g. "yaml", "json" or "toml". It replaces any config
+// loaded before, flags, env and defaults are kept. An unknown format or an
+// input which does not parse fails, the config loaded before is kept then.
+func ReadFrom(r io.Reader, format string) error {
+	if !slices.Contains(viper.SupportedExts, strings.ToLower(format)) {
+		return fmt.Errorf("unsupported config format '%s', should be one of [%s]", format, strings.Join(viper.SupportedExts, ", "))
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	// parsed aside first, ReadConfig clears the config before parsing
+	staging := viper.New()
+	staging.SetConfigType(format)
+	if err := staging.ReadConfig(bytes.NewReader(b)); err != nil {
+		return err
+	}
+
+	vx.mutex.Lock()
+	defer vx.mutex.Unlock()
+	vx.configType = format
+	vx.v.SetConfigType(format)
+	if err := vx.v.ReadConfig(bytes.NewReader(b)); err != nil {
+		return err
+	}
+	if err := applyProfile(vx.v, vx.profile); err != nil {
+		return err
+	}
+	vx.generation.Add(1)
+	return nil
+}
+
+// ReadBytes loads config from b, see ReadFrom.
+func ReadBytes(b []byte, format string) error {
+	return ReadFrom(bytes.NewReader(b), format)
+}
+
+// Exists reports whether key has a value, from any layer: a Set, a flag, an
+// env var, the config files or a default. A key with only a default
+// registered, e.g. by GetViper().SetDefault or by BindFlags from the flag
+// default, does exist, as the getters return that default rather than
+// theirs. A section, e.g. "db" when "db.host" is set, exists too.
+func Exists(key string) bool {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.Exists(key)
+}
+
+// TypeOf returns the type of the value of key, one of "string", "int",
+// "float", "bool", "map", "slice", "duration" and "time", "" if the key does
+// not exist, following Exists for the defaults. It is the type as decoded
+// from its source, e.g. of a polymorphic item taking a name or a table:
+//
+//	switch viperx.TypeOf("backend") {
+//	case "string":
+//		addr = viperx.GetString("backend", "")
+//	case "map":
+//		addr = viperx.GetString("backend.addr", "")
+//	}
+//
+// The env vars and the flags are strings for viper, so is a value set by them,
+// whatever the type of the one of the config files it overrides.
+func TypeOf(key string) string {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.TypeOf(key)
 }
 
 // GetString retrieves a string value from the configuration.
 // It returns a default value if the key is not set.
 func GetString(name string, def string) string {
-	rst := vx.v.GetString(name)
-	if len(rst) == 0 {
-		return def
-	}
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v, decryption: vx.decryption}.GetString(name, def)
+}
 
-	return rst
+// GetEnum retrieves a string value which must be one of allowed, e.g.
+// GetEnum("log.level", []string{"debug", "info", "warn", "error"}, "info").
+// The match is case-insensitive and returns the spelling in allowed.
+// It returns def if the key is not set, an error naming the value and the
+// allowed ones if it is not in allowed.
+func GetEnum(name string, allowed []string, def string) (string, error) {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v, decryption: vx.decryption}.GetEnum(name, allowed, def)
 }
 
 // GetStrings retrieves a slice of strings from the configuration.
 // It returns a default value if the key is not set.
 func GetStrings(name string, def []string) []string {
-	if !vx.v.IsSet(name) {
-		return def
-	}
-	return vx.v.GetStringSlice(name)
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetStrings(name, def)
 }
 
 // GetInt retrieves an integer value from the configuration.
 // It returns a default value if the key is not set.
 func GetInt(name string, def int) int {
-	if !vx.v.IsSet(name) {
-		return def
-	}
-	return vx.v.GetInt(name)
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetInt(name, def)
 }
 
 // GetInt64 retrieves an int64 value from the configuration.
 // It returns a default value if the key is not set.
 func GetInt64(name string, def int64) int64 {
-	if !vx.v.IsSet(name) {
-		return def
-	}
-	return vx.v.GetInt64(name)
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetInt64(name, def)
 }
 
 // GetBool retrieves a boolean value from the configuration.
 // It returns a default value if the key is not set.
 func GetBool(name string, def bool) bool {
-	if !vx.v.IsSet(name) {
-		return def
-	}
-	return vx.v.GetBool(name)
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetBool(name, def)
 }
 
 // GetFloat64 retrieves a float64 value from the configuration.
 // It returns a default value if the key is not set.
 func GetFloat64(name string, def float64) float64 {
-	if !vx.v.IsSet(name) {
-		return def
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetFloat64(name, def)
+}
+
+// GetDuration retrieves a duration from the configuration. A string follows
+// the time.ParseDuration syntax, e.g. "30s", "1h30m", a number is taken as
+// nanoseconds like in viper.
+// It returns a default value if the key is not set or fails to parse.
+func GetDuration(name string, def time.Duration) time.Duration {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetDuration(name, def)
+}
+
+func toDuration(val interface{}) (time.Duration, bool) {
+	switch val := val.(type) {
+	case time.Duration:
+		return val, true
+	case string:
+		d, err := time.ParseDuration(strings.TrimSpace(val))
+		return d, err == nil
+	default:
+		rv := reflect.ValueOf(val)
+		switch {
+		case rv.CanInt():
+			return time.Duration(rv.Int()), true
+		case rv.CanUint():
+			return time.Duration(rv.Uint()), true
+		case rv.CanFloat():
+			return time.Duration(rv.Float()), true
+		}
+		return 0, false
+	}
+}
+
+// GetIntInRange retrieves an integer which must be within [min, max], e.g.
+// GetIntInRange("workers", 1, 64, 8), to catch a "workers: -5" at startup.
+// It returns def if the key is not set, def and an error naming the key,
+// the value and the bounds if it is not an integer or out of range.
+func GetIntInRange(name string, min, max, def int) (int, error) {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetIntInRange(name, min, max, def)
+}
+
+func toInt(val interface{}) (int, bool) {
+	if s, ok := val.(string); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		return n, err == nil
+	}
+	rv := reflect.ValueOf(val)
+	switch {
+	case rv.CanInt():
+		return int(rv.Int()), true
+	case rv.CanUint() && rv.Uint() <= math.MaxInt:
+		return int(rv.Uint()), true
+	case rv.CanFloat() && rv.Float() == math.Trunc(rv.Float()):
+		return int(rv.Float()), true
+	}
+	return 0, false
+}
+
+// GetDurationInRange retrieves a duration, parsed like GetDuration, which
+// must be within [min, max], e.g. GetDurationInRange("timeout", time.Second,
+// time.Minute, 10*time.Second).
+// It returns def if the key is not set, def and an error naming the key,
+// the value and the bounds if it fails to parse or is out of range.
+func GetDurationInRange(name string, min, max, def time.Duration) (time.Duration, error) {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetDurationInRange(name, min, max, def)
+}
+
+// GetBytes retrieves a size in bytes from the configuration. A string is
+// parsed by ParseBytes, e.g. "100MB", "1.5GiB", a number is taken as bytes.
+// It returns a default value if the key is not set or fails to parse.
+func GetBytes(name string, def int64) int64 {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetBytes(name, def)
+}
+
+// GetIP retrieves an IPv4 or IPv6 address from the configuration, e.g.
+// "10.0.0.1", "::1", parsed by net.ParseIP: no CIDR suffix, port nor zone.
+// It returns a default value if the key is not set or fails to parse, so the
+// result is nil only with a nil def.
+func GetIP(name string, def net.IP) net.IP {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetIP(name, def)
+}
+
+// GetURL retrieves an absolute URL from the configuration, e.g.
+// "https://api.example.com/v1". Any scheme is accepted, but both the scheme
+// and the host are required, a relative reference like "/v1" or "example.com"
+// is a parse failure.
+// It returns a default value if the key is not set or fails to parse.
+func GetURL(name string, def *url.URL) *url.URL {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetURL(name, def)
+}
+
+// GetTime retrieves a point in time from the configuration. A string is parsed
+// by time.Parse with layout, time.RFC3339 if empty, e.g.
+// "2024-06-01T00:00:00Z", a time.Time, e.g. from a TOML or YAML timestamp, is
+// taken as is.
+// It returns a default value if the key is not set or fails to parse.
+func GetTime(name string, layout string, def time.Time) time.Time {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetTime(name, layout, def)
+}
+
+// GetStringMap retrieves a dynamic section, e.g. per-tenant settings, as a map.
+// It returns a default value if the key is not set. The map is a copy, free to
+// change, and does not change with the config.
+func GetStringMap(name string, def map[string]interface{}) map[string]interface{} {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	return ConfigView{v: vx.v}.GetStringMap(name, def)
+}
+
+// Sub returns a viper scoped to the section at name, to be handed to a
+// sub module, nil if the key is not set or not a section.
+// The result is a snapshot, a later reload or Set of the config is not
+// reflected, call Sub again after reloading.
+func Sub(name string) *viper.Viper {
+	vx.mutex.RLock()
+	defer vx.mutex.RUnlock()
+	sub := vx.v.Sub(name)
+	if sub == nil {
+		return nil
 	}
-	return vx.v.GetFloat64(name)
+	// viper shares the maps of the section with the live config
+	v := viper.New()
+	_ = v.MergeConfigMap(sub.AllSettings())
+	return v
 }
@@ -2,9 +2,15 @@ package viperx
 
 import (
 	"log"
+	"net"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTomls(t *testing.T) {
@@ -15,3 +21,151 @@ func TestTomls(t *testing.T) {
 
 	log.Printf("sys.logdir:%v", GetString("sys.logdir", "./"))
 }
+
+func TestGetEnum(t *testing.T) {
+	levels := []string{"debug", "info", "warn", "error"}
+	viper.Set("enumtest.level", "WARN")
+	viper.Set("enumtest.typo", "verbse")
+
+	v, err := GetEnum("enumtest.level", levels, "info")
+	assert.NoError(t, err)
+	assert.Equal(t, "warn", v)
+
+	v, err = GetEnum("enumtest.unset", levels, "info")
+	assert.NoError(t, err)
+	assert.Equal(t, "info", v)
+
+	_, err = GetEnum("enumtest.typo", levels, "info")
+	assert.EqualError(t, err, "invalid value 'verbse' of enumtest.typo, should be one of [debug, info, warn, error]")
+}
+
+func TestGetInRange(t *testing.T) {
+	viper.Set("rangetest.workers", 8)
+	viper.Set("rangetest.backups", -5)
+	viper.Set("rangetest.size", "12")
+	viper.Set("rangetest.name", "many")
+	viper.Set("rangetest.timeout", "30s")
+	viper.Set("rangetest.idle", "2h")
+
+	n, err := GetIntInRange("rangetest.workers", 1, 64, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, n)
+	n, err = GetIntInRange("rangetest.size", 1, 64, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, n)
+	n, err = GetIntInRange("rangetest.unset", 1, 64, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	n, err = GetIntInRange("rangetest.backups", 0, 100, 5)
+	assert.EqualError(t, err, "invalid value '-5' of rangetest.backups, should be an integer in [0, 100]")
+	assert.Equal(t, 5, n)
+	_, err = GetIntInRange("rangetest.name", 0, 100, 5)
+	assert.Error(t, err)
+
+	d, err := GetDurationInRange("rangetest.timeout", time.Second, time.Minute, 10*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, d)
+	d, err = GetDurationInRange("rangetest.idle", time.Second, time.Hour, 10*time.Second)
+	assert.EqualError(t, err, "invalid value '2h' of rangetest.idle, should be a duration in [1s, 1h0m0s]")
+	assert.Equal(t, 10*time.Second, d)
+}
+
+func TestGetIPURLTime(t *testing.T) {
+	viper.Set("typedtest.ip", " 10.0.0.1 ")
+	viper.Set("typedtest.ip6", "::1")
+	viper.Set("typedtest.badip", "10.0.0.1/8")
+	viper.Set("typedtest.url", "https://api.example.com/v1")
+	viper.Set("typedtest.relurl", "api.example.com/v1")
+	viper.Set("typedtest.time", "2024-06-01T08:00:00Z")
+	viper.Set("typedtest.day", "2024-06-01")
+
+	defIP := net.IPv4(127, 0, 0, 1)
+	assert.True(t, net.IPv4(10, 0, 0, 1).Equal(GetIP("typedtest.ip", defIP)))
+	assert.True(t, net.IPv6loopback.Equal(GetIP("typedtest.ip6", defIP)))
+	assert.Equal(t, defIP, GetIP("typedtest.badip", defIP))
+	assert.Equal(t, defIP, GetIP("typedtest.unset", defIP))
+
+	defURL := &url.URL{Scheme: "http", Host: "localhost"}
+	assert.Equal(t, "https://api.example.com/v1", GetURL("typedtest.url", defURL).String())
+	assert.Equal(t, defURL, GetURL("typedtest.relurl", defURL))
+	assert.Equal(t, defURL, GetURL("typedtest.unset", defURL))
+
+	defTime := time.Unix(0, 0)
+	assert.Equal(t, time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC), GetTime("typedtest.time", "", defTime))
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), GetTime("typedtest.day", time.DateOnly, defTime))
+	assert.Equal(t, defTime, GetTime("typedtest.day", "", defTime))
+	assert.Equal(t, defTime, GetTime("typedtest.unset", "", defTime))
+}
+
+func TestExistsTypeOf(t *testing.T) {
+	require.NoError(t, ReadBytes([]byte("introtest:\n  name: a\n  port: 8080\n  ratio: 0.5\n  on: true\n  tags: [x]\n  backend:\n    addr: b:1\n"), "yaml"))
+	viper.SetDefault("introtest.defaulted", 3)
+	Set("introtest.timeout", 2*time.Second)
+
+	for key, want := range map[string]string{
+		"introtest.name":      "string",
+		"introtest.port":      "int",
+		"introtest.ratio":     "float",
+		"introtest.on":        "bool",
+		"introtest.tags":      "slice",
+		"introtest.backend":   "map",
+		"introtest.defaulted": "int",
+		"introtest.timeout":   "duration",
+		"introtest.missing":   "",
+	} {
+		assert.Equal(t, want, TypeOf(key), key)
+		assert.Equal(t, want != "", Exists(key), key)
+	}
+	assert.True(t, Exists("introtest"))
+	assert.Equal(t, "map", Snapshot().TypeOf("introtest.backend"))
+	assert.True(t, Snapshot().Exists("introtest.defaulted"))
+}
+
+func TestReadFrom(t *testing.T) {
+	viper.SetDefault("readtest.mode", "safe")
+	t.Setenv("READTEST_TOKEN", "tok")
+	require.NoError(t, viper.BindEnv("readtest.token", "READTEST_TOKEN"))
+
+	require.NoError(t, ReadBytes([]byte("readtest:\n  host: a\n  port: 1\n"), "yaml"))
+	assert.Equal(t, "a", GetString("readtest.host", ""))
+
+	// a new config replaces the keys of the previous one, the other layers stay
+	require.NoError(t, ReadFrom(strings.NewReader(`{"readtest": {"host": "b"}}`), "json"))
+	assert.Equal(t, "b", GetString("readtest.host", ""))
+	assert.False(t, viper.InConfig("readtest.port"))
+	assert.Equal(t, 0, GetInt("readtest.port", 0))
+	assert.Equal(t, "safe", GetString("readtest.mode", ""))
+	assert.Equal(t, "tok", GetString("readtest.token", ""))
+
+	gen := vx.Generation()
+	assert.ErrorContains(t, ReadBytes([]byte("readtest:\n  host: c\n"), "ini2"), "unsupported config format 'ini2'")
+	assert.Error(t, ReadBytes([]byte("readtest:\n  host: [\n"), "yaml"))
+	assert.Equal(t, "b", GetString("readtest.host", ""), "the config is kept")
+	assert.Equal(t, gen, vx.Generation())
+}
+
+func TestGetStringMapSub(t *testing.T) {
+	require.NoError(t, ReadBytes([]byte("maptest:\n  port: 1\n  tenants:\n    a:\n      size: 1\n"), "yaml"))
+
+	def := map[string]interface{}{"x": 1}
+	assert.Equal(t, def, GetStringMap("maptest.missing", def))
+	assert.Nil(t, Sub("maptest.missing"))
+	assert.Nil(t, Sub("maptest.port"), "a leaf is not a section")
+
+	m := GetStringMap("maptest.tenants", nil)
+	sub := Sub("maptest.tenants")
+	require.NotNil(t, sub)
+	assert.Equal(t, 1, sub.GetInt("a.size"))
+
+	viper.Set("maptest.tenants.a.size", 2)
+	viper.Set("maptest.tenants.b", 3)
+	assert.Equal(t, map[string]interface{}{"a": map[string]interface{}{"size": 1}}, m)
+	assert.Equal(t, 1, sub.GetInt("a.size"))
+	assert.False(t, sub.IsSet("b"))
+
+	// changing the map returned does not change the config
+	m = GetStringMap("maptest.tenants", nil)
+	m["a"].(map[string]interface{})["size"] = 9
+	assert.Equal(t, 2, GetInt("maptest.tenants.a.size", 0))
+}